@@ -1,23 +1,46 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/audit"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/cache"
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/handlers"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/license"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/metrics"
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/router"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/source"
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/licensefile"
 	"github.com/joho/godotenv"
 )
 
+// backgroundStopTimeout bounds the wait for cancelled background work on shutdown
+const backgroundStopTimeout = 10 * time.Second
+
 // API represents the main API application
 type API struct {
-	config *domain.Config
-	router *router.Router
-	server *http.Server
+	config   *domain.Config
+	licenses *license.Store
+	router   *router.Router
+	server   *http.Server
+	// background is the work cancelled and waited for before temp dirs are removed
+	background *handlers.Background
 }
 
 // New creates a new API instance
 func New() *API {
+	// Log as JSON, including messages of the standard log package
+	logging.Setup()
+
 	// Load environment variables (if there is a .env file)
 	_ = godotenv.Load()
 
@@ -26,29 +49,104 @@ func New() *API {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Validate required configuration
-	if config.LicenseToken == "" {
-		log.Fatal("LICENSE_TOKEN environment variable is required")
+	// Invalid settings don't stop the service, but keep /health/ready failing
+	if err := config.Validate(); err != nil {
+		log.Printf("Invalid configuration: %v", strings.ReplaceAll(err.Error(), "\n", "; "))
 	}
-	if config.GitHubToken == "" {
+	for _, warning := range config.Warnings() {
+		log.Printf("Configuration warning: %s", warning)
+	}
+
+	// Validate required configuration
+	if config.GetSourceType() == source.TypeGitHub && config.GitHubToken == "" {
 		log.Fatal("GITHUB_TOKEN environment variable is required")
 	}
 
+	// Create the artifact source
+	artifactSource, err := source.New(config)
+	if err != nil {
+		log.Fatalf("Failed to create artifact source: %v", err)
+	}
+	log.Printf("Artifact source: %s", artifactSource.Name())
+
+	// Load license registry
+	licenses, err := license.LoadStore(config.GetLicenseFile())
+	if err != nil {
+		log.Fatalf("Failed to load licenses: %v", err)
+	}
+
+	// Keep accepting the legacy shared token as the "default" license
+	if token := config.GetLicenseToken(); token != "" {
+		if err := licenses.AddBuiltin(&license.License{
+			ID:        "default",
+			Customer:  "Legacy shared license",
+			TokenHash: license.HashToken(token),
+		}); err != nil {
+			log.Fatalf("Failed to register LICENSE_TOKEN: %v", err)
+		}
+	}
+
+	// Trust signed license files when a public key is configured
+	signedLicenses := config.GetLicensePublicKey() != ""
+	if signedLicenses {
+		publicKey, err := licensefile.ParsePublicKey(config.GetLicensePublicKey())
+		if err != nil {
+			log.Fatalf("Failed to load LICENSE_PUBLIC_KEY: %v", err)
+		}
+		licenses.SetPublicKey(publicKey)
+		log.Println("Signed license files enabled")
+	}
+
+	if licenses.Len() == 0 && !signedLicenses {
+		log.Fatal("No licenses configured: set LICENSE_TOKEN, LICENSE_PUBLIC_KEY or provide a license file")
+	}
+	log.Printf("Loaded %d license(s)", licenses.Len())
+
+	// Open the download package cache
+	packageCache, err := cache.New(config.GetCacheDir(), config.GetCacheMaxSize())
+	if err != nil {
+		log.Fatalf("Failed to open package cache: %v", err)
+	}
+	log.Printf("Package cache: %s (%d bytes used)", packageCache.Dir(), packageCache.Size())
+
+	// Open the download audit log
+	auditLog, err := audit.Open(config.GetAuditFile())
+	if err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+	if auditLog.Enabled() {
+		log.Printf("Audit log: %s", auditLog.Path())
+	} else {
+		log.Println("Audit log disabled")
+	}
+
+	metrics.BuildInfo.Set(1, domain.Version, domain.GitCommit)
+	metrics.NewGaugeFunc("gojo_cache_size_bytes", "Disk space used by the package cache.", func() float64 {
+		return float64(packageCache.Size())
+	})
+
+	// Remove temp dirs left behind by a previous crash
+	cleanupTempDirs(config)
+
 	// Create router
-	apiRouter := router.New(config)
+	base := handlers.NewBaseHandler(config, licenses, packageCache, artifactSource, auditLog)
+	apiRouter := router.New(base)
 
 	// Create server
 	server := &http.Server{
 		Addr:         "0.0.0.0:" + config.API.DefaultPort,
-		Handler:      apiRouter.GetRouter(),
+		Handler:      apiRouter.Handler(),
 		ReadTimeout:  config.Server.ReadTimeout,
 		WriteTimeout: config.Server.WriteTimeout,
 	}
 
 	return &API{
-		config: config,
-		router: apiRouter,
-		server: server,
+		config:   config,
+		licenses: licenses,
+		router:   apiRouter,
+		server:   server,
+
+		background: base.Background,
 	}
 }
 
@@ -58,21 +156,90 @@ func (a *API) Start() error {
 	log.Printf("Build info: commit=%s, date=%s", domain.GitCommit, domain.BuildDate)
 	log.Printf("Configuration loaded from: %s", "config.yaml")
 	log.Printf("Endpoints available:")
-	log.Printf("  GET /versions")
-	log.Printf("  GET /integrations")
-	log.Printf("  GET /download/{app_version}/{integration}")
-	log.Printf("  GET /health")
+	log.Printf("  GET /v1/versions")
+	log.Printf("  GET /v1/versions/{version}")
+	log.Printf("  GET /v1/integrations")
+	log.Printf("  GET /v1/download/{app_version}/{integration}")
+	log.Printf("  GET /v1/health")
+	log.Printf("  GET /v1/health/ready")
+	if a.config.Metrics.Enabled {
+		log.Printf("  GET /v1/metrics")
+	}
+	log.Printf("  GET /v1/openapi.json")
+	log.Printf("  GET /v1/admin/audit")
+	log.Printf("  GET|POST /v1/admin/licenses")
+	log.Printf("  GET|PATCH|DELETE /v1/admin/licenses/{id}")
+	log.Printf("  POST /v1/admin/licenses/{id}/{suspend,resume,rotate}")
+	log.Printf("  POST /v1/webhooks/github")
+	log.Printf("Unversioned paths (e.g. /versions) are deprecated aliases of the /v1 routes")
 
 	// Optionally log all routes for debugging
 	a.router.LogRoutes()
 
-	return a.server.ListenAndServe()
+	if err := a.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
-// Stop gracefully stops the API server
+// Stop gracefully stops the API server. New connections are refused while
+// in-flight requests get server.shutdown_timeout to finish, after which the
+// remaining connections are closed. Background work (prebuilds, package
+// assemblies) is then cancelled and waited for, and temp dirs are removed.
 func (a *API) Stop() error {
-	log.Println("Stopping go-jo-api...")
-	return a.server.Close()
+	log.Printf("Stopping go-jo-api, waiting up to %s for in-flight requests...", a.config.Server.ShutdownTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.config.Server.ShutdownTimeout)
+	defer cancel()
+
+	err := a.server.Shutdown(ctx)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Println("Grace period expired, closing remaining connections")
+		err = a.server.Close()
+	case err == nil:
+		log.Println("All in-flight requests finished")
+	}
+
+	// Cancelled work returns quickly, but don't hang on a stuck one
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), backgroundStopTimeout)
+	defer waitCancel()
+	if stopErr := a.background.Stop(waitCtx); stopErr != nil {
+		log.Printf("Background work still running after %s, removing temp dirs anyway", backgroundStopTimeout)
+	}
+
+	cleanupTempDirs(a.config)
+	return err
+}
+
+// cleanupTempDirs removes the download temp dirs created under temp_dir_prefix
+func cleanupTempDirs(config *domain.Config) {
+	if config.GetTempDirPrefix() == "" {
+		return
+	}
+
+	matches, err := filepath.Glob(filepath.Join(os.TempDir(), config.GetTempDirPrefix()+"*"))
+	if err != nil {
+		log.Printf("Failed to list temp dirs: %v", err)
+		return
+	}
+
+	cacheDir, _ := filepath.Abs(config.GetCacheDir())
+	for _, dir := range matches {
+		// The default cache dir shares the temp dir prefix
+		if dir == cacheDir {
+			continue
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("Failed to remove temp dir %s: %v", dir, err)
+			continue
+		}
+		log.Printf("Removed temp dir %s", dir)
+	}
 }
 
 // GetConfig returns the API configuration
@@ -80,6 +247,11 @@ func (a *API) GetConfig() *domain.Config {
 	return a.config
 }
 
+// GetLicenses returns the license registry
+func (a *API) GetLicenses() *license.Store {
+	return a.licenses
+}
+
 // GetRouter returns the API router
 func (a *API) GetRouter() *router.Router {
 	return a.router
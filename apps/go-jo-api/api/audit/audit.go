@@ -0,0 +1,282 @@
+// Package audit keeps a persistent record of package downloads and admin
+// actions, stored as JSON lines in a single append-only file.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Kinds of audit entries
+const (
+	KindDownload = "download"
+	KindAdmin    = "admin"
+)
+
+// Results of an audited request
+const (
+	ResultSuccess     = "success"
+	ResultNotModified = "not_modified"
+	ResultDenied      = "denied"
+	ResultRateLimited = "rate_limited"
+	ResultAborted     = "aborted"
+	ResultFailed      = "failed"
+)
+
+// maxLineSize bounds a single entry when reading the log back
+const maxLineSize = 1024 * 1024
+
+// Entry is one audited event
+type Entry struct {
+	Time            time.Time `json:"time"`
+	Kind            string    `json:"kind"`
+	Action          string    `json:"action,omitempty"`
+	RequestID       string    `json:"request_id,omitempty"`
+	License         string    `json:"license,omitempty"`
+	Version         string    `json:"version,omitempty"`
+	ResolvedVersion string    `json:"resolved_version,omitempty"`
+	Integration     string    `json:"integration,omitempty"`
+	Commit          string    `json:"commit,omitempty"`
+	ClientIP        string    `json:"client_ip,omitempty"`
+	ForwardedFor    string    `json:"forwarded_for,omitempty"`
+	UserAgent       string    `json:"user_agent,omitempty"`
+	Status          int       `json:"status"`
+	Bytes           int64     `json:"bytes"`
+	DurationMS      float64   `json:"duration_ms"`
+	Result          string    `json:"result"`
+	Message         string    `json:"message,omitempty"`
+}
+
+// Result classifies a response status. aborted tells whether the client went
+// away before the response was complete.
+func Result(status int, aborted bool) string {
+	switch {
+	case aborted:
+		return ResultAborted
+	case status == 304:
+		return ResultNotModified
+	case status >= 200 && status < 300:
+		return ResultSuccess
+	case status == 401 || status == 403:
+		return ResultDenied
+	case status == 429:
+		return ResultRateLimited
+	default:
+		return ResultFailed
+	}
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the entry being recorded, so
+// handlers can fill in what they resolve (e.g. the version behind "latest")
+func NewContext(ctx context.Context, e *Entry) context.Context {
+	return context.WithValue(ctx, contextKey{}, e)
+}
+
+// FromContext returns the entry being recorded for the request, if any
+func FromContext(ctx context.Context) *Entry {
+	e, _ := ctx.Value(contextKey{}).(*Entry)
+	return e
+}
+
+// Filter selects audit entries. Zero fields match everything.
+type Filter struct {
+	Kind    string
+	Action  string
+	License string
+	// Version matches the requested or the resolved version
+	Version string
+	// From and To bound the entry time, From inclusive and To exclusive
+	From time.Time
+	To   time.Time
+}
+
+// Match reports whether an entry passes the filter
+func (f Filter) Match(e *Entry) bool {
+	if f.Kind != "" && e.Kind != f.Kind {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if f.License != "" && e.License != f.License {
+		return false
+	}
+	if f.Version != "" && e.Version != f.Version && e.ResolvedVersion != f.Version {
+		return false
+	}
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.Time.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// Log is the append-only audit log. A Log without a file records nothing.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// Open opens (or creates) the audit log at path. An empty path disables the log.
+func Open(path string) (*Log, error) {
+	if path == "" {
+		return &Log{}, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	return &Log{path: path, file: file}, nil
+}
+
+// Enabled reports whether entries are persisted
+func (l *Log) Enabled() bool {
+	return l.file != nil
+}
+
+// Path returns the file backing the log
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Record appends an entry to the log. Each entry is written with a single
+// write call, so a crash never leaves half an entry behind another one.
+func (l *Log) Record(e Entry) error {
+	if !l.Enabled() {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Scan reads the log and calls fn with each entry matching the filter,
+// oldest first, until fn returns false. Entries are read one at a time, so
+// the log is never loaded in memory as a whole. Lines that can't be parsed
+// (e.g. truncated by a crash) are skipped.
+func (l *Log) Scan(filter Filter, fn func(e *Entry) bool) error {
+	if !l.Enabled() {
+		return nil
+	}
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if filter.Match(&e) && !fn(&e) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return nil
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	if !l.Enabled() {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// csvHeader lists the CSV columns, in the order of csvRecord
+var csvHeader = []string{
+	"time", "kind", "action", "request_id", "license", "version", "resolved_version", "integration", "commit",
+	"client_ip", "forwarded_for", "user_agent", "status", "bytes", "duration_ms", "result", "message",
+}
+
+// CSVWriter writes entries as CSV, starting with a header row
+type CSVWriter struct {
+	writer *csv.Writer
+}
+
+// NewCSVWriter returns a writer of entries to w, writing the header row
+func NewCSVWriter(w io.Writer) (*CSVWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &CSVWriter{writer: writer}, nil
+}
+
+// Write writes an entry. Rows are buffered until Flush.
+func (c *CSVWriter) Write(e *Entry) error {
+	return c.writer.Write(csvRecord(e))
+}
+
+// Flush writes the buffered rows and reports any write error
+func (c *CSVWriter) Flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func csvRecord(e *Entry) []string {
+	return []string{
+		e.Time.Format(time.RFC3339Nano),
+		e.Kind,
+		e.Action,
+		e.RequestID,
+		e.License,
+		e.Version,
+		e.ResolvedVersion,
+		e.Integration,
+		e.Commit,
+		e.ClientIP,
+		e.ForwardedFor,
+		e.UserAgent,
+		strconv.Itoa(e.Status),
+		strconv.FormatInt(e.Bytes, 10),
+		strconv.FormatFloat(e.DurationMS, 'f', 3, 64),
+		e.Result,
+		e.Message,
+	}
+}
@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestLog opens a log in a temp dir with the given entries
+func newTestLog(t *testing.T, entries ...Entry) *Log {
+	t.Helper()
+
+	l, err := Open(filepath.Join(t.TempDir(), "audit", "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	for _, e := range entries {
+		if err := l.Record(e); err != nil {
+			t.Fatalf("Record error = %v", err)
+		}
+	}
+	return l
+}
+
+func day(d int) time.Time {
+	return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC)
+}
+
+func scanAll(t *testing.T, l *Log, filter Filter) []Entry {
+	t.Helper()
+
+	var entries []Entry
+	if err := l.Scan(filter, func(e *Entry) bool {
+		entries = append(entries, *e)
+		return true
+	}); err != nil {
+		t.Fatalf("Scan error = %v", err)
+	}
+	return entries
+}
+
+func TestScanFilter(t *testing.T) {
+	l := newTestLog(t,
+		Entry{Time: day(1), Kind: KindDownload, License: "acme", Version: "latest", ResolvedVersion: "v1.2.0", Result: ResultSuccess},
+		Entry{Time: day(2), Kind: KindDownload, License: "globex", Version: "v1.1.0", Result: ResultDenied},
+		Entry{Time: day(3), Kind: KindAdmin, Action: "license.issue", Result: ResultSuccess},
+		Entry{Time: day(4), Kind: KindDownload, License: "acme", Version: "v1.1.0", Result: ResultSuccess},
+	)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int
+	}{
+		{"everything", Filter{}, []int{1, 2, 3, 4}},
+		{"kind", Filter{Kind: KindDownload}, []int{1, 2, 4}},
+		{"action", Filter{Action: "license.issue"}, []int{3}},
+		{"license", Filter{License: "acme"}, []int{1, 4}},
+		{"requested version", Filter{Version: "v1.1.0"}, []int{2, 4}},
+		{"resolved version", Filter{Version: "v1.2.0"}, []int{1}},
+		{"from is inclusive", Filter{From: day(2)}, []int{2, 3, 4}},
+		{"to is exclusive", Filter{To: day(3)}, []int{1, 2}},
+		{"range", Filter{From: day(2), To: day(4)}, []int{2, 3}},
+		{"no match", Filter{License: "initech"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := scanAll(t, l, tt.filter)
+			if len(entries) != len(tt.want) {
+				t.Fatalf("Scan matched %d entries, want %d", len(entries), len(tt.want))
+			}
+			for i, e := range entries {
+				if !e.Time.Equal(day(tt.want[i])) {
+					t.Errorf("entry %d time = %s, want %s", i, e.Time, day(tt.want[i]))
+				}
+			}
+		})
+	}
+}
+
+func TestScanStopsAndSkipsBrokenLines(t *testing.T) {
+	l := newTestLog(t, Entry{Time: day(1), Kind: KindDownload})
+
+	// A crash can leave a truncated line behind
+	file, err := os.OpenFile(l.Path(), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"time":"2026-03-0` + "\n")
+	file.Close()
+
+	for d := 2; d <= 5; d++ {
+		if err := l.Record(Entry{Time: day(d), Kind: KindDownload}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if entries := scanAll(t, l, Filter{}); len(entries) != 5 {
+		t.Errorf("Scan matched %d entries, want 5 around the broken line", len(entries))
+	}
+
+	calls := 0
+	if err := l.Scan(Filter{}, func(e *Entry) bool {
+		calls++
+		return calls < 2
+	}); err != nil {
+		t.Fatalf("Scan error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Scan called fn %d times after it returned false, want 2", calls)
+	}
+}
+
+func TestDisabledLog(t *testing.T) {
+	l, err := Open("")
+	if err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+	if err := l.Record(Entry{Kind: KindDownload}); err != nil {
+		t.Errorf("Record error = %v", err)
+	}
+	if entries := scanAll(t, l, Filter{}); len(entries) != 0 {
+		t.Errorf("disabled log returned %d entries", len(entries))
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewCSVWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewCSVWriter error = %v", err)
+	}
+	entry := Entry{
+		Time: day(1), Kind: KindDownload, License: "acme", Version: "latest", ResolvedVersion: "v1.2.0",
+		Integration: "zabbix", UserAgent: `curl, "quoted"`, Status: 200, Bytes: 1024, DurationMS: 12.3456, Result: ResultSuccess,
+	}
+	if err := writer.Write(&entry); err != nil {
+		t.Fatalf("Write error = %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	if len(records) != 2 || len(records[0]) != len(csvHeader) || len(records[1]) != len(csvHeader) {
+		t.Fatalf("CSV = %v, want a header and one row of %d columns", records, len(csvHeader))
+	}
+
+	row := make(map[string]string)
+	for i, column := range records[0] {
+		row[column] = records[1][i]
+	}
+	want := map[string]string{
+		"time":        "2026-03-01T12:00:00Z",
+		"user_agent":  `curl, "quoted"`,
+		"status":      "200",
+		"bytes":       "1024",
+		"duration_ms": "12.346",
+		"result":      ResultSuccess,
+	}
+	for column, value := range want {
+		if row[column] != value {
+			t.Errorf("%s = %q, want %q", column, row[column], value)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	fileExt    = ".zip"
+	tempPrefix = ".tmp-"
+)
+
+// Key identifies an assembled download package by its content: the app
+// package (release asset) and the commit of the integration
+type Key struct {
+	PackageID   string
+	Integration string
+	Commit      string
+}
+
+// group identifies all packages built from the same app package and
+// integration, regardless of the integration commit
+func (k Key) group() string {
+	return digest(k.PackageID, 6) + "-" + digest(k.Integration, 6)
+}
+
+// filename returns the name of the cache file for the key
+func (k Key) filename() string {
+	return k.group() + "-" + digest(k.Commit, 12) + fileExt
+}
+
+// digest returns the first n bytes of the SHA-256 of a value as hex
+func digest(value string, n int) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:n])
+}
+
+// fileDigests splits a cache file name into the digests of the app package,
+// the integration and the commit of its key
+func fileDigests(name string) (pkg, integration, commit string, ok bool) {
+	parts := strings.Split(strings.TrimSuffix(name, fileExt), "-")
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// entry tracks a cached file
+type entry struct {
+	name string
+	size int64
+	elem *list.Element
+}
+
+// Cache is an on-disk, size capped LRU cache of assembled download packages
+type Cache struct {
+	mu      sync.Mutex
+	dir     string
+	maxSize int64
+	size    int64
+	entries map[string]*entry
+	lru     *list.List // front is most recently used
+}
+
+// New creates a cache in dir holding at most maxSize bytes.
+// Files left by a previous run are indexed using their modification time as
+// last access time.
+func New(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	c := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		entries: make(map[string]*entry),
+		lru:     list.New(),
+	}
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load indexes the files already present in the cache directory
+func (c *Cache) load() error {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	type existing struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+
+	var found []existing
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasPrefix(name, tempPrefix) {
+			// Leftover of an interrupted build
+			os.Remove(filepath.Join(c.dir, name))
+			continue
+		}
+		if file.IsDir() || !strings.HasSuffix(name, fileExt) {
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		found = append(found, existing{name: name, size: info.Size(), modTime: info.ModTime()})
+	}
+
+	// Oldest first so the most recently used file ends up at the front
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].modTime.Before(found[j].modTime)
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, f := range found {
+		e := &entry{name: f.name, size: f.size}
+		e.elem = c.lru.PushFront(e)
+		c.entries[f.name] = e
+		c.size += f.size
+	}
+	c.evictLocked()
+
+	return nil
+}
+
+// Open opens a cached package for reading. The file stays readable even if
+// the package is evicted while it is being served.
+func (c *Cache) Open(key Key) (*os.File, bool) {
+	name := key.filename()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[name]
+	if !ok {
+		return nil, false
+	}
+
+	path := filepath.Join(c.dir, name)
+	file, err := os.Open(path)
+	if err != nil {
+		// The file vanished from disk, forget about it
+		c.removeLocked(name)
+		return nil, false
+	}
+	c.lru.MoveToFront(e.elem)
+
+	// Persist the access time so the LRU order survives restarts
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	return file, true
+}
+
+// Store builds a package with the write function, adds it to the cache and
+// returns it opened for reading. Packages of the same asset and integration
+// built from another commit are evicted, since the branch head has moved on.
+// A failed build never leaves a partial package in the cache.
+func (c *Cache) Store(key Key, write func(w io.Writer) error) (*os.File, error) {
+	tmp, err := os.CreateTemp(c.dir, tempPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	// Rewind so the caller can read the package it just built
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	name := key.filename()
+	path := filepath.Join(c.dir, name)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to store cache file: %w", err)
+	}
+
+	if old, ok := c.entries[name]; ok {
+		c.size -= old.size
+		c.lru.Remove(old.elem)
+		delete(c.entries, name)
+	}
+
+	// Drop packages superseded by the new integration commit
+	group := key.group() + "-"
+	for other := range c.entries {
+		if strings.HasPrefix(other, group) {
+			c.removeLocked(other)
+		}
+	}
+
+	e := &entry{name: name, size: info.Size()}
+	e.elem = c.lru.PushFront(e)
+	c.entries[name] = e
+	c.size += e.size
+	c.evictLocked()
+
+	return tmp, nil
+}
+
+// EvictIntegration removes the packages of an integration built from another
+// commit than keep, or all of them when keep is empty, and returns how many
+// were removed
+func (c *Cache) EvictIntegration(integration, keep string) int {
+	integrationDigest := digest(integration, 6)
+	keepDigest := digest(keep, 12)
+	return c.evict(func(_, integrationPart, commitPart string) bool {
+		return integrationPart == integrationDigest && (keep == "" || commitPart != keepDigest)
+	})
+}
+
+// EvictPackage removes the packages built from an app package and returns
+// how many were removed
+func (c *Cache) EvictPackage(packageID string) int {
+	pkgDigest := digest(packageID, 6)
+	return c.evict(func(pkgPart, _, _ string) bool {
+		return pkgPart == pkgDigest
+	})
+}
+
+// evict removes the packages whose key digests match
+func (c *Cache) evict(match func(pkg, integration, commit string) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for name := range c.entries {
+		if pkg, integration, commit, ok := fileDigests(name); ok && match(pkg, integration, commit) {
+			c.removeLocked(name)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Size returns the total size of the cached packages
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Dir returns the cache directory
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// evictLocked removes least recently used packages until the cache fits its
+// size cap. The most recently used package is always kept.
+func (c *Cache) evictLocked() {
+	for c.size > c.maxSize && c.lru.Len() > 1 {
+		oldest := c.lru.Back().Value.(*entry)
+		log.Printf("Evicting cached package %s (%d bytes)", oldest.name, oldest.size)
+		c.removeLocked(oldest.name)
+	}
+}
+
+// removeLocked deletes a cached package
+func (c *Cache) removeLocked(name string) {
+	e, ok := c.entries[name]
+	if !ok {
+		return
+	}
+	os.Remove(filepath.Join(c.dir, name))
+	c.lru.Remove(e.elem)
+	delete(c.entries, name)
+	c.size -= e.size
+}
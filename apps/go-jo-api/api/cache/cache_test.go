@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// store adds a package of the given size to the cache
+func store(t *testing.T, c *Cache, key Key, size int) {
+	t.Helper()
+	file, err := c.Store(key, func(w io.Writer) error {
+		_, err := w.Write([]byte(strings.Repeat("x", size)))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Store(%v) error = %v", key, err)
+	}
+	file.Close()
+}
+
+// cached reports whether a package is in the cache, without touching its
+// position in the LRU order
+func cached(c *Cache, key Key) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[key.filename()]
+	return ok
+}
+
+func TestStoreAndOpen(t *testing.T) {
+	c, err := New(t.TempDir(), 1024)
+	if err != nil {
+		t.Fatalf("New error = %v", err)
+	}
+
+	key := Key{PackageID: "101", Integration: "zabbix", Commit: "aaa"}
+	if _, ok := c.Open(key); ok {
+		t.Fatal("Open() found a package in an empty cache")
+	}
+
+	store(t, c, key, 10)
+
+	file, ok := c.Open(key)
+	if !ok {
+		t.Fatal("Open() missed a stored package")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil || len(data) != 10 {
+		t.Fatalf("read %d bytes, err = %v, want 10 bytes", len(data), err)
+	}
+	if c.Size() != 10 {
+		t.Errorf("Size() = %d, want 10", c.Size())
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	a := Key{PackageID: "101", Integration: "a", Commit: "1"}
+	b := Key{PackageID: "101", Integration: "b", Commit: "1"}
+	d := Key{PackageID: "101", Integration: "d", Commit: "1"}
+
+	tests := []struct {
+		name    string
+		access  []Key
+		evicted Key
+		kept    []Key
+	}{
+		{name: "oldest is evicted", evicted: a, kept: []Key{b, d}},
+		{name: "recently opened is kept", access: []Key{a}, evicted: b, kept: []Key{a, d}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(t.TempDir(), 25)
+			if err != nil {
+				t.Fatalf("New error = %v", err)
+			}
+
+			store(t, c, a, 10)
+			store(t, c, b, 10)
+			for _, key := range tt.access {
+				file, ok := c.Open(key)
+				if !ok {
+					t.Fatalf("Open(%v) missed", key)
+				}
+				file.Close()
+			}
+			store(t, c, d, 10)
+
+			if cached(c, tt.evicted) {
+				t.Errorf("%s should have been evicted", tt.evicted.Integration)
+			}
+			for _, key := range tt.kept {
+				if !cached(c, key) {
+					t.Errorf("%s should have been kept", key.Integration)
+				}
+			}
+			if c.Size() != 20 {
+				t.Errorf("Size() = %d, want 20", c.Size())
+			}
+			if _, err := os.Stat(filepath.Join(c.Dir(), tt.evicted.filename())); !os.IsNotExist(err) {
+				t.Errorf("file of the evicted package still exists: %v", err)
+			}
+		})
+	}
+}
+
+func TestLargePackageIsKept(t *testing.T) {
+	c, err := New(t.TempDir(), 5)
+	if err != nil {
+		t.Fatalf("New error = %v", err)
+	}
+
+	key := Key{PackageID: "101", Integration: "zabbix", Commit: "aaa"}
+	store(t, c, key, 10)
+
+	if !cached(c, key) {
+		t.Error("the most recently used package must be kept even above the size cap")
+	}
+}
+
+func TestStoreEvictsSameGroup(t *testing.T) {
+	c, err := New(t.TempDir(), 1024)
+	if err != nil {
+		t.Fatalf("New error = %v", err)
+	}
+
+	old := Key{PackageID: "101", Integration: "zabbix", Commit: "aaa"}
+	otherRelease := Key{PackageID: "102", Integration: "zabbix", Commit: "aaa"}
+	otherIntegration := Key{PackageID: "101", Integration: "grafana", Commit: "aaa"}
+	store(t, c, old, 10)
+	store(t, c, otherRelease, 10)
+	store(t, c, otherIntegration, 10)
+
+	current := Key{PackageID: "101", Integration: "zabbix", Commit: "bbb"}
+	store(t, c, current, 10)
+
+	if cached(c, old) {
+		t.Error("package of the previous integration commit should have been evicted")
+	}
+	for _, key := range []Key{otherRelease, otherIntegration, current} {
+		if !cached(c, key) {
+			t.Errorf("%+v should have been kept", key)
+		}
+	}
+	if c.Size() != 30 {
+		t.Errorf("Size() = %d, want 30", c.Size())
+	}
+}
+
+func TestEvictIntegrationAndPackage(t *testing.T) {
+	zabbix1 := Key{PackageID: "101", Integration: "zabbix", Commit: "aaa"}
+	zabbix2 := Key{PackageID: "102", Integration: "zabbix", Commit: "bbb"}
+	grafana := Key{PackageID: "101", Integration: "grafana", Commit: "ccc"}
+
+	tests := []struct {
+		name    string
+		evict   func(c *Cache) int
+		removed []Key
+	}{
+		{name: "integration keeping a commit", evict: func(c *Cache) int { return c.EvictIntegration("zabbix", "bbb") }, removed: []Key{zabbix1}},
+		{name: "whole integration", evict: func(c *Cache) int { return c.EvictIntegration("zabbix", "") }, removed: []Key{zabbix1, zabbix2}},
+		{name: "unknown integration", evict: func(c *Cache) int { return c.EvictIntegration("nginx", "") }},
+		{name: "app package", evict: func(c *Cache) int { return c.EvictPackage("101") }, removed: []Key{zabbix1, grafana}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(t.TempDir(), 1024)
+			if err != nil {
+				t.Fatalf("New error = %v", err)
+			}
+			all := []Key{zabbix1, zabbix2, grafana}
+			for _, key := range all {
+				store(t, c, key, 10)
+			}
+
+			if got := tt.evict(c); got != len(tt.removed) {
+				t.Errorf("evicted %d packages, want %d", got, len(tt.removed))
+			}
+
+			removed := make(map[Key]bool)
+			for _, key := range tt.removed {
+				removed[key] = true
+			}
+			for _, key := range all {
+				if cached(c, key) == removed[key] {
+					t.Errorf("%+v cached = %v, want %v", key, cached(c, key), !removed[key])
+				}
+			}
+		})
+	}
+}
+
+func TestFailedStoreLeavesNothing(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 1024)
+	if err != nil {
+		t.Fatalf("New error = %v", err)
+	}
+
+	key := Key{PackageID: "101", Integration: "zabbix", Commit: "aaa"}
+	failure := errors.New("upstream went away")
+	_, err = c.Store(key, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Store() error = %v, want %v", err, failure)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 || c.Size() != 0 {
+		t.Errorf("failed build left %d files and %d bytes", len(entries), c.Size())
+	}
+}
+
+func TestReloadKeepsLRUOrder(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 1024)
+	if err != nil {
+		t.Fatalf("New error = %v", err)
+	}
+
+	older := Key{PackageID: "101", Integration: "a", Commit: "1"}
+	newer := Key{PackageID: "101", Integration: "b", Commit: "1"}
+	store(t, c, older, 10)
+	store(t, c, newer, 10)
+
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, older.filename()), past, past)
+	os.WriteFile(filepath.Join(dir, tempPrefix+"leftover"), []byte("partial"), 0644)
+
+	// A smaller cap on restart evicts the least recently used package
+	reloaded, err := New(dir, 15)
+	if err != nil {
+		t.Fatalf("New error = %v", err)
+	}
+	if cached(reloaded, older) || !cached(reloaded, newer) {
+		t.Error("reloading should evict the package with the oldest access time")
+	}
+	if _, err := os.Stat(filepath.Join(dir, tempPrefix+"leftover")); !os.IsNotExist(err) {
+		t.Error("leftover of an interrupted build should be removed")
+	}
+}
@@ -0,0 +1,66 @@
+package domain
+
+import "time"
+
+// Release is a published go-jo version offered by an artifact source
+type Release struct {
+	Version    string
+	Prerelease bool
+	// Notes is the description of the release (markdown)
+	Notes string
+	// PublishedAt is zero when the source doesn't record it
+	PublishedAt time.Time
+	// Assets lists every file attached to the release
+	Assets []Asset
+	// AppPackage is the go-jo .deb of the release, nil when the release has none
+	AppPackage *Package
+}
+
+// Asset is a file attached to a release. Size is 0 when the source doesn't
+// report it.
+type Asset struct {
+	Name string
+	Size int64
+}
+
+// Package is a downloadable release artifact. ID identifies its content and
+// changes whenever the artifact is replaced.
+type Package struct {
+	ID   string
+	Name string
+	Size int64
+	// Location is a backend specific locator used to fetch the package
+	Location string
+}
+
+// Integration is a docker environment offered by an artifact source, pinned
+// to the commit (or content revision) currently published
+type Integration struct {
+	Name   string
+	Commit string
+}
+
+// IntegrationManifest is the integration.yaml at the root of an integration,
+// describing the environment and what it needs to run. Every field is
+// optional.
+type IntegrationManifest struct {
+	// Name is the display name of the integration
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Maintainer  string `yaml:"maintainer"`
+	// GoJo is the range of go-jo versions the integration works with
+	GoJo struct {
+		MinVersion string `yaml:"min_version"`
+		MaxVersion string `yaml:"max_version"`
+	} `yaml:"go_jo"`
+	// Ports are the host ports the environment binds
+	Ports []int `yaml:"ports"`
+	// Env are the environment variables that must be set before deploying
+	Env []string `yaml:"env"`
+	// Requires holds version constraints (e.g. ">=20.10") of the tools
+	// deploying the environment
+	Requires struct {
+		Docker  string `yaml:"docker"`
+		Compose string `yaml:"compose"`
+	} `yaml:"requires"`
+}
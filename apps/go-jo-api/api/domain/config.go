@@ -1,10 +1,16 @@
 package domain
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/audit"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/license"
 	"github.com/spf13/viper"
 )
 
@@ -15,17 +21,39 @@ var (
 	BuildDate = "unknown"
 )
 
+// Source types selectable with source.type, implemented by the source package
+const (
+	SourceTypeGitHub = "github"
+	SourceTypeLocal  = "local"
+	SourceTypeGitLab = "gitlab"
+	SourceTypeGitea  = "gitea"
+)
+
+// Placeholder secrets shipped in the default configuration
+const (
+	placeholderGitHubToken  = "your-github-token-here"
+	placeholderLicenseToken = "your-license-token-here"
+)
+
 // Configuration loaded from config.yaml and environment
 type Config struct {
 	GitHubToken  string
 	LicenseToken string
 
 	// Loaded from config.yaml
-	API     APIConfig     `mapstructure:"api"`
-	GitHub  GitHubConfig  `mapstructure:"github"`
-	License LicenseConfig `mapstructure:"license"`
-	Server  ServerConfig  `mapstructure:"server"`
-	App     AppConfig     `mapstructure:"app"`
+	API       APIConfig       `mapstructure:"api"`
+	GitHub    GitHubConfig    `mapstructure:"github"`
+	Source    SourceConfig    `mapstructure:"source"`
+	License   LicenseConfig   `mapstructure:"license"`
+	Cache     CacheConfig     `mapstructure:"cache"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	Health    HealthConfig    `mapstructure:"health"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	Audit     AuditConfig     `mapstructure:"audit"`
+	Admin     AdminConfig     `mapstructure:"admin"`
+	Webhooks  WebhooksConfig  `mapstructure:"webhooks"`
+	Server    ServerConfig    `mapstructure:"server"`
+	App       AppConfig       `mapstructure:"app"`
 }
 
 type APIConfig struct {
@@ -38,13 +66,101 @@ type APIConfig struct {
 type GitHubConfig struct {
 	APIBaseURL   string             `mapstructure:"api_base_url"`
 	Token        string             `mapstructure:"token"`
+	PerPage      int                `mapstructure:"per_page"`
+	MaxPages     int                `mapstructure:"max_pages"`
 	Repositories RepositoriesConfig `mapstructure:"repositories"`
 }
 
+type SourceConfig struct {
+	Type     string        `mapstructure:"type"`
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	// DownloadTimeout bounds the download of a release asset or integration
+	// archive from the upstream
+	DownloadTimeout time.Duration      `mapstructure:"download_timeout"`
+	Local           LocalSourceConfig  `mapstructure:"local"`
+	GitLab          RemoteSourceConfig `mapstructure:"gitlab"`
+	Gitea           RemoteSourceConfig `mapstructure:"gitea"`
+}
+
+// RemoteSourceConfig configures a self-hosted forge (GitLab, Gitea)
+type RemoteSourceConfig struct {
+	BaseURL      string             `mapstructure:"base_url"`
+	Token        string             `mapstructure:"token"`
+	PerPage      int                `mapstructure:"per_page"`
+	MaxPages     int                `mapstructure:"max_pages"`
+	Repositories RepositoriesConfig `mapstructure:"repositories"`
+}
+
+func (c RemoteSourceConfig) GetPerPage() int {
+	if c.PerPage <= 0 || c.PerPage > 100 {
+		return 50
+	}
+	return c.PerPage
+}
+
+func (c RemoteSourceConfig) GetMaxPages() int {
+	if c.MaxPages <= 0 {
+		return 10
+	}
+	return c.MaxPages
+}
+
+type LocalSourceConfig struct {
+	ReleasesDir     string `mapstructure:"releases_dir"`
+	IntegrationsDir string `mapstructure:"integrations_dir"`
+}
+
 type LicenseConfig struct {
+	Token     string `mapstructure:"token"`
+	File      string `mapstructure:"file"`
+	PublicKey string `mapstructure:"public_key"`
+}
+
+type CacheConfig struct {
+	Dir       string `mapstructure:"dir"`
+	MaxSizeMB int64  `mapstructure:"max_size_mb"`
+}
+
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Token   string `mapstructure:"token"`
+}
+
+type RateLimitConfig struct {
+	Enabled                bool       `mapstructure:"enabled"`
+	List                   RateConfig `mapstructure:"list"`
+	Download               RateConfig `mapstructure:"download"`
+	MaxConcurrentDownloads int        `mapstructure:"max_concurrent_downloads"`
+}
+
+type RateConfig struct {
+	PerMinute float64 `mapstructure:"per_minute"`
+	Burst     int     `mapstructure:"burst"`
+}
+
+type AuditConfig struct {
+	File string `mapstructure:"file"`
+}
+
+type AdminConfig struct {
 	Token string `mapstructure:"token"`
 }
 
+type WebhooksConfig struct {
+	GitHub GitHubWebhookConfig `mapstructure:"github"`
+}
+
+type GitHubWebhookConfig struct {
+	Secret string `mapstructure:"secret"`
+	// PrebuildIntegrations is the number of most downloaded integrations
+	// assembled with a new release in advance (0 disables it)
+	PrebuildIntegrations int `mapstructure:"prebuild_integrations"`
+}
+
+type HealthConfig struct {
+	MinFreeMB int64 `mapstructure:"min_free_mb"`
+}
+
 type RepositoriesConfig struct {
 	GoJo               string `mapstructure:"go_jo"`
 	DockerEnvironments string `mapstructure:"docker_environments"`
@@ -53,6 +169,10 @@ type RepositoriesConfig struct {
 type ServerConfig struct {
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	// DownloadWriteTimeout replaces WriteTimeout on the download route, long
+	// enough to assemble a package and send it over a slow link
+	DownloadWriteTimeout time.Duration `mapstructure:"download_write_timeout"`
+	ShutdownTimeout      time.Duration `mapstructure:"shutdown_timeout"`
 }
 
 type AppConfig struct {
@@ -70,6 +190,24 @@ func (c *Config) GetGitHubAPIBaseURL() string {
 	return c.GitHub.APIBaseURL
 }
 
+func (c *Config) GetGitHubPerPage() int {
+	if c.GitHub.PerPage <= 0 || c.GitHub.PerPage > 100 {
+		return 100
+	}
+	return c.GitHub.PerPage
+}
+
+func (c *Config) GetGitHubMaxPages() int {
+	if c.GitHub.MaxPages <= 0 {
+		return 10
+	}
+	return c.GitHub.MaxPages
+}
+
+func (c *Config) GetSourceType() string {
+	return c.Source.Type
+}
+
 func (c *Config) GetGoJoRepo() string {
 	return c.GitHub.Repositories.GoJo
 }
@@ -90,10 +228,106 @@ func (c *Config) GetDebAppName() string {
 	return c.API.DebAppName
 }
 
+// GetSourceDownloadTimeout returns the timeout of upstream artifact
+// downloads, 30 minutes when unset
+func (c *Config) GetSourceDownloadTimeout() time.Duration {
+	if c.Source.DownloadTimeout <= 0 {
+		return 30 * time.Minute
+	}
+	return c.Source.DownloadTimeout
+}
+
 func (c *Config) GetTempDirPrefix() string {
 	return c.API.TempDirPrefix
 }
 
+func (c *Config) GetLicenseFile() string {
+	return c.License.File
+}
+
+func (c *Config) GetCacheDir() string {
+	return c.Cache.Dir
+}
+
+// GetDownloadWriteTimeout returns the time allowed to assemble and send a
+// package, 1h when unset
+func (c *Config) GetDownloadWriteTimeout() time.Duration {
+	if c.Server.DownloadWriteTimeout <= 0 {
+		return time.Hour
+	}
+	return c.Server.DownloadWriteTimeout
+}
+
+func (c *Config) GetCacheMaxSize() int64 {
+	return c.Cache.MaxSizeMB * 1024 * 1024
+}
+
+// GetLicenseToken returns the legacy shared license token, empty when it is
+// unset or still has its placeholder value, which must never be accepted
+func (c *Config) GetLicenseToken() string {
+	if c.LicenseToken == placeholderLicenseToken {
+		return ""
+	}
+	return c.LicenseToken
+}
+
+func (c *Config) GetLicensePublicKey() string {
+	return c.License.PublicKey
+}
+
+func (c *Config) GetAuditFile() string {
+	return c.Audit.File
+}
+
+func (c *Config) GetMinFreeSpace() uint64 {
+	if c.Health.MinFreeMB <= 0 {
+		return 0
+	}
+	return uint64(c.Health.MinFreeMB) * 1024 * 1024
+}
+
+// Validate reports configuration values that can't work
+func (c *Config) Validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.API.DefaultPort); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("api.port %q is not a valid port", c.API.DefaultPort))
+	}
+	if timeout, err := time.ParseDuration(c.API.RequestTimeout); err != nil || timeout <= 0 {
+		errs = append(errs, fmt.Errorf("api.request_timeout %q is not a positive duration", c.API.RequestTimeout))
+	}
+	if c.API.TempDirPrefix == "" {
+		errs = append(errs, errors.New("api.temp_dir_prefix is empty"))
+	}
+	if c.Source.DownloadTimeout < 0 {
+		errs = append(errs, fmt.Errorf("source.download_timeout %s must not be negative", c.Source.DownloadTimeout))
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.DownloadWriteTimeout < 0 || c.Server.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("server timeouts must not be negative"))
+	}
+	if c.Cache.Dir == "" {
+		errs = append(errs, errors.New("cache.dir is empty"))
+	}
+	if c.Cache.MaxSizeMB <= 0 {
+		errs = append(errs, fmt.Errorf("cache.max_size_mb %d must be positive", c.Cache.MaxSizeMB))
+	}
+	if c.GetSourceType() == SourceTypeGitHub && (c.GitHubToken == "" || c.GitHubToken == placeholderGitHubToken) {
+		errs = append(errs, errors.New("GITHUB_TOKEN is not set"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Warnings reports configuration values that are ignored, without keeping
+// the service from working
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if c.LicenseToken == placeholderLicenseToken {
+		warnings = append(warnings, "LICENSE_TOKEN still has its placeholder value and is ignored")
+	}
+	return warnings
+}
+
 // LoadConfig loads configuration from config.yaml and environment variables
 func LoadConfig() (*Config, error) {
 	viper.SetConfigName("config")
@@ -113,13 +347,39 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("api.request_timeout", "30s")
 	viper.SetDefault("api.deb_app_name", "go-jo-selected.deb")
 	viper.SetDefault("api.temp_dir_prefix", "go-jo-api-")
+	viper.SetDefault("source.type", SourceTypeGitHub)
+	viper.SetDefault("source.cache_ttl", "30s")
+	viper.SetDefault("source.download_timeout", "30m")
+	viper.SetDefault("source.local.releases_dir", "/var/lib/go-jo-api/releases")
+	viper.SetDefault("source.local.integrations_dir", "/var/lib/go-jo-api/integrations")
+	viper.SetDefault("source.gitlab.base_url", "https://gitlab.com")
+	viper.SetDefault("source.gitlab.per_page", 100)
+	viper.SetDefault("source.gitlab.max_pages", 10)
+	viper.SetDefault("source.gitea.per_page", 50)
+	viper.SetDefault("source.gitea.max_pages", 10)
 	viper.SetDefault("github.api_base_url", "https://api.github.com")
-	viper.SetDefault("github.token", "your-github-token-here")
+	viper.SetDefault("github.token", placeholderGitHubToken)
+	viper.SetDefault("github.per_page", 100)
+	viper.SetDefault("github.max_pages", 10)
 	viper.SetDefault("github.repositories.go_jo", "henrique-ferreira-unvoid/go-jo")
 	viper.SetDefault("github.repositories.docker_environments", "henrique-ferreira-unvoid/go-jo-docker-environments")
+	viper.SetDefault("cache.dir", filepath.Join(os.TempDir(), "go-jo-api-cache"))
+	viper.SetDefault("cache.max_size_mb", 2048)
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("health.min_free_mb", 512)
+	viper.SetDefault("rate_limit.enabled", true)
+	viper.SetDefault("rate_limit.list.per_minute", 60)
+	viper.SetDefault("rate_limit.list.burst", 20)
+	viper.SetDefault("rate_limit.download.per_minute", 10)
+	viper.SetDefault("rate_limit.download.burst", 5)
+	viper.SetDefault("rate_limit.max_concurrent_downloads", 2)
+	viper.SetDefault("audit.file", "/var/lib/go-jo-api/audit.jsonl")
 	viper.SetDefault("server.read_timeout", "15s")
 	viper.SetDefault("server.write_timeout", "15s")
-	viper.SetDefault("license.token", "your-license-token-here")
+	viper.SetDefault("server.download_write_timeout", "1h")
+	viper.SetDefault("server.shutdown_timeout", "60s")
+	viper.SetDefault("license.token", "")
+	viper.SetDefault("license.file", "/etc/go-jo-api/licenses.json")
 
 	// Read config file
 	configFileUsed := ""
@@ -128,10 +388,10 @@ func LoadConfig() (*Config, error) {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
 			return nil, fmt.Errorf("error reading config file: %w", err)
 		}
-		fmt.Println("No config file found, using defaults")
+		log.Println("No config file found, using defaults")
 	} else {
 		configFileUsed = viper.ConfigFileUsed()
-		fmt.Printf("Using config file: %s\n", configFileUsed)
+		log.Printf("Using config file: %s", configFileUsed)
 	}
 
 	var config Config
@@ -142,6 +402,13 @@ func LoadConfig() (*Config, error) {
 	// Load environment variables (these override config file values)
 	config.GitHubToken = getEnvOrDefault("GITHUB_TOKEN", config.GitHub.Token)
 	config.LicenseToken = getEnvOrDefault("LICENSE_TOKEN", config.License.Token)
+	config.License.File = getEnvOrDefault("LICENSE_FILE", config.License.File)
+	config.License.PublicKey = getEnvOrDefault("LICENSE_PUBLIC_KEY", config.License.PublicKey)
+	config.Metrics.Token = getEnvOrDefault("METRICS_TOKEN", config.Metrics.Token)
+	config.Admin.Token = getEnvOrDefault("ADMIN_TOKEN", config.Admin.Token)
+	config.Webhooks.GitHub.Secret = getEnvOrDefault("GITHUB_WEBHOOK_SECRET", config.Webhooks.GitHub.Secret)
+	config.Source.GitLab.Token = getEnvOrDefault("GITLAB_TOKEN", config.Source.GitLab.Token)
+	config.Source.Gitea.Token = getEnvOrDefault("GITEA_TOKEN", config.Source.Gitea.Token)
 
 	return &config, nil
 }
@@ -157,18 +424,137 @@ func getEnvOrDefault(key, defaultValue string) string {
 
 // Response structures
 type VersionResponse struct {
-	Versions []string `json:"versions"`
+	Versions []string      `json:"versions"`
+	Latest   string        `json:"latest,omitempty"`
+	Releases []VersionInfo `json:"releases"`
+}
+
+type VersionInfo struct {
+	Version    string `json:"version"`
+	Prerelease bool   `json:"prerelease"`
 }
 
+// VersionDetails describes a single release with its notes
+type VersionDetails struct {
+	Version     string      `json:"version"`
+	Prerelease  bool        `json:"prerelease"`
+	PublishedAt *time.Time  `json:"published_at,omitempty"`
+	Commit      string      `json:"commit,omitempty"`
+	Notes       string      `json:"notes"`
+	Assets      []AssetInfo `json:"assets"`
+}
+
+type AssetInfo struct {
+	Name string `json:"name"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// IntegrationsResponse lists the integrations with their manifests
 type IntegrationsResponse struct {
-	Integrations []string `json:"integrations"`
+	// Integrations repeats the names of Details for installers that predate
+	// it. Deprecated in the OpenAPI document, kept for compatibility.
+	Integrations []string          `json:"integrations"`
+	Details      []IntegrationInfo `json:"details"`
+}
+
+// IntegrationInfo describes an integration from its integration.yaml. Only
+// Name and DisplayName are set when the integration has no manifest.
+type IntegrationInfo struct {
+	Name            string   `json:"name"`
+	DisplayName     string   `json:"display_name"`
+	Description     string   `json:"description,omitempty"`
+	Maintainer      string   `json:"maintainer,omitempty"`
+	MinVersion      string   `json:"min_version,omitempty"`
+	MaxVersion      string   `json:"max_version,omitempty"`
+	Ports           []int    `json:"ports,omitempty"`
+	Env             []string `json:"env,omitempty"`
+	RequiresDocker  string   `json:"requires_docker,omitempty"`
+	RequiresCompose string   `json:"requires_compose,omitempty"`
+	// Compatible is set when the request names a go-jo version
+	Compatible *bool `json:"compatible,omitempty"`
 }
 
+// ErrorResponse is the body of every error. Code is stable and meant for
+// clients to branch on, Message is for humans.
 type ErrorResponse struct {
 	Error   string `json:"error"`
+	Code    string `json:"code"`
 	Message string `json:"message,omitempty"`
 }
 
+// Error codes of ErrorResponse
+const (
+	ErrorCodeInvalidInput         = "invalid_input"
+	ErrorCodeUnauthorized         = "unauthorized"
+	ErrorCodeLicenseExpired       = "license_expired"
+	ErrorCodeLicenseRevoked       = "license_revoked"
+	ErrorCodeLicenseSuspended     = "license_suspended"
+	ErrorCodeForbidden            = "forbidden"
+	ErrorCodeNotFound             = "not_found"
+	ErrorCodeConflict             = "conflict"
+	ErrorCodeRateLimited          = "rate_limited"
+	ErrorCodeInternal             = "internal_error"
+	ErrorCodeUpstreamUnauthorized = "upstream_unauthorized"
+	ErrorCodeUpstreamRateLimited  = "upstream_rate_limited"
+	ErrorCodeUpstreamUnavailable  = "upstream_unavailable"
+)
+
+// AuditResponse is a page of GET /admin/audit. NextOffset is set when more
+// entries match after the page.
+type AuditResponse struct {
+	Entries    []audit.Entry `json:"entries"`
+	Count      int           `json:"count"`
+	Offset     int           `json:"offset"`
+	Limit      int           `json:"limit"`
+	NextOffset *int          `json:"next_offset,omitempty"`
+}
+
+// WebhookResponse reports what the API did with a webhook delivery
+type WebhookResponse struct {
+	Event string `json:"event"`
+	// Status is "processed", "ignored" (other repositories, refs or events) or "pong"
+	Status string `json:"status"`
+	// Evicted is the number of cached packages removed
+	Evicted int `json:"evicted"`
+	// Prebuild is set when the packages of the release are assembled in the background
+	Prebuild bool `json:"prebuild,omitempty"`
+}
+
+type LicensesResponse struct {
+	Licenses []*license.License `json:"licenses"`
+}
+
+// LicenseRequest creates (POST) or changes (PATCH) a license. Fields left out
+// of a PATCH keep their value.
+type LicenseRequest struct {
+	ID       string  `json:"id"`
+	Customer *string `json:"customer"`
+	// ExpiresAt is an RFC 3339 timestamp or a YYYY-MM-DD date, "" removes the expiry
+	ExpiresAt    *string             `json:"expires_at"`
+	Integrations *[]string           `json:"integrations"`
+	Versions     *string             `json:"versions"`
+	Channel      *string             `json:"channel"`
+	RateLimits   *license.RateLimits `json:"rate_limits"`
+}
+
+// IssuedLicenseResponse carries a new license token, returned only once
+type IssuedLicenseResponse struct {
+	License *license.License `json:"license"`
+	Token   string           `json:"token"`
+}
+
+type ReadinessResponse struct {
+	Status    string                 `json:"status"`
+	Timestamp string                 `json:"timestamp"`
+	Checks    map[string]CheckResult `json:"checks"`
+}
+
+type CheckResult struct {
+	Status     string  `json:"status"`
+	Message    string  `json:"message,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
 type HealthResponse struct {
 	Status    string `json:"status"`
 	Timestamp string `json:"timestamp"`
@@ -178,22 +564,90 @@ type HealthResponse struct {
 }
 
 // GitHub API structures
-type GitHubRelease struct {
-	TagName string `json:"tag_name"`
-	Draft   bool   `json:"draft"`
+type GitHubBranch struct {
+	Name   string       `json:"name"`
+	Commit GitHubCommit `json:"commit"`
 }
 
-type GitHubBranch struct {
-	Name string `json:"name"`
+type GitHubCommit struct {
+	SHA string `json:"sha"`
 }
 
 type GitHubAsset struct {
 	ID                 int    `json:"id"`
 	Name               string `json:"name"`
+	Size               int64  `json:"size"`
 	BrowserDownloadURL string `json:"browser_download_url"`
 }
 
+type GitHubRelease struct {
+	TagName     string     `json:"tag_name"`
+	Draft       bool       `json:"draft"`
+	Prerelease  bool       `json:"prerelease"`
+	Body        string     `json:"body"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
 type GitHubReleaseWithAssets struct {
-	TagName string        `json:"tag_name"`
-	Assets  []GitHubAsset `json:"assets"`
+	GitHubRelease
+	Assets []GitHubAsset `json:"assets"`
+}
+
+type GitHubRepository struct {
+	FullName string `json:"full_name"`
+}
+
+// GitHubWebhookEvent holds the fields of release and push webhook payloads
+// used by the API
+type GitHubWebhookEvent struct {
+	Action     string                   `json:"action"`
+	Repository GitHubRepository         `json:"repository"`
+	Release    *GitHubReleaseWithAssets `json:"release"`
+	// Ref, After and Deleted are set by push events
+	Ref     string `json:"ref"`
+	After   string `json:"after"`
+	Deleted bool   `json:"deleted"`
+}
+
+// GitLab API structures
+type GitLabBranch struct {
+	Name   string       `json:"name"`
+	Commit GitLabCommit `json:"commit"`
+}
+
+type GitLabCommit struct {
+	ID string `json:"id"`
+}
+
+type GitLabAssetLink struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	DirectAssetURL string `json:"direct_asset_url"`
+}
+
+type GitLabRelease struct {
+	TagName         string       `json:"tag_name"`
+	UpcomingRelease bool         `json:"upcoming_release"`
+	Description     string       `json:"description"`
+	ReleasedAt      *time.Time   `json:"released_at"`
+	Commit          GitLabCommit `json:"commit"`
+	Assets          struct {
+		Links []GitLabAssetLink `json:"links"`
+	} `json:"assets"`
+}
+
+// Gitea API structures. Releases and assets share the GitHub layout.
+type GiteaBranch struct {
+	Name   string      `json:"name"`
+	Commit GiteaCommit `json:"commit"`
+}
+
+type GiteaCommit struct {
+	ID string `json:"id"`
+}
+
+type GiteaTag struct {
+	Name   string       `json:"name"`
+	Commit GitHubCommit `json:"commit"`
 }
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/audit"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+)
+
+// AdminHandler handles the administration API
+type AdminHandler struct {
+	*BaseHandler
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(base *BaseHandler) *AdminHandler {
+	return &AdminHandler{
+		BaseHandler: base,
+	}
+}
+
+// AdminAuthMiddleware requires the admin token. Customer licenses are not
+// accepted, and the admin API is disabled while no admin token is configured.
+func (h *AdminHandler) AdminAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := h.Config.Admin.Token
+		if token == "" {
+			h.SendErrorResponse(w, r, http.StatusForbidden, "Admin API is disabled, set admin.token or ADMIN_TOKEN")
+			return
+		}
+
+		if !presentsToken(r, token) {
+			h.SendErrorResponse(w, r, http.StatusUnauthorized, "Invalid admin token")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// Page size of GET /admin/audit
+const (
+	defaultAuditPageSize = 1000
+	maxAuditPageSize     = 10000
+)
+
+// GetAuditLog handles GET /admin/audit - Audit log entries as JSON or CSV,
+// filtered by kind, action, license, version and time range, one page of
+// limit entries after offset at a time. JSON entries are streamed as they
+// are read from the log; a CSV page is sent once complete, with the offset
+// of the next page in X-Next-Offset.
+func (h *AdminHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Kind:    query.Get("kind"),
+		Action:  query.Get("action"),
+		License: query.Get("license"),
+		Version: query.Get("version"),
+	}
+
+	var err error
+	if filter.From, err = parseTimeParam(query.Get("from"), false); err != nil {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, "Invalid from: "+err.Error())
+		return
+	}
+	if filter.To, err = parseTimeParam(query.Get("to"), true); err != nil {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, "Invalid to: "+err.Error())
+		return
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid format %q, use json or csv", format))
+		return
+	}
+
+	limit, err := parseCountParam(query.Get("limit"), defaultAuditPageSize)
+	if err != nil || limit < 1 || limit > maxAuditPageSize {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid limit, use 1 to %d", maxAuditPageSize))
+		return
+	}
+	offset, err := parseCountParam(query.Get("offset"), 0)
+	if err != nil {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, "Invalid offset: "+err.Error())
+		return
+	}
+
+	var page auditPageWriter
+	if format == "csv" {
+		page = &csvAuditPage{w: w}
+	} else {
+		page = &jsonAuditPage{w: w}
+	}
+
+	// The response starts with the first entry, so a log that can't be
+	// opened still answers 500
+	started := false
+	skipped, count, more := 0, 0, false
+	var writeErr error
+	err = h.Audit.Scan(filter, func(e *audit.Entry) bool {
+		if skipped < offset {
+			skipped++
+			return true
+		}
+		if count == limit {
+			more = true
+			return false
+		}
+
+		if !started {
+			started = true
+			if writeErr = page.start(); writeErr != nil {
+				return false
+			}
+		}
+		writeErr = page.write(e)
+		count++
+		return writeErr == nil
+	})
+
+	logger := logging.Logger(r.Context())
+	switch {
+	case err != nil && !page.sent():
+		h.SendErrorResponse(w, r, http.StatusInternalServerError, "Failed to read audit log: "+err.Error())
+		return
+	case err != nil:
+		// Abort the connection rather than end a truncated page normally.
+		// The audit entry and the access line still record the failure.
+		message := "Failed to read audit log: " + err.Error()
+		logger.Error("Failed to read audit log", "error", err)
+		if entry := auditEntry(r); entry != nil {
+			entry.Result = audit.ResultFailed
+			entry.Message = message
+		}
+		logging.SetError(r.Context(), message)
+		panic(http.ErrAbortHandler)
+	case writeErr != nil:
+		logger.Warn("Failed to write audit log", "error", writeErr)
+		return
+	}
+
+	if !started {
+		if writeErr = page.start(); writeErr != nil {
+			logger.Warn("Failed to write audit log", "error", writeErr)
+			return
+		}
+	}
+
+	response := domain.AuditResponse{Count: count, Offset: offset, Limit: limit}
+	if more {
+		next := offset + count
+		response.NextOffset = &next
+	}
+	if err := page.finish(&response); err != nil {
+		logger.Warn("Failed to write audit log", "error", err)
+	}
+}
+
+// auditPageWriter streams a page of audit entries in a response format
+type auditPageWriter interface {
+	// start writes the headers and whatever precedes the entries
+	start() error
+	write(e *audit.Entry) error
+	// finish writes what follows the entries, from the page fields of response
+	finish(response *domain.AuditResponse) error
+	// sent reports whether the response status has been sent
+	sent() bool
+}
+
+// jsonAuditPage writes an AuditResponse, entries first so they can be
+// written as they are read
+type jsonAuditPage struct {
+	w     http.ResponseWriter
+	buf   *bufio.Writer
+	count int
+}
+
+func (p *jsonAuditPage) start() error {
+	p.w.Header().Set("Content-Type", "application/json")
+	p.w.WriteHeader(http.StatusOK)
+	p.buf = bufio.NewWriter(p.w)
+	_, err := p.buf.WriteString(`{"entries":[`)
+	return err
+}
+
+func (p *jsonAuditPage) sent() bool {
+	return p.buf != nil
+}
+
+func (p *jsonAuditPage) write(e *audit.Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if p.count > 0 {
+		p.buf.WriteByte(',')
+	}
+	p.count++
+	_, err = p.buf.Write(data)
+	return err
+}
+
+func (p *jsonAuditPage) finish(response *domain.AuditResponse) error {
+	// The page fields follow the entries in the same object
+	trailer, err := json.Marshal(struct {
+		Count      int  `json:"count"`
+		Offset     int  `json:"offset"`
+		Limit      int  `json:"limit"`
+		NextOffset *int `json:"next_offset,omitempty"`
+	}{response.Count, response.Offset, response.Limit, response.NextOffset})
+	if err != nil {
+		return err
+	}
+
+	p.buf.WriteString("],")
+	p.buf.Write(trailer[1:])
+	p.buf.WriteByte('\n')
+	return p.buf.Flush()
+}
+
+// csvAuditPage writes the entries as CSV. CSV has no room for the page
+// fields, so the rows are kept until the page is complete and the offset of
+// the next page is sent in the X-Next-Offset header.
+type csvAuditPage struct {
+	w      http.ResponseWriter
+	buf    bytes.Buffer
+	writer *audit.CSVWriter
+	done   bool
+}
+
+func (p *csvAuditPage) start() error {
+	var err error
+	p.writer, err = audit.NewCSVWriter(&p.buf)
+	return err
+}
+
+func (p *csvAuditPage) write(e *audit.Entry) error {
+	return p.writer.Write(e)
+}
+
+func (p *csvAuditPage) finish(response *domain.AuditResponse) error {
+	if err := p.writer.Flush(); err != nil {
+		return err
+	}
+
+	p.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	p.w.Header().Set("Content-Disposition", "attachment; filename=audit.csv")
+	if response.NextOffset != nil {
+		p.w.Header().Set("X-Next-Offset", strconv.Itoa(*response.NextOffset))
+	}
+	p.w.WriteHeader(http.StatusOK)
+	p.done = true
+
+	_, err := p.buf.WriteTo(p.w)
+	return err
+}
+
+func (p *csvAuditPage) sent() bool {
+	return p.done
+}
+
+// parseCountParam parses a non-negative integer query parameter, returning
+// fallback when it is empty
+func parseCountParam(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a non-negative integer", value)
+	}
+	return n, nil
+}
+
+// parseTimeParam parses an RFC 3339 timestamp or a YYYY-MM-DD date (UTC).
+// A date used as an upper bound includes the whole day.
+func parseTimeParam(value string, upper bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 timestamp nor a YYYY-MM-DD date", value)
+	}
+	if upper {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/audit"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+)
+
+// newAuditHandler returns an admin handler on an audit log holding one
+// download per day of March 2026, on the given number of days
+func newAuditHandler(t *testing.T, days int) *AdminHandler {
+	t.Helper()
+
+	base := newTestBase(t, newFakeSource())
+	auditLog, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("audit.Open error = %v", err)
+	}
+	t.Cleanup(func() { auditLog.Close() })
+	base.Audit = auditLog
+
+	for d := 1; d <= days; d++ {
+		if err := auditLog.Record(audit.Entry{
+			Time:        time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC),
+			Kind:        audit.KindDownload,
+			License:     "acme",
+			Integration: fmt.Sprintf("day-%02d", d),
+			Result:      audit.ResultSuccess,
+		}); err != nil {
+			t.Fatalf("Record error = %v", err)
+		}
+	}
+	return NewAdminHandler(base)
+}
+
+func getAudit(h *AdminHandler, query string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.GetAuditLog(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/audit?"+query, nil))
+	return rec
+}
+
+func TestGetAuditLogPages(t *testing.T) {
+	h := newAuditHandler(t, 10)
+
+	next := func(n int) *int { return &n }
+	tests := []struct {
+		name      string
+		query     string
+		wantFirst string
+		wantCount int
+		wantNext  *int
+	}{
+		{"default page", "", "day-01", 10, nil},
+		{"first page", "limit=4", "day-01", 4, next(4)},
+		{"middle page", "limit=4&offset=4", "day-05", 4, next(8)},
+		{"last page", "limit=4&offset=8", "day-09", 2, nil},
+		{"exact last page", "limit=5&offset=5", "day-06", 5, nil},
+		{"past the end", "offset=20", "", 0, nil},
+		{"filtered", "from=2026-03-03&to=2026-03-06&limit=2", "day-03", 2, next(2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := getAudit(h, tt.query)
+			if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+				t.Fatalf("status = %d %q, want 200 JSON", rec.Code, rec.Header().Get("Content-Type"))
+			}
+
+			var response domain.AuditResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decoding %s: %v", rec.Body, err)
+			}
+			if response.Count != tt.wantCount || len(response.Entries) != tt.wantCount {
+				t.Errorf("count = %d with %d entries, want %d", response.Count, len(response.Entries), tt.wantCount)
+			}
+			if tt.wantCount > 0 && response.Entries[0].Integration != tt.wantFirst {
+				t.Errorf("first entry = %s, want %s", response.Entries[0].Integration, tt.wantFirst)
+			}
+			if (response.NextOffset == nil) != (tt.wantNext == nil) ||
+				(tt.wantNext != nil && *response.NextOffset != *tt.wantNext) {
+				t.Errorf("next_offset = %v, want %v", response.NextOffset, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestGetAuditLogEmptyPage(t *testing.T) {
+	rec := getAudit(newAuditHandler(t, 0), "")
+
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	if string(response["entries"]) != "[]" || string(response["count"]) != "0" || string(response["limit"]) != "1000" {
+		t.Errorf("response = %s, want no entries and the default limit", rec.Body)
+	}
+}
+
+func TestGetAuditLogCSV(t *testing.T) {
+	rec := getAudit(newAuditHandler(t, 10), "format=csv&limit=3&offset=1")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("status = %d %q, want 200 CSV", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	if len(records) != 4 || records[0][0] != "time" || records[1][7] != "day-02" || records[3][7] != "day-04" {
+		t.Errorf("CSV = %v, want a header and days 2 to 4", records)
+	}
+	if next := rec.Header().Get("X-Next-Offset"); next != "4" {
+		t.Errorf("X-Next-Offset = %q, want 4", next)
+	}
+
+	// The last page has no next offset
+	rec = getAudit(newAuditHandler(t, 10), "format=csv&limit=3&offset=7")
+	if next, ok := rec.Header()["X-Next-Offset"]; ok {
+		t.Errorf("X-Next-Offset of the last page = %q, want none", next)
+	}
+
+	// An empty page still has its header row
+	rec = getAudit(newAuditHandler(t, 0), "format=csv")
+	if records, err := csv.NewReader(rec.Body).ReadAll(); err != nil || len(records) != 1 {
+		t.Errorf("empty CSV = %v, %v, want the header row", records, err)
+	}
+}
+
+func TestGetAuditLogInvalidParams(t *testing.T) {
+	h := newAuditHandler(t, 1)
+
+	for _, query := range []string{
+		"limit=0",
+		"limit=10001",
+		"limit=-1",
+		"limit=ten",
+		"offset=-5",
+		"format=xml",
+		"from=yesterday",
+		"to=2026-13-01",
+	} {
+		if rec := getAudit(h, query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestGetAuditLogReadFailure(t *testing.T) {
+	h := newAuditHandler(t, 3)
+
+	// An entry too long to read back fails the scan after the first entries
+	file, err := os.OpenFile(h.Audit.Path(), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("opening audit log: %v", err)
+	}
+	file.WriteString(`{"message":"` + strings.Repeat("x", 2*1024*1024) + "\"}\n")
+	file.Close()
+
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	handler := logging.Middleware(h.AdminAuditMiddleware("audit.export", h.GetAuditLog),
+		func(r *http.Request) string { return "/v1/admin/audit" })
+
+	// The entries already sent as JSON can't be taken back, so the
+	// response is aborted
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != http.ErrAbortHandler {
+				t.Errorf("recovered %v, want http.ErrAbortHandler", recovered)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/admin/audit", nil))
+	}()
+
+	// The log can't be scanned past the long entry, the export is recorded last
+	data, err := os.ReadFile(h.Audit.Path())
+	if err != nil {
+		t.Fatalf("reading audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var recorded audit.Entry
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &recorded); err != nil {
+		t.Fatalf("decoding the last entry: %v", err)
+	}
+	if recorded.Action != "audit.export" || recorded.Result != audit.ResultFailed ||
+		!strings.HasPrefix(recorded.Message, "Failed to read audit log") {
+		t.Errorf("recorded %+v, want the failed export", recorded)
+	}
+	if !strings.Contains(logs.String(), `"msg":"request"`) || !strings.Contains(logs.String(), `"error":"Failed to read audit log`) {
+		t.Errorf("logs = %s, want an access line with the error", logs.String())
+	}
+
+	// A CSV page is sent once complete, so it still answers 500
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/audit?format=csv", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("CSV status = %d, want 500", rec.Code)
+	}
+}
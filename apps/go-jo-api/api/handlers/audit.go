@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/audit"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+)
+
+// AuditMiddleware records every download attempt in the audit log once the
+// response is done. It must wrap AuthMiddleware and the rate limits so refused
+// attempts are recorded too.
+func (h *BaseHandler) AuditMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		h.audited(&audit.Entry{
+			Kind:        audit.KindDownload,
+			Version:     vars["app_version"],
+			Integration: strings.ReplaceAll(vars["integration"], "@", "/"),
+		}, next, w, r)
+	}
+}
+
+// AdminAuditMiddleware records an admin action in the audit log once the
+// response is done. The license acted upon is taken from the {id} route
+// variable, or set by the handler. It must wrap AdminAuthMiddleware so
+// refused attempts are recorded too.
+func (h *BaseHandler) AdminAuditMiddleware(action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.audited(&audit.Entry{
+			Kind:    audit.KindAdmin,
+			Action:  action,
+			License: mux.Vars(r)["id"],
+		}, next, w, r)
+	}
+}
+
+// audited serves the request with the entry in its context, then completes
+// the entry with the client and the outcome and records it
+func (h *BaseHandler) audited(entry *audit.Entry, next http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	if !h.Audit.Enabled() {
+		next(w, r)
+		return
+	}
+
+	start := time.Now()
+	entry.Time = start
+	entry.ClientIP = clientIP(r)
+	entry.ForwardedFor = r.Header.Get("X-Forwarded-For")
+	entry.UserAgent = r.UserAgent()
+
+	recorder := logging.WrapResponseWriter(w)
+	defer h.record(entry, recorder, r, start)
+
+	next(recorder, r.WithContext(audit.NewContext(r.Context(), entry)))
+}
+
+// record completes the entry with the outcome of the response and records
+// it. It runs deferred so responses aborted with http.ErrAbortHandler are
+// recorded too; their handler sets the result.
+func (h *BaseHandler) record(entry *audit.Entry, recorder *logging.ResponseRecorder, r *http.Request, start time.Time) {
+	if info := logging.FromContext(r.Context()); info != nil {
+		entry.RequestID = info.ID
+		if entry.License == "" {
+			entry.License = info.LicenseID
+		}
+	}
+	entry.Status = recorder.Status()
+	entry.Bytes = recorder.Bytes()
+	entry.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+	if entry.Result == "" {
+		entry.Result = audit.Result(entry.Status, r.Context().Err() != nil)
+	}
+
+	if err := h.Audit.Record(*entry); err != nil {
+		logging.Logger(r.Context()).Error("Failed to record audit entry", "error", err)
+	}
+}
+
+// auditEntry returns the audit entry of the request, nil when it is not audited
+func auditEntry(r *http.Request) *audit.Entry {
+	return audit.FromContext(r.Context())
+}
+
+// clientIP returns the address of the peer that sent the request
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrShuttingDown is returned for background work started after Stop
+var ErrShuttingDown = errors.New("the service is shutting down")
+
+// Background tracks the work that outlives the request starting it, such as
+// release prebuilds and package assemblies shared between requests. Stop
+// cancels it and waits for it, so its temp dirs are only removed once it is
+// done with them.
+type Background struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu orders starting tasks with Stop, so none is added while waiting
+	mu    sync.Mutex
+	tasks sync.WaitGroup
+}
+
+// NewBackground creates a tracker for background work
+func NewBackground() *Background {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Background{ctx: ctx, cancel: cancel}
+}
+
+// Go runs a task in a goroutine with a context cancelled by Stop. It reports
+// false when the service is already stopping and the task was not started.
+func (b *Background) Go(task func(ctx context.Context)) bool {
+	if !b.start() {
+		return false
+	}
+
+	go func() {
+		defer b.tasks.Done()
+		task(b.ctx)
+	}()
+	return true
+}
+
+// Run runs a task with the values of ctx, but cancelled by Stop rather than
+// by ctx, so it goes on when the request starting it goes away
+func (b *Background) Run(ctx context.Context, task func(ctx context.Context) error) error {
+	if !b.start() {
+		return ErrShuttingDown
+	}
+	defer b.tasks.Done()
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	stop := context.AfterFunc(b.ctx, cancel)
+	defer stop()
+
+	return task(ctx)
+}
+
+// Stop cancels the background work and waits for it to return, or for ctx
+// to be done. Tasks started afterwards are refused.
+func (b *Background) Stop(ctx context.Context) error {
+	b.mu.Lock()
+	b.cancel()
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.tasks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// start counts a new task unless the work was stopped
+func (b *Background) start() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ctx.Err() != nil {
+		return false
+	}
+	b.tasks.Add(1)
+	return true
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackgroundStopCancelsAndWaits(t *testing.T) {
+	b := NewBackground()
+
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	if !b.Go(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		close(finished)
+	}) {
+		t.Fatal("Go refused a task before Stop")
+	}
+	<-started
+
+	if err := b.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop error = %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Fatal("Stop returned before the task finished")
+	}
+
+	if b.Go(func(ctx context.Context) {}) {
+		t.Error("Go started a task after Stop")
+	}
+	if err := b.Run(context.Background(), func(ctx context.Context) error { return nil }); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Run after Stop error = %v, want ErrShuttingDown", err)
+	}
+}
+
+func TestBackgroundRunOutlivesRequest(t *testing.T) {
+	b := NewBackground()
+	request, cancelRequest := context.WithCancel(context.Background())
+
+	running := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- b.Run(request, func(ctx context.Context) error {
+			close(running)
+			cancelRequest()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(20 * time.Millisecond):
+				return nil
+			}
+		})
+	}()
+	<-running
+
+	if err := <-result; err != nil {
+		t.Fatalf("Run error = %v, want the task to outlive the request", err)
+	}
+
+	// Stop cancels running tasks
+	running = make(chan struct{})
+	go func() {
+		result <- b.Run(context.Background(), func(ctx context.Context) error {
+			close(running)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+	<-running
+
+	if err := b.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop error = %v", err)
+	}
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run error = %v, want context.Canceled", err)
+	}
+}
+
+func TestBackgroundStopTimeout(t *testing.T) {
+	b := NewBackground()
+
+	release := make(chan struct{})
+	defer close(release)
+	b.Go(func(ctx context.Context) { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop error = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -1,46 +1,98 @@
 package handlers
 
 import (
-	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/audit"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/cache"
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/license"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/ratelimit"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/source"
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/licensefile"
 )
 
 // BaseHandler contains common functionality for all handlers
 type BaseHandler struct {
-	Config *domain.Config
+	Config   *domain.Config
+	Licenses *license.Store
+	Cache    *cache.Cache
+	Source   source.ArtifactSource
+	Audit    *audit.Log
+
+	// Shared by all handlers so limits apply across routes
+	RateLimiter *ratelimit.Limiter
+	Downloads   *ratelimit.Concurrency
+
+	// Background is the work cancelled and waited for on shutdown
+	Background *Background
 }
 
 // NewBaseHandler creates a new base handler
-func NewBaseHandler(config *domain.Config) *BaseHandler {
+func NewBaseHandler(config *domain.Config, licenses *license.Store, packageCache *cache.Cache, artifactSource source.ArtifactSource, auditLog *audit.Log) *BaseHandler {
 	return &BaseHandler{
-		Config: config,
+		Config:   config,
+		Licenses: licenses,
+		Cache:    packageCache,
+		Source:   artifactSource,
+		Audit:    auditLog,
+
+		RateLimiter: ratelimit.NewLimiter(),
+		Downloads:   ratelimit.NewConcurrency(),
+
+		Background: NewBackground(),
 	}
 }
 
-// AuthMiddleware validates the authorization token
+// AuthMiddleware validates the authorization token and stores the resolved
+// license in the request context
 func (h *BaseHandler) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			h.SendErrorResponse(w, http.StatusUnauthorized, "Authorization header is required")
+			h.SendErrorResponse(w, r, http.StatusUnauthorized, "Authorization header is required")
 			return
 		}
 
-		if authHeader != h.Config.LicenseToken {
-			h.SendErrorResponse(w, http.StatusUnauthorized, "Invalid authorization token")
+		lic, err := h.Licenses.Authenticate(authHeader)
+		if err != nil {
+			switch {
+			case errors.Is(err, license.ErrExpired):
+				h.SendErrorCode(w, r, http.StatusUnauthorized, domain.ErrorCodeLicenseExpired, "License has expired")
+			case errors.Is(err, license.ErrRevoked):
+				h.SendErrorCode(w, r, http.StatusUnauthorized, domain.ErrorCodeLicenseRevoked, "License has been revoked")
+			case errors.Is(err, license.ErrSuspended):
+				h.SendErrorCode(w, r, http.StatusUnauthorized, domain.ErrorCodeLicenseSuspended, "License has been suspended")
+			case errors.Is(err, licensefile.ErrInvalidSignature), errors.Is(err, licensefile.ErrMalformed):
+				h.SendErrorResponse(w, r, http.StatusUnauthorized, "Invalid license: "+err.Error())
+			default:
+				h.SendErrorResponse(w, r, http.StatusUnauthorized, "Invalid authorization token")
+			}
 			return
 		}
 
-		next(w, r)
+		logging.SetLicenseID(r.Context(), lic.ID)
+		next(w, r.WithContext(license.NewContext(r.Context(), lic)))
 	}
 }
 
+// LicenseFromRequest returns the license resolved by AuthMiddleware
+func (h *BaseHandler) LicenseFromRequest(r *http.Request) *license.License {
+	lic, _ := license.FromContext(r.Context())
+	return lic
+}
+
 // SendJSONResponse sends a JSON response with the specified status and data
 func (h *BaseHandler) SendJSONResponse(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -48,72 +100,117 @@ func (h *BaseHandler) SendJSONResponse(w http.ResponseWriter, status int, data i
 	json.NewEncoder(w).Encode(data)
 }
 
-// SendErrorResponse sends an error response
-func (h *BaseHandler) SendErrorResponse(w http.ResponseWriter, status int, message string) {
-	log.Printf("Error: %s", message)
+// SendErrorResponse sends an error response with the default code of its
+// status and logs it with the request ID
+func (h *BaseHandler) SendErrorResponse(w http.ResponseWriter, r *http.Request, status int, message string) {
+	h.SendErrorCode(w, r, status, errorCode(status), message)
+}
+
+// SendErrorCode sends an error response with a specific code and logs it with
+// the request ID
+func (h *BaseHandler) SendErrorCode(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	level := slog.LevelWarn
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logging.Logger(r.Context()).Log(r.Context(), level, "Error: "+message, "status", status, "code", code)
+
+	if entry := auditEntry(r); entry != nil {
+		entry.Message = message
+	}
+
 	h.SendJSONResponse(w, status, domain.ErrorResponse{
 		Error:   http.StatusText(status),
+		Code:    code,
 		Message: message,
 	})
 }
 
-// SendFileResponse sends a file response
-func (h *BaseHandler) SendFileResponse(w http.ResponseWriter, filePath, filename string) {
-	// Open and read the file
-	fileData, err := os.ReadFile(filePath)
-	if err != nil {
-		h.SendErrorResponse(w, http.StatusInternalServerError, "Failed to read file: "+err.Error())
-		return
-	}
-
-	// Get file info for size and mod time
-	fileInfo, err := os.Stat(filePath)
-	if err != nil {
-		h.SendErrorResponse(w, http.StatusInternalServerError, "Failed to get file info: "+err.Error())
-		return
+// SendSourceError answers a failed artifact source call with the status and
+// code matching its error:
+//   - unknown version or integration: 404 not_found
+//   - name that can't be looked up: 400 invalid_input
+//   - credentials refused by the upstream: 502 upstream_unauthorized
+//   - exhausted upstream rate limit: 503 upstream_rate_limited with Retry-After
+//   - unreachable or failing upstream: 503 upstream_unavailable
+//
+// Anything else answers 500.
+func (h *BaseHandler) SendSourceError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	message += ": " + err.Error()
+
+	var rateLimited *source.RateLimitError
+	switch {
+	case errors.As(err, &rateLimited):
+		setRetryAfter(w, rateLimited.RetryAfter)
+		h.SendErrorCode(w, r, http.StatusServiceUnavailable, domain.ErrorCodeUpstreamRateLimited, message)
+	case errors.Is(err, source.ErrNotFound):
+		h.SendErrorCode(w, r, http.StatusNotFound, domain.ErrorCodeNotFound, message)
+	case errors.Is(err, source.ErrInvalidInput):
+		h.SendErrorCode(w, r, http.StatusBadRequest, domain.ErrorCodeInvalidInput, message)
+	case errors.Is(err, source.ErrUpstreamUnauthorized):
+		h.SendErrorCode(w, r, http.StatusBadGateway, domain.ErrorCodeUpstreamUnauthorized, message)
+	case errors.Is(err, source.ErrUpstreamUnavailable):
+		h.SendErrorCode(w, r, http.StatusServiceUnavailable, domain.ErrorCodeUpstreamUnavailable, message)
+	default:
+		h.SendErrorCode(w, r, http.StatusInternalServerError, domain.ErrorCodeInternal, message)
 	}
+}
 
-	// Set headers
-	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(fileData)))
-	w.Header().Set("Last-Modified", fileInfo.ModTime().UTC().Format(http.TimeFormat))
-
-	// Write the file data directly
-	w.WriteHeader(http.StatusOK)
-	_, err = w.Write(fileData)
-	if err != nil {
-		log.Printf("Error writing file response: %v", err)
+// errorCode returns the default error code of a status
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return domain.ErrorCodeInvalidInput
+	case http.StatusUnauthorized:
+		return domain.ErrorCodeUnauthorized
+	case http.StatusForbidden:
+		return domain.ErrorCodeForbidden
+	case http.StatusNotFound:
+		return domain.ErrorCodeNotFound
+	case http.StatusConflict:
+		return domain.ErrorCodeConflict
+	case http.StatusTooManyRequests:
+		return domain.ErrorCodeRateLimited
+	case http.StatusServiceUnavailable:
+		return domain.ErrorCodeUpstreamUnavailable
+	default:
+		return domain.ErrorCodeInternal
 	}
 }
 
-// FetchFromGitHub makes authenticated requests to GitHub API
-func (h *BaseHandler) FetchFromGitHub(url string, result interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), h.Config.GetRequestTimeout())
-	defer cancel()
+// presentsToken reports whether the request presents a configured token as
+// its Authorization header, with or without the Bearer prefix
+func presentsToken(r *http.Request, token string) bool {
+	presented := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return err
+// setRetryAfter sets the Retry-After header in whole seconds, at least one
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
 	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
 
-	req.Header.Set("Authorization", "token "+h.Config.GitHubToken)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
+// SendFileResponse serves an opened file from disk. The file is streamed
+// with http.ServeContent instead of being loaded into memory, and is closed
+// once the response is done, also when the client disconnects halfway.
+func (h *BaseHandler) SendFileResponse(w http.ResponseWriter, r *http.Request, file *os.File, filename string) {
+	defer file.Close()
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// Get file info for mod time
+	fileInfo, err := file.Stat()
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("GitHub API error: %d", resp.StatusCode)
+		h.SendErrorResponse(w, r, http.StatusInternalServerError, "Failed to get file info: "+err.Error())
+		return
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
-		return err
-	}
+	// Set headers
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
 
-	return nil
+	// Sets Content-Length and Last-Modified and copies the file in chunks
+	http.ServeContent(w, r, filename, fileInfo.ModTime(), file)
 }
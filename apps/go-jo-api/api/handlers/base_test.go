@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/source"
+)
+
+func TestSendSourceError(t *testing.T) {
+	h := newTestBase(t, newFakeSource())
+
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantCode       string
+		wantRetryAfter string
+	}{
+		{"rate limited", &source.RateLimitError{Upstream: "GitHub API", RetryAfter: 90 * time.Second}, http.StatusServiceUnavailable, domain.ErrorCodeUpstreamRateLimited, "90"},
+		{"rate limited, wrapped", fmt.Errorf("download failed: %w", &source.RateLimitError{Upstream: "GitHub API", RetryAfter: 1500 * time.Millisecond}), http.StatusServiceUnavailable, domain.ErrorCodeUpstreamRateLimited, "2"},
+		{"rate limit reset", &source.RateLimitError{Upstream: "GitHub API"}, http.StatusServiceUnavailable, domain.ErrorCodeUpstreamRateLimited, "1"},
+		{"not found", fmt.Errorf("version v9.9.9 %w", source.ErrNotFound), http.StatusNotFound, domain.ErrorCodeNotFound, ""},
+		{"invalid input", fmt.Errorf("%w: integration name", source.ErrInvalidInput), http.StatusBadRequest, domain.ErrorCodeInvalidInput, ""},
+		{"upstream unauthorized", fmt.Errorf("%w: GitHub API answered 401", source.ErrUpstreamUnauthorized), http.StatusBadGateway, domain.ErrorCodeUpstreamUnauthorized, ""},
+		{"upstream unavailable", fmt.Errorf("%w: GitHub API answered 502", source.ErrUpstreamUnavailable), http.StatusServiceUnavailable, domain.ErrorCodeUpstreamUnavailable, ""},
+		{"anything else", errors.New("GitHub API error: 422"), http.StatusInternalServerError, domain.ErrorCodeInternal, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.SendSourceError(w, httptest.NewRequest(http.MethodGet, "/v1/versions", nil), "Failed to fetch releases", tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if retryAfter := w.Header().Get("Retry-After"); retryAfter != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", retryAfter, tt.wantRetryAfter)
+			}
+
+			var response domain.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decoding %s: %v", w.Body, err)
+			}
+			if response.Code != tt.wantCode || response.Error != http.StatusText(tt.wantStatus) ||
+				!strings.HasPrefix(response.Message, "Failed to fetch releases: ") {
+				t.Errorf("response = %+v, want code %s", response, tt.wantCode)
+			}
+		})
+	}
+}
@@ -3,28 +3,37 @@ package handlers
 import (
 	"archive/zip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/cache"
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/metrics"
+	"golang.org/x/sync/singleflight"
 )
 
 // DownloadHandler handles download-related requests
 type DownloadHandler struct {
 	*BaseHandler
 	versionsHandler *VersionsHandler
+
+	// assembling shares the assembly of a package between the requests and
+	// prebuilds missing the cache for the same key at once
+	assembling singleflight.Group
 }
 
 // NewDownloadHandler creates a new download handler
-func NewDownloadHandler(config *domain.Config, versionsHandler *VersionsHandler) *DownloadHandler {
+func NewDownloadHandler(base *BaseHandler, versionsHandler *VersionsHandler) *DownloadHandler {
 	return &DownloadHandler{
-		BaseHandler:     NewBaseHandler(config),
+		BaseHandler:     base,
 		versionsHandler: versionsHandler,
 	}
 }
@@ -34,187 +43,251 @@ func (h *DownloadHandler) DownloadPackage(w http.ResponseWriter, r *http.Request
 	vars := mux.Vars(r)
 	appVersion := vars["app_version"]
 	integration := vars["integration"]
+	branch := strings.ReplaceAll(integration, "@", "/")
 	integration = strings.ReplaceAll(integration, "@", "%2F")
 
-	log.Printf("Download request: version=%s, integration=%s", appVersion, integration)
+	// Assembling and sending a package takes longer than server.write_timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(h.Config.GetDownloadWriteTimeout())); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logging.Logger(r.Context()).Warn("Failed to extend the write deadline", "error", err)
+	}
+
+	metrics.DownloadsInFlight.Inc()
+	defer metrics.DownloadsInFlight.Dec()
+	start := time.Now()
+
+	lic := h.LicenseFromRequest(r)
+	logger := logging.Logger(r.Context())
+	logger.Info("Download request", "version", appVersion, "integration", branch)
+
+	// Refuse integrations the license is not entitled to
+	if !lic.AllowsIntegration(branch) {
+		h.SendErrorResponse(w, r, http.StatusForbidden, fmt.Sprintf("License %s is not entitled to integration %s", lic.ID, branch))
+		return
+	}
+
+	ctx := r.Context()
 
 	// Handle "latest" version
 	if appVersion == "latest" {
-		latest, err := h.versionsHandler.GetLatestVersion()
+		latest, err := h.versionsHandler.GetLatestVersion(ctx, lic)
 		if err != nil {
-			h.SendErrorResponse(w, http.StatusInternalServerError, "Failed to get latest version: "+err.Error())
+			h.SendSourceError(w, r, "Failed to get latest version", err)
 			return
 		}
 		appVersion = latest
-		log.Printf("Resolved 'latest' to version: %s", appVersion)
+		logger.Info("Resolved 'latest' version", "version", appVersion)
 	}
 
-	// Create temporary directory
-	tempDir, err := os.MkdirTemp("", h.Config.GetTempDirPrefix())
+	// Fetch release with its app package
+	release, err := h.Source.GetVersion(ctx, appVersion)
 	if err != nil {
-		h.SendErrorResponse(w, http.StatusInternalServerError, "Failed to create temp directory: "+err.Error())
+		h.SendSourceError(w, r, "Failed to download app", err)
 		return
 	}
-	defer os.RemoveAll(tempDir) // Clean up
 
-	// Download app .deb file
-	debPath, err := h.downloadAppDeb(appVersion, tempDir)
-	if err != nil {
-		h.SendErrorResponse(w, http.StatusInternalServerError, "Failed to download app: "+err.Error())
+	// Refuse versions outside the license's range or channel
+	if !lic.AllowsVersion(release.Version, isPrerelease(*release)) {
+		h.SendErrorResponse(w, r, http.StatusForbidden, fmt.Sprintf("License %s is not entitled to version %s", lic.ID, release.Version))
 		return
 	}
 
-	// Download integration branch as zip
-	integrationZipPath, err := h.downloadIntegrationZip(integration, tempDir)
-	if err != nil {
-		h.SendErrorResponse(w, http.StatusInternalServerError, "Failed to download integration: "+err.Error())
+	if entry := auditEntry(r); entry != nil {
+		entry.ResolvedVersion = release.Version
+	}
+
+	if release.AppPackage == nil {
+		h.SendErrorResponse(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to download app: no .deb file found in release %s", release.Version))
 		return
 	}
 
-	// Create combined zip
-	combinedZipPath, err := h.createCombinedZip(debPath, integrationZipPath, integration, tempDir)
+	// Resolve the current commit of the integration
+	resolved, err := h.Source.GetIntegration(ctx, branch)
 	if err != nil {
-		h.SendErrorResponse(w, http.StatusInternalServerError, "Failed to create combined package: "+err.Error())
+		h.SendSourceError(w, r, "Failed to download integration", err)
 		return
 	}
 
-	// Send file
-	h.SendFileResponse(w, combinedZipPath, fmt.Sprintf("go-jo-%s.zip", integration))
-}
+	if entry := auditEntry(r); entry != nil {
+		entry.Commit = resolved.Commit
+	}
 
-// downloadAppDeb downloads the .deb file for a specific version
-func (h *DownloadHandler) downloadAppDeb(version, tempDir string) (string, error) {
-	// Fetch release with assets
-	url := fmt.Sprintf("%s/repos/%s/releases/tags/%s", h.Config.GetGitHubAPIBaseURL(), h.Config.GetGoJoRepo(), version)
+	filename := fmt.Sprintf("go-jo-%s.zip", integration)
+	key := cache.Key{PackageID: release.AppPackage.ID, Integration: branch, Commit: resolved.Commit}
 
-	var release domain.GitHubReleaseWithAssets
-	err := h.FetchFromGitHub(url, &release)
-	if err != nil {
-		return "", err
-	}
+	// The package content only depends on the app package and the integration
+	// commit, so the ETag is known before anything is assembled.
+	// http.ServeContent uses it for If-Range and If-None-Match.
+	etag := fmt.Sprintf("\"%s-%s\"", release.AppPackage.ID, resolved.Commit)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Accept-Ranges", "bytes")
 
-	// Find .deb asset
-	var debAsset *domain.GitHubAsset
-	for _, asset := range release.Assets {
-		if strings.HasSuffix(asset.Name, ".deb") && !strings.Contains(asset.Name, "api") {
-			debAsset = &asset
-			break
-		}
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
 
-	if debAsset == nil {
-		return "", fmt.Errorf("no .deb file found in release %s", version)
+	// Count the bytes actually sent once the package is served
+	defer func() {
+		recorder := logging.WrapResponseWriter(w)
+		metrics.DownloadBytes.Add(float64(recorder.Bytes()), release.Version, branch)
+		metrics.DownloadDuration.Observe(time.Since(start).Seconds(), release.Version, branch)
+	}()
+
+	// Serve straight from the cache when the package was already assembled
+	if cached, ok := h.Cache.Open(key); ok {
+		metrics.CacheHits.Inc()
+		logger.Info("Serving cached package", "version", release.Version, "integration", branch, "commit", resolved.Commit)
+		h.SendFileResponse(w, r, cached, filename)
+		return
 	}
 
-	// Download .deb file using the asset ID (for private repos)
-	debPath := filepath.Join(tempDir, h.Config.GetDebAppName())
-	return debPath, h.downloadGitHubAsset(debAsset.ID, debPath)
-}
+	metrics.CacheMisses.Inc()
 
-// downloadIntegrationZip downloads the integration branch as a zip file
-func (h *DownloadHandler) downloadIntegrationZip(integration, tempDir string) (string, error) {
-	// Use GitHub API to get the archive URL for the branch
-	url := fmt.Sprintf("%s/repos/%s/zipball/%s", h.Config.GetGitHubAPIBaseURL(), h.Config.GetDockerEnvRepo(), integration)
+	combinedZip, err := h.assemblePackage(ctx, key, release.AppPackage, resolved)
+	if err != nil {
+		h.SendSourceError(w, r, "Failed to assemble package", err)
+		return
+	}
 
-	zipPath := filepath.Join(tempDir, "integration.zip")
-	return zipPath, h.downloadGitHubArchive(url, zipPath)
+	// Send file
+	h.SendFileResponse(w, r, combinedZip, filename)
 }
 
-// downloadGitHubAsset downloads a GitHub asset by ID (works for private repos)
-func (h *DownloadHandler) downloadGitHubAsset(assetID int, filepath string) error {
-	url := fmt.Sprintf("%s/repos/%s/releases/assets/%d", h.Config.GetGitHubAPIBaseURL(), h.Config.GetGoJoRepo(), assetID)
-
-	ctx, cancel := context.WithTimeout(context.Background(), h.Config.Server.ReadTimeout)
-	defer cancel()
+// assemblePackage returns the package of the key opened for reading,
+// assembling it unless another request is already doing so, in which case it
+// waits for that one and opens the result from the cache. The assembly is not
+// cancelled when the request that started it goes away, since others may be
+// waiting for it, but it is on shutdown.
+func (h *DownloadHandler) assemblePackage(ctx context.Context, key cache.Key, pkg *domain.Package, integration *domain.Integration) (*os.File, error) {
+	var built *os.File
+	_, err, _ := h.assembling.Do(key.PackageID+"/"+key.Integration+"@"+key.Commit, func() (interface{}, error) {
+		// A request that just finished may have stored it already
+		if cached, ok := h.Cache.Open(key); ok {
+			built = cached
+			return nil, nil
+		}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		err := h.Background.Run(ctx, func(ctx context.Context) error {
+			file, err := h.buildPackage(ctx, key, pkg, integration)
+			built = file
+			return err
+		})
+		return nil, err
+	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if built != nil {
+		return built, nil
+	}
+
+	// Another request assembled the package
+	if cached, ok := h.Cache.Open(key); ok {
+		return cached, nil
 	}
+	return nil, fmt.Errorf("package of %s at %s was evicted from the cache right after it was assembled", key.Integration, key.Commit)
+}
 
-	req.Header.Set("Authorization", "token "+h.Config.GitHubToken)
-	req.Header.Set("Accept", "application/octet-stream")
+// buildPackage downloads the app package and the integration, stores the
+// combined package in the cache and returns it opened for reading
+func (h *DownloadHandler) buildPackage(ctx context.Context, key cache.Key, pkg *domain.Package, integration *domain.Integration) (*os.File, error) {
+	// Create temporary directory
+	tempDir, err := os.MkdirTemp("", h.Config.GetTempDirPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir) // Clean up
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// Download app .deb file, cancelled with ctx (e.g. when the client goes away)
+	debPath, err := h.downloadAppDeb(ctx, pkg, tempDir)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to download app: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status: %d", resp.StatusCode)
+	// Download integration commit as zip
+	integrationZipPath, err := h.downloadIntegrationZip(ctx, integration, tempDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download integration: %w", err)
 	}
 
-	file, err := os.Create(filepath)
+	// Create combined zip in the cache
+	combinedZip, err := h.Cache.Store(key, func(w io.Writer) error {
+		return h.writeCombinedZip(w, debPath, integrationZipPath)
+	})
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to create combined package: %w", err)
 	}
-	defer file.Close()
+	return combinedZip, nil
+}
 
-	_, err = io.Copy(file, resp.Body)
-	return err
+// etagMatches reports whether an If-None-Match header matches the ETag
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
 }
 
-// downloadGitHubArchive downloads a GitHub archive (works for private repos)
-func (h *DownloadHandler) downloadGitHubArchive(url, filepath string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), h.Config.Server.ReadTimeout)
-	defer cancel()
+// downloadAppDeb downloads the app .deb package
+func (h *DownloadHandler) downloadAppDeb(ctx context.Context, pkg *domain.Package, tempDir string) (string, error) {
+	debPath := filepath.Join(tempDir, h.Config.GetDebAppName())
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	body, err := h.Source.FetchAppPackage(ctx, pkg)
 	if err != nil {
-		return err
+		return "", err
 	}
+	defer body.Close()
 
-	req.Header.Set("Authorization", "token "+h.Config.GitHubToken)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	return debPath, writeFile(debPath, body)
+}
+
+// downloadIntegrationZip downloads the integration at its resolved commit as a zip file
+func (h *DownloadHandler) downloadIntegrationZip(ctx context.Context, integration *domain.Integration, tempDir string) (string, error) {
+	zipPath := filepath.Join(tempDir, "integration.zip")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	body, err := h.Source.FetchIntegrationArchive(ctx, integration)
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer resp.Body.Close()
+	defer body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status: %d", resp.StatusCode)
-	}
+	return zipPath, writeFile(zipPath, body)
+}
 
-	file, err := os.Create(filepath)
+// writeFile copies a stream to a new file
+func writeFile(path string, r io.Reader) error {
+	file, err := os.Create(path)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
+	_, err = io.Copy(file, r)
 	return err
 }
 
-// createCombinedZip creates a combined zip file with the .deb and integration files
-func (h *DownloadHandler) createCombinedZip(debPath, integrationZipPath, integration, tempDir string) (string, error) {
-	combinedZipPath := filepath.Join(tempDir, fmt.Sprintf("go-jo-%s.zip", integration))
-
-	// Create new zip file
-	combinedZip, err := os.Create(combinedZipPath)
-	if err != nil {
-		return "", err
-	}
-	defer combinedZip.Close()
-
-	zipWriter := zip.NewWriter(combinedZip)
-	defer zipWriter.Close()
+// writeCombinedZip writes a combined zip file with the .deb and integration files
+func (h *DownloadHandler) writeCombinedZip(w io.Writer, debPath, integrationZipPath string) error {
+	zipWriter := zip.NewWriter(w)
 
 	// Extract and add integration zip contents
 	if err := h.addZipContentsToZip(zipWriter, integrationZipPath); err != nil {
-		return "", err
+		return err
 	}
 
 	// Add the .deb file
 	if err := h.addFileToZip(zipWriter, debPath, h.Config.GetDebAppName()); err != nil {
-		return "", err
+		return err
 	}
 
-	return combinedZipPath, nil
+	return zipWriter.Close()
 }
 
 // addFileToZip adds a file to a zip archive
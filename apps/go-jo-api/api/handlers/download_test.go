@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestEtagMatches(t *testing.T) {
+	const etag = `"101-aaa111"`
+
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: `"101-aaa111"`, want: true},
+		{header: `W/"101-aaa111"`, want: true},
+		{header: `"other", "101-aaa111"`, want: true},
+		{header: `"other"`, want: false},
+		{header: `101-aaa111`, want: false},
+		{header: `*`, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, etag); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+// download runs a download request through the handler
+func download(h *DownloadHandler, version, integration string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/v1/download/"+version+"/"+integration, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	r = mux.SetURLVars(withLicense(r), map[string]string{"app_version": version, "integration": integration})
+
+	w := httptest.NewRecorder()
+	h.DownloadPackage(w, r)
+	return w
+}
+
+func TestDownloadConditionalAndRange(t *testing.T) {
+	base := newTestBase(t, newFakeSource())
+	h := NewDownloadHandler(base, NewVersionsHandler(base))
+
+	full := download(h, "v1.0.0", "zabbix", nil)
+	if full.Code != http.StatusOK {
+		t.Fatalf("download status = %d, body %s", full.Code, full.Body)
+	}
+	etag := full.Header().Get("ETag")
+	if etag != `"101-aaa111"` {
+		t.Fatalf("ETag = %q", etag)
+	}
+	size := full.Body.Len()
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		wantStatus int
+		wantLength int
+	}{
+		{name: "unconditional", wantStatus: http.StatusOK, wantLength: size},
+		{name: "matching If-None-Match", header: http.Header{"If-None-Match": {etag}}, wantStatus: http.StatusNotModified},
+		{name: "stale If-None-Match", header: http.Header{"If-None-Match": {`"101-old"`}}, wantStatus: http.StatusOK, wantLength: size},
+		{name: "range", header: http.Header{"Range": {"bytes=0-9"}}, wantStatus: http.StatusPartialContent, wantLength: 10},
+		{name: "open range", header: http.Header{"Range": {"bytes=10-"}}, wantStatus: http.StatusPartialContent, wantLength: size - 10},
+		{name: "range with matching If-Range", header: http.Header{"Range": {"bytes=0-9"}, "If-Range": {etag}}, wantStatus: http.StatusPartialContent, wantLength: 10},
+		{name: "range with stale If-Range", header: http.Header{"Range": {"bytes=0-9"}, "If-Range": {`"101-old"`}}, wantStatus: http.StatusOK, wantLength: size},
+		{name: "unsatisfiable range", header: http.Header{"Range": {"bytes=999999-"}}, wantStatus: http.StatusRequestedRangeNotSatisfiable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := download(h, "v1.0.0", "zabbix", tt.header)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantLength > 0 && w.Body.Len() != tt.wantLength {
+				t.Errorf("body length = %d, want %d", w.Body.Len(), tt.wantLength)
+			}
+			// http.ServeContent drops the ETag of error responses
+			if w.Code != http.StatusRequestedRangeNotSatisfiable && w.Header().Get("ETag") != etag {
+				t.Errorf("ETag = %q, want %q", w.Header().Get("ETag"), etag)
+			}
+		})
+	}
+}
+
+func TestDownloadErrors(t *testing.T) {
+	base := newTestBase(t, newFakeSource())
+	h := NewDownloadHandler(base, NewVersionsHandler(base))
+
+	tests := []struct {
+		version, integration string
+		wantStatus           int
+	}{
+		{version: "v9.9.9", integration: "zabbix", wantStatus: http.StatusNotFound},
+		{version: "v1.0.0", integration: "nginx", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		if w := download(h, tt.version, tt.integration, nil); w.Code != tt.wantStatus {
+			t.Errorf("download %s/%s status = %d, want %d", tt.version, tt.integration, w.Code, tt.wantStatus)
+		}
+	}
+}
+
+func TestConcurrentDownloadsAssembleOnce(t *testing.T) {
+	src := newFakeSource()
+	base := newTestBase(t, src)
+	h := NewDownloadHandler(base, NewVersionsHandler(base))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if w := download(h, "v1.0.0", "grafana", nil); w.Code != http.StatusOK {
+				t.Errorf("download status = %d", w.Code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if fetches := src.packageFetches.Load(); fetches != 1 {
+		t.Errorf("app package fetched %d times, want 1", fetches)
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/audit"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/cache"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/license"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/source"
+)
+
+// fakeSource serves a fixed release and integrations from memory
+type fakeSource struct {
+	releases     []domain.Release
+	integrations map[string]string
+	manifests    map[string]*domain.IntegrationManifest
+
+	// packageFetches counts the app package downloads
+	packageFetches atomic.Int32
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		releases: []domain.Release{
+			{Version: "v1.0.0", AppPackage: &domain.Package{ID: "101", Name: "go-jo_1.0.0_linux_amd64.deb"}},
+			{Version: "v1.1.0-rc.1", Prerelease: true, AppPackage: &domain.Package{ID: "102", Name: "go-jo_1.1.0-rc.1_linux_amd64.deb"}},
+		},
+		integrations: map[string]string{"zabbix": "aaa111", "grafana": "bbb222"},
+		manifests:    make(map[string]*domain.IntegrationManifest),
+	}
+}
+
+func (s *fakeSource) Name() string { return "fake" }
+
+func (s *fakeSource) ListVersions(ctx context.Context) ([]domain.Release, error) {
+	return s.releases, nil
+}
+
+func (s *fakeSource) GetVersion(ctx context.Context, version string) (*domain.Release, error) {
+	for _, release := range s.releases {
+		if release.Version == version {
+			return &release, nil
+		}
+	}
+	return nil, fmt.Errorf("version %s %w", version, source.ErrNotFound)
+}
+
+func (s *fakeSource) GetVersionCommit(ctx context.Context, version string) (string, error) {
+	return "c0ffee", nil
+}
+
+func (s *fakeSource) ListIntegrations(ctx context.Context) ([]domain.Integration, error) {
+	var integrations []domain.Integration
+	for name, commit := range s.integrations {
+		integrations = append(integrations, domain.Integration{Name: name, Commit: commit})
+	}
+	return integrations, nil
+}
+
+func (s *fakeSource) GetIntegration(ctx context.Context, name string) (*domain.Integration, error) {
+	commit, ok := s.integrations[name]
+	if !ok {
+		return nil, fmt.Errorf("integration %s %w", name, source.ErrNotFound)
+	}
+	return &domain.Integration{Name: name, Commit: commit}, nil
+}
+
+func (s *fakeSource) GetIntegrationManifest(ctx context.Context, integration *domain.Integration) (*domain.IntegrationManifest, error) {
+	manifest, ok := s.manifests[integration.Name]
+	if !ok {
+		return nil, fmt.Errorf("integration.yaml of %s %w", integration.Name, source.ErrNotFound)
+	}
+	return manifest, nil
+}
+
+func (s *fakeSource) FetchAppPackage(ctx context.Context, pkg *domain.Package) (io.ReadCloser, error) {
+	s.packageFetches.Add(1)
+	return io.NopCloser(bytes.NewReader([]byte("deb package " + pkg.ID))), nil
+}
+
+func (s *fakeSource) FetchIntegrationArchive(ctx context.Context, integration *domain.Integration) (io.ReadCloser, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	file, err := archive.Create(integration.Name + "-" + integration.Commit + "/docker-compose.yml")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(file, "# %s at %s\n", integration.Name, integration.Commit)
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&buf), nil
+}
+
+// newTestBase returns a base handler on the fake source with a temporary
+// cache and no audit log
+func newTestBase(t *testing.T, src source.ArtifactSource) *BaseHandler {
+	t.Helper()
+
+	dir := t.TempDir()
+	packageCache, err := cache.New(filepath.Join(dir, "cache"), 100*1024*1024)
+	if err != nil {
+		t.Fatalf("cache.New error = %v", err)
+	}
+	auditLog, err := audit.Open("")
+	if err != nil {
+		t.Fatalf("audit.Open error = %v", err)
+	}
+
+	config := &domain.Config{
+		API: domain.APIConfig{DebAppName: "go-jo-selected.deb", TempDirPrefix: "go-jo-api-test-"},
+	}
+	return NewBaseHandler(config, license.NewStore(), packageCache, src, auditLog)
+}
+
+// withLicense returns the request authenticated as an unrestricted license
+func withLicense(r *http.Request) *http.Request {
+	return withLicenseOf(r, &license.License{ID: "test"})
+}
+
+// withLicenseOf returns the request authenticated as the license
+func withLicenseOf(r *http.Request, lic *license.License) *http.Request {
+	return r.WithContext(license.NewContext(r.Context(), lic))
+}
@@ -1,25 +1,41 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/health"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/source"
 )
 
+// upstreamCheckTTL is how long the result of the upstream readiness check is
+// reused, so frequent probes don't spend the upstream rate limit
+const upstreamCheckTTL = 5 * time.Second
+
 // HealthHandler handles health check requests
 type HealthHandler struct {
 	*BaseHandler
+
+	// upstreamMu serializes the upstream checks, so concurrent probes share one
+	upstreamMu      sync.Mutex
+	upstreamResult  error
+	upstreamChecked time.Time
 }
 
 // NewHealthHandler creates a new health handler
-func NewHealthHandler(config *domain.Config) *HealthHandler {
+func NewHealthHandler(base *BaseHandler) *HealthHandler {
 	return &HealthHandler{
-		BaseHandler: NewBaseHandler(config),
+		BaseHandler: base,
 	}
 }
 
-// HealthCheck handles GET /health - Health check endpoint (no auth required)
+// HealthCheck handles GET /health - Liveness check endpoint (no auth required)
 func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	response := domain.HealthResponse{
 		Status:    "healthy",
@@ -30,3 +46,70 @@ func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	}
 	h.SendJSONResponse(w, http.StatusOK, response)
 }
+
+// ReadinessCheck handles GET /health/ready - Readiness check (no auth required).
+// Verifies the configuration, the artifact source and its credentials, and
+// the temp and cache directories. Answers 503 when any check fails. Failure
+// messages are only included for requests presenting the admin or metrics
+// token, since they describe the configuration.
+func (h *HealthHandler) ReadinessCheck(w http.ResponseWriter, r *http.Request) {
+	detailed := presentsToken(r, h.Config.Admin.Token) || presentsToken(r, h.Config.Metrics.Token)
+
+	response := domain.ReadinessResponse{
+		Status:    "ready",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Checks:    make(map[string]domain.CheckResult),
+	}
+
+	run := func(name string, check func() error) {
+		start := time.Now()
+		err := check()
+
+		result := domain.CheckResult{
+			Status:     "ok",
+			DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if err != nil {
+			message := strings.ReplaceAll(err.Error(), "\n", "; ")
+			result.Status = "fail"
+			if detailed {
+				result.Message = message
+			}
+			response.Status = "not_ready"
+			logging.Logger(r.Context()).Warn("Readiness check failed", "check", name, "error", message)
+		}
+		response.Checks[name] = result
+	}
+
+	run("config", h.Config.Validate)
+	if checker, ok := h.Source.(source.Checker); ok {
+		run("upstream", func() error { return h.checkUpstream(r.Context(), checker) })
+	}
+	run("temp_dir", func() error { return health.CheckDir(os.TempDir(), h.Config.GetMinFreeSpace()) })
+	run("cache_dir", func() error { return health.CheckDir(h.Cache.Dir(), h.Config.GetMinFreeSpace()) })
+
+	status := http.StatusOK
+	if response.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	h.SendJSONResponse(w, status, response)
+}
+
+// checkUpstream checks the artifact source, reusing a result younger than
+// upstreamCheckTTL
+func (h *HealthHandler) checkUpstream(ctx context.Context, checker source.Checker) error {
+	h.upstreamMu.Lock()
+	defer h.upstreamMu.Unlock()
+
+	if !h.upstreamChecked.IsZero() && time.Since(h.upstreamChecked) < upstreamCheckTTL {
+		return h.upstreamResult
+	}
+
+	// The result is shared, so it must not be the cancellation of one request
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), h.Config.GetRequestTimeout())
+	defer cancel()
+
+	h.upstreamResult = checker.Check(ctx)
+	h.upstreamChecked = time.Now()
+	return h.upstreamResult
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+)
+
+// checkingSource is a fake source with an upstream check
+type checkingSource struct {
+	*fakeSource
+	err    error
+	checks atomic.Int32
+}
+
+func (s *checkingSource) Check(ctx context.Context) error {
+	s.checks.Add(1)
+	return s.err
+}
+
+func readiness(t *testing.T, h *HealthHandler, authorization string) (int, domain.ReadinessResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/health/ready", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	h.ReadinessCheck(rec, req)
+
+	var response domain.ReadinessResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return rec.Code, response
+}
+
+func TestReadinessHidesMessagesWithoutToken(t *testing.T) {
+	src := &checkingSource{fakeSource: newFakeSource(), err: errors.New("GitHub API answered 401")}
+	base := newTestBase(t, src)
+	base.Config.Admin.Token = "admin-secret"
+	base.Config.Metrics.Token = "metrics-secret"
+	h := NewHealthHandler(base)
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantMessages  bool
+	}{
+		{"anonymous", "", false},
+		{"license token", "customer-token", false},
+		{"wrong admin token", "Bearer admin-secre", false},
+		{"admin token", "Bearer admin-secret", true},
+		{"metrics token", "metrics-secret", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := readiness(t, h, tt.authorization)
+			if status != http.StatusServiceUnavailable || response.Status != "not_ready" {
+				t.Fatalf("status = %d %q, want 503 not_ready", status, response.Status)
+			}
+
+			// The test config is incomplete, so the config check fails too
+			for _, name := range []string{"config", "upstream"} {
+				check := response.Checks[name]
+				if check.Status != "fail" {
+					t.Errorf("%s check = %+v, want fail", name, check)
+				}
+				if hasMessage := check.Message != ""; hasMessage != tt.wantMessages {
+					t.Errorf("%s check message = %q, want message %v", name, check.Message, tt.wantMessages)
+				}
+			}
+			if check := response.Checks["temp_dir"]; check.Status != "ok" {
+				t.Errorf("temp_dir check = %+v, want ok", check)
+			}
+		})
+	}
+}
+
+func TestReadinessReusesUpstreamCheck(t *testing.T) {
+	src := &checkingSource{fakeSource: newFakeSource()}
+	h := NewHealthHandler(newTestBase(t, src))
+
+	for i := 0; i < 3; i++ {
+		if _, response := readiness(t, h, ""); response.Checks["upstream"].Status != "ok" {
+			t.Fatalf("upstream check = %+v, want ok", response.Checks["upstream"])
+		}
+	}
+	if checks := src.checks.Load(); checks != 1 {
+		t.Errorf("upstream checked %d times, want 1 within %s", checks, upstreamCheckTTL)
+	}
+
+	// A result older than the TTL is refreshed
+	h.upstreamMu.Lock()
+	h.upstreamChecked = h.upstreamChecked.Add(-upstreamCheckTTL)
+	h.upstreamMu.Unlock()
+	src.err = errors.New("unreachable")
+
+	if _, response := readiness(t, h, ""); response.Checks["upstream"].Status != "fail" {
+		t.Errorf("upstream check = %+v, want fail after the TTL", response.Checks["upstream"])
+	}
+	if checks := src.checks.Load(); checks != 2 {
+		t.Errorf("upstream checked %d times, want 2", checks)
+	}
+}
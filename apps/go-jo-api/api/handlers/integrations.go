@@ -1,55 +1,165 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"sort"
+	"sync"
 
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/source"
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/semver"
 )
 
+// manifestConcurrency bounds the manifests read from the source at once
+const manifestConcurrency = 8
+
 // IntegrationsHandler handles integration-related requests
 type IntegrationsHandler struct {
 	*BaseHandler
 }
 
 // NewIntegrationsHandler creates a new integrations handler
-func NewIntegrationsHandler(config *domain.Config) *IntegrationsHandler {
+func NewIntegrationsHandler(base *BaseHandler) *IntegrationsHandler {
 	return &IntegrationsHandler{
-		BaseHandler: NewBaseHandler(config),
+		BaseHandler: base,
 	}
 }
 
-// GetIntegrations handles GET /integrations - Get all branches from go-jo-docker-environments
+// GetIntegrations handles GET /integrations - Get all integration environments
+// with their manifests. With ?version=, each integration tells whether it
+// works with that go-jo version.
 func (h *IntegrationsHandler) GetIntegrations(w http.ResponseWriter, r *http.Request) {
-	log.Printf("Fetching integrations (branches) for repository: %s", h.Config.GetDockerEnvRepo())
+	logging.Logger(r.Context()).Info("Fetching integrations", "source", h.Source.Name())
+
+	var version *semver.Version
+	if value := r.URL.Query().Get("version"); value != "" {
+		var err error
+		if version, err = semver.Parse(value); err != nil {
+			h.SendErrorResponse(w, r, http.StatusBadRequest, "Invalid version: "+err.Error())
+			return
+		}
+	}
 
-	branches, err := h.fetchGitHubBranches(h.Config.GetDockerEnvRepo())
+	available, err := h.Source.ListIntegrations(r.Context())
 	if err != nil {
-		h.SendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch branches: "+err.Error())
+		h.SendSourceError(w, r, "Failed to fetch integrations", err)
 		return
 	}
 
-	var integrations []string
-	for _, branch := range branches {
+	lic := h.LicenseFromRequest(r)
+
+	var integrations []domain.Integration
+	for _, integration := range available {
 		// Filter out main/master branches if you only want integration branches
-		if branch.Name != "main" && branch.Name != "master" {
-			integrations = append(integrations, branch.Name)
+		if integration.Name == "main" || integration.Name == "master" {
+			continue
+		}
+
+		// Only list integrations the license is entitled to
+		if !lic.AllowsIntegration(integration.Name) {
+			continue
 		}
+
+		integrations = append(integrations, integration)
 	}
 
-	sort.Strings(integrations)
+	sort.Slice(integrations, func(i, j int) bool {
+		return integrations[i].Name < integrations[j].Name
+	})
+
+	response := domain.IntegrationsResponse{
+		Integrations: make([]string, len(integrations)),
+		Details:      make([]domain.IntegrationInfo, len(integrations)),
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, manifestConcurrency)
+	for i := range integrations {
+		response.Integrations[i] = integrations[i].Name
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			response.Details[i] = h.integrationInfo(r.Context(), &integrations[i], version)
+		}(i)
+	}
+	wg.Wait()
 
-	response := domain.IntegrationsResponse{Integrations: integrations}
 	h.SendJSONResponse(w, http.StatusOK, response)
 }
 
-// fetchGitHubBranches fetches branches from GitHub API
-func (h *IntegrationsHandler) fetchGitHubBranches(repo string) ([]domain.GitHubBranch, error) {
-	url := fmt.Sprintf("%s/repos/%s/branches", h.Config.GetGitHubAPIBaseURL(), repo)
+// integrationInfo describes an integration from its manifest. Integrations
+// without a readable manifest are still listed, under their name.
+func (h *IntegrationsHandler) integrationInfo(ctx context.Context, integration *domain.Integration, version *semver.Version) domain.IntegrationInfo {
+	info := domain.IntegrationInfo{
+		Name:        integration.Name,
+		DisplayName: integration.Name,
+	}
 
-	var branches []domain.GitHubBranch
-	err := h.FetchFromGitHub(url, &branches)
-	return branches, err
+	manifest, err := h.Source.GetIntegrationManifest(ctx, integration)
+	if err != nil {
+		if !errors.Is(err, source.ErrNotFound) {
+			logging.Logger(ctx).Warn("Failed to read integration manifest", "integration", integration.Name,
+				"commit", integration.Commit, "error", err)
+		}
+		manifest = nil
+	}
+
+	if manifest != nil {
+		if manifest.Name != "" {
+			info.DisplayName = manifest.Name
+		}
+		info.Description = manifest.Description
+		info.Maintainer = manifest.Maintainer
+		info.MinVersion = manifest.GoJo.MinVersion
+		info.MaxVersion = manifest.GoJo.MaxVersion
+		info.Ports = manifest.Ports
+		info.Env = manifest.Env
+		info.RequiresDocker = manifest.Requires.Docker
+		info.RequiresCompose = manifest.Requires.Compose
+	}
+
+	if version != nil {
+		compatible := true
+		if manifest != nil {
+			if compatible, err = supportsVersion(manifest, version); err != nil {
+				// Sources validate manifests, one that slips through is never
+				// offered as compatible
+				logging.Logger(ctx).Warn("Invalid go-jo version range in integration manifest",
+					"integration", integration.Name, "commit", integration.Commit, "error", err)
+			}
+		}
+		info.Compatible = &compatible
+	}
+	return info
+}
+
+// supportsVersion reports whether a go-jo version is within the range of the
+// manifest. Both bounds are inclusive and a partial max_version such as "1.4"
+// covers its whole line. Malformed bounds are an error.
+func supportsVersion(manifest *domain.IntegrationManifest, version *semver.Version) (bool, error) {
+	if manifest.GoJo.MinVersion != "" {
+		minVersion, err := semver.Parse(manifest.GoJo.MinVersion)
+		if err != nil {
+			return false, fmt.Errorf("go_jo.min_version: %w", err)
+		}
+		if version.Compare(minVersion) < 0 {
+			return false, nil
+		}
+	}
+	if manifest.GoJo.MaxVersion != "" {
+		within, err := version.AtMost(manifest.GoJo.MaxVersion)
+		if err != nil {
+			return false, fmt.Errorf("go_jo.max_version: %w", err)
+		}
+		return within, nil
+	}
+	return true, nil
 }
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/license"
+)
+
+func TestIntegrationEntitlements(t *testing.T) {
+	src := newFakeSource()
+	src.integrations["zabbix-agent"] = "ccc333"
+	base := newTestBase(t, src)
+	integrations := NewIntegrationsHandler(base)
+	downloads := NewDownloadHandler(base, NewVersionsHandler(base))
+
+	tests := []struct {
+		name         string
+		integrations []string
+		want         []string
+	}{
+		{"unrestricted", nil, []string{"grafana", "zabbix", "zabbix-agent"}},
+		{"glob", []string{"zabbix*"}, []string{"zabbix", "zabbix-agent"}},
+		{"exact", []string{"grafana"}, []string{"grafana"}},
+		{"nothing matches", []string{"prometheus"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lic := &license.License{ID: "acme", Integrations: tt.integrations}
+
+			w := httptest.NewRecorder()
+			integrations.GetIntegrations(w, withLicenseOf(httptest.NewRequest(http.MethodGet, "/v1/integrations", nil), lic))
+			if w.Code != http.StatusOK {
+				t.Fatalf("list status = %d, body %s", w.Code, w.Body)
+			}
+			var response domain.IntegrationsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decoding %s: %v", w.Body, err)
+			}
+			var listed []string
+			for _, info := range response.Details {
+				listed = append(listed, info.Name)
+			}
+			if !reflect.DeepEqual(listed, tt.want) {
+				t.Errorf("listed %q, want %q", listed, tt.want)
+			}
+
+			// Every integration left out of the list is refused on download
+			for name := range src.integrations {
+				r := httptest.NewRequest(http.MethodGet, "/v1/download/v1.0.0/"+name, nil)
+				r = mux.SetURLVars(withLicenseOf(r, lic), map[string]string{"app_version": "v1.0.0", "integration": name})
+				w := httptest.NewRecorder()
+				downloads.DownloadPackage(w, r)
+
+				wantStatus := http.StatusForbidden
+				if lic.AllowsIntegration(name) {
+					wantStatus = http.StatusOK
+				}
+				if w.Code != wantStatus {
+					t.Errorf("download of %s status = %d, want %d", name, w.Code, wantStatus)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegrationCompatibility(t *testing.T) {
+	src := newFakeSource()
+	ranges := map[string][2]string{
+		"full":       {"v1.0.0", "v1.4.0"},
+		"minor-line": {"v1.0.0", "1.4"},
+		"major-line": {"", "1"},
+		"too-new":    {"v1.5.0", ""},
+		"bad-min":    {"latest", ""},
+		"bad-max":    {"", "1.x"},
+	}
+	for name, r := range ranges {
+		src.integrations[name] = "ddd444"
+		manifest := &domain.IntegrationManifest{}
+		manifest.GoJo.MinVersion, manifest.GoJo.MaxVersion = r[0], r[1]
+		src.manifests[name] = manifest
+	}
+	integrations := NewIntegrationsHandler(newTestBase(t, src))
+
+	w := httptest.NewRecorder()
+	integrations.GetIntegrations(w, withLicense(httptest.NewRequest(http.MethodGet, "/v1/integrations?version=v1.4.7", nil)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var response domain.IntegrationsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body, err)
+	}
+
+	want := map[string]bool{
+		"full":       false,
+		"minor-line": true,
+		"major-line": true,
+		"too-new":    false,
+		"bad-min":    false,
+		"bad-max":    false,
+		// Integrations without a manifest are assumed to work
+		"grafana": true,
+		"zabbix":  true,
+	}
+	for _, info := range response.Details {
+		if info.Compatible == nil {
+			t.Errorf("%s: compatible not set", info.Name)
+			continue
+		}
+		if *info.Compatible != want[info.Name] {
+			t.Errorf("%s: compatible = %v, want %v", info.Name, *info.Compatible, want[info.Name])
+		}
+	}
+	if len(response.Details) != len(want) {
+		t.Errorf("listed %d integrations, want %d", len(response.Details), len(want))
+	}
+}
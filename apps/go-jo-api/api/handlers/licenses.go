@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/license"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/licensefile"
+)
+
+// maxLicenseRequestSize bounds the body of license requests
+const maxLicenseRequestSize = 64 * 1024
+
+// licenseIDPattern restricts license IDs to characters safe in URLs and logs
+var licenseIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// ListLicenses handles GET /admin/licenses - All licenses of the registry
+func (h *AdminHandler) ListLicenses(w http.ResponseWriter, r *http.Request) {
+	licenses := h.Licenses.List()
+	for _, l := range licenses {
+		l.TokenHash = ""
+	}
+
+	h.SendJSONResponse(w, http.StatusOK, domain.LicensesResponse{Licenses: licenses})
+}
+
+// GetLicense handles GET /admin/licenses/{id} - A single license
+func (h *AdminHandler) GetLicense(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	l, ok := h.Licenses.Get(id)
+	if !ok {
+		h.sendLicenseError(w, r, fmt.Errorf("%w: %s", license.ErrNotFound, id))
+		return
+	}
+
+	l.TokenHash = ""
+	h.SendJSONResponse(w, http.StatusOK, l)
+}
+
+// IssueLicense handles POST /admin/licenses - Issue a license with a new token.
+// The token is only part of this response.
+func (h *AdminHandler) IssueLicense(w http.ResponseWriter, r *http.Request) {
+	var request domain.LicenseRequest
+	if !h.decodeLicenseRequest(w, r, &request) {
+		return
+	}
+
+	if entry := auditEntry(r); entry != nil {
+		entry.License = request.ID
+	}
+
+	if !licenseIDPattern.MatchString(request.ID) {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, "id must be 1-64 letters, digits, '.', '_' or '-', starting with a letter or digit")
+		return
+	}
+	if request.Customer == nil {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, "customer is required")
+		return
+	}
+
+	l := &license.License{ID: request.ID}
+	if _, err := applyLicenseRequest(l, &request); err != nil {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	issued, token, err := h.Licenses.Issue(l)
+	if err != nil {
+		h.sendLicenseError(w, r, err)
+		return
+	}
+
+	logging.Logger(r.Context()).Info("License issued", "license", issued.ID, "customer", issued.Customer)
+	issued.TokenHash = ""
+	h.SendJSONResponse(w, http.StatusCreated, domain.IssuedLicenseResponse{License: issued, Token: token})
+}
+
+// UpdateLicense handles PATCH /admin/licenses/{id} - Change the customer,
+// expiry, entitlements or rate limits of a license
+func (h *AdminHandler) UpdateLicense(w http.ResponseWriter, r *http.Request) {
+	var request domain.LicenseRequest
+	if !h.decodeLicenseRequest(w, r, &request) {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if request.ID != "" && request.ID != id {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, "The ID of a license can't be changed")
+		return
+	}
+
+	var changed []string
+	var invalid error
+	updated, err := h.Licenses.Update(id, func(l *license.License) error {
+		if l.Revoked {
+			return license.ErrRevoked
+		}
+		changed, invalid = applyLicenseRequest(l, &request)
+		return invalid
+	})
+	if invalid != nil {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, invalid.Error())
+		return
+	}
+	if err != nil {
+		h.sendLicenseError(w, r, err)
+		return
+	}
+
+	message := "No changes"
+	if len(changed) > 0 {
+		message = "Changed " + strings.Join(changed, ", ")
+	}
+	if entry := auditEntry(r); entry != nil {
+		entry.Message = message
+	}
+
+	logging.Logger(r.Context()).Info("License updated", "license", id, "changed", changed)
+	updated.TokenHash = ""
+	h.SendJSONResponse(w, http.StatusOK, updated)
+}
+
+// SuspendLicense handles POST /admin/licenses/{id}/suspend - Refuse the
+// license until it is resumed. A signed license without a registry entry
+// gets one holding only its ID and state.
+func (h *AdminHandler) SuspendLicense(w http.ResponseWriter, r *http.Request) {
+	h.setLicenseState(w, r, "suspended", h.blockingUpdate(), func(l *license.License) error {
+		if l.Revoked {
+			return license.ErrRevoked
+		}
+		l.Suspended = true
+		return nil
+	})
+}
+
+// ResumeLicense handles POST /admin/licenses/{id}/resume - Accept a
+// suspended license again
+func (h *AdminHandler) ResumeLicense(w http.ResponseWriter, r *http.Request) {
+	h.setLicenseState(w, r, "resumed", h.Licenses.Update, func(l *license.License) error {
+		if l.Revoked {
+			return license.ErrRevoked
+		}
+		l.Suspended = false
+		return nil
+	})
+}
+
+// RevokeLicense handles DELETE /admin/licenses/{id} - Revoke a license for
+// good. The entry is kept so the ID stays blocked. A signed license without a
+// registry entry gets one holding only its ID and state.
+func (h *AdminHandler) RevokeLicense(w http.ResponseWriter, r *http.Request) {
+	h.setLicenseState(w, r, "revoked", h.blockingUpdate(), func(l *license.License) error {
+		l.Revoked = true
+		return nil
+	})
+}
+
+// RotateLicense handles POST /admin/licenses/{id}/rotate - Replace the token
+// of a license. The new token is only part of this response.
+func (h *AdminHandler) RotateLicense(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	rotated, token, err := h.Licenses.Rotate(id)
+	if err != nil {
+		h.sendLicenseError(w, r, err)
+		return
+	}
+
+	logging.Logger(r.Context()).Info("License token rotated", "license", id)
+	rotated.TokenHash = ""
+	h.SendJSONResponse(w, http.StatusOK, domain.IssuedLicenseResponse{License: rotated, Token: token})
+}
+
+// blockingUpdate returns the update of suspend and revoke. Signed licenses
+// have no registry entry, so with a public key configured unknown IDs get
+// one; without it an unknown ID is a typo and answers 404.
+func (h *AdminHandler) blockingUpdate() func(id string, change func(l *license.License) error) (*license.License, error) {
+	if h.Licenses.HasPublicKey() {
+		return h.Licenses.UpdateOrBlock
+	}
+	return h.Licenses.Update
+}
+
+// setLicenseState applies a state change to the license of the request with
+// update and answers with the updated license
+func (h *AdminHandler) setLicenseState(w http.ResponseWriter, r *http.Request, state string,
+	update func(id string, change func(l *license.License) error) (*license.License, error), change func(l *license.License) error) {
+	id := mux.Vars(r)["id"]
+	if _, exists := h.Licenses.Get(id); !exists && !licenseIDPattern.MatchString(id) {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, "id must be 1-64 letters, digits, '.', '_' or '-', starting with a letter or digit")
+		return
+	}
+
+	updated, err := update(id, change)
+	if err != nil {
+		h.sendLicenseError(w, r, err)
+		return
+	}
+
+	logging.Logger(r.Context()).Info("License "+state, "license", id)
+	updated.TokenHash = ""
+	h.SendJSONResponse(w, http.StatusOK, updated)
+}
+
+// decodeLicenseRequest parses the JSON body of a license request, answering
+// 400 when it is invalid
+func (h *AdminHandler) decodeLicenseRequest(w http.ResponseWriter, r *http.Request, request *domain.LicenseRequest) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLicenseRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(request); err != nil {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, "Invalid license request: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// sendLicenseError maps license registry errors to HTTP statuses
+func (h *AdminHandler) sendLicenseError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, license.ErrNotFound):
+		h.SendErrorResponse(w, r, http.StatusNotFound, "License not found: "+mux.Vars(r)["id"])
+	case errors.Is(err, license.ErrExists), errors.Is(err, license.ErrBuiltin), errors.Is(err, license.ErrRevoked):
+		h.SendErrorResponse(w, r, http.StatusConflict, err.Error())
+	default:
+		h.SendErrorResponse(w, r, http.StatusInternalServerError, "Failed to update license registry: "+err.Error())
+	}
+}
+
+// parseExpiresAt parses the expiry of a license, an RFC 3339 timestamp or a
+// YYYY-MM-DD date (UTC). A date keeps the license valid until the end of that
+// day, as for signed licenses.
+func parseExpiresAt(value string) (time.Time, error) {
+	if day, err := time.Parse(time.DateOnly, value); err == nil {
+		return licensefile.EndOfDay(day), nil
+	}
+	return parseTimeParam(value, false)
+}
+
+// applyLicenseRequest copies the fields present in the request to the license
+// and returns the names of the fields that were set
+func applyLicenseRequest(l *license.License, request *domain.LicenseRequest) ([]string, error) {
+	var changed []string
+
+	if request.Customer != nil {
+		if strings.TrimSpace(*request.Customer) == "" {
+			return nil, errors.New("customer can't be empty")
+		}
+		l.Customer = strings.TrimSpace(*request.Customer)
+		changed = append(changed, "customer")
+	}
+	if request.ExpiresAt != nil {
+		expiresAt, err := parseExpiresAt(*request.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_at: %w", err)
+		}
+		l.ExpiresAt = expiresAt.UTC()
+		changed = append(changed, "expires_at")
+	}
+	if request.Integrations != nil {
+		for _, pattern := range *request.Integrations {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid integration pattern %q", pattern)
+			}
+		}
+		l.Integrations = *request.Integrations
+		changed = append(changed, "integrations")
+	}
+	if request.Versions != nil {
+		l.Versions = *request.Versions
+		changed = append(changed, "versions")
+	}
+	if request.Channel != nil {
+		l.Channel = *request.Channel
+		changed = append(changed, "channel")
+	}
+	if request.RateLimits != nil {
+		l.RateLimits = request.RateLimits
+		if *request.RateLimits == (license.RateLimits{}) {
+			l.RateLimits = nil
+		}
+		changed = append(changed, "rate_limits")
+	}
+
+	if err := l.CheckEntitlements(); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/license"
+)
+
+// newLicenseHandler returns an admin handler on an empty license registry
+func newLicenseHandler(t *testing.T) *AdminHandler {
+	t.Helper()
+
+	base := newTestBase(t, newFakeSource())
+	store, err := license.LoadStore(filepath.Join(t.TempDir(), "licenses.json"))
+	if err != nil {
+		t.Fatalf("LoadStore error = %v", err)
+	}
+	base.Licenses = store
+	return NewAdminHandler(base)
+}
+
+// callLicense calls handler for the license ID and decodes the license of a
+// successful response
+func callLicense(t *testing.T, handler http.HandlerFunc, method, id, body string) (int, *license.License) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, "/v1/admin/licenses/"+id, strings.NewReader(body))
+	if id != "" {
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code >= 300 {
+		return rec.Code, nil
+	}
+	var response struct {
+		license.License
+		Nested *license.License `json:"license"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	if response.Nested != nil {
+		return rec.Code, response.Nested
+	}
+	return rec.Code, &response.License
+}
+
+func TestLicenseStateOfSignedLicenses(t *testing.T) {
+	h := newLicenseHandler(t)
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey error = %v", err)
+	}
+	h.Licenses.SetPublicKey(publicKey)
+
+	// Signed licenses have no registry entry until they are revoked or suspended
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		method     string
+		id         string
+		wantStatus int
+	}{
+		{"resume without entry", h.ResumeLicense, http.MethodPost, "offline", http.StatusNotFound},
+		{"suspend without entry", h.SuspendLicense, http.MethodPost, "offline", http.StatusOK},
+		{"resume", h.ResumeLicense, http.MethodPost, "offline", http.StatusOK},
+		{"revoke", h.RevokeLicense, http.MethodDelete, "offline", http.StatusOK},
+		{"suspend revoked", h.SuspendLicense, http.MethodPost, "offline", http.StatusConflict},
+		{"revoke without entry", h.RevokeLicense, http.MethodDelete, "other", http.StatusOK},
+		{"revoke invalid ID", h.RevokeLicense, http.MethodDelete, "-offline", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _ := callLicense(t, tt.handler, tt.method, tt.id, ""); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+
+	for _, id := range []string{"offline", "other"} {
+		if l, ok := h.Licenses.Get(id); !ok || !l.Revoked {
+			t.Errorf("registry entry of %s = %+v, want it revoked", id, l)
+		}
+	}
+	if _, ok := h.Licenses.Get("-offline"); ok {
+		t.Error("registry holds an entry for an invalid ID")
+	}
+}
+
+func TestLicenseStateWithoutPublicKey(t *testing.T) {
+	h := newLicenseHandler(t)
+
+	// Without signed licenses an unknown ID is a typo, not a license to block
+	for _, handler := range []http.HandlerFunc{h.SuspendLicense, h.RevokeLicense, h.ResumeLicense} {
+		if status, _ := callLicense(t, handler, http.MethodPost, "acme-typo", ""); status != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", status)
+		}
+	}
+	if _, ok := h.Licenses.Get("acme-typo"); ok {
+		t.Error("registry holds an entry for an unknown ID")
+	}
+}
+
+func TestLicenseExpiresAt(t *testing.T) {
+	h := newLicenseHandler(t)
+
+	tests := []struct {
+		expiresAt string
+		want      time.Time
+	}{
+		{"2026-01-01", time.Date(2026, 1, 1, 23, 59, 59, 0, time.UTC)},
+		{"2026-01-01T12:30:00+02:00", time.Date(2026, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{"", time.Time{}},
+	}
+
+	if status, _ := callLicense(t, h.IssueLicense, http.MethodPost, "", `{"id": "acme", "customer": "ACME Corp"}`); status != http.StatusCreated {
+		t.Fatalf("issue status = %d, want 201", status)
+	}
+	for _, tt := range tests {
+		status, l := callLicense(t, h.UpdateLicense, http.MethodPatch, "acme", `{"expires_at": "`+tt.expiresAt+`"}`)
+		if status != http.StatusOK {
+			t.Fatalf("%q: status = %d, want 200", tt.expiresAt, status)
+		}
+		if !l.ExpiresAt.Equal(tt.want) {
+			t.Errorf("%q: expires_at = %s, want %s", tt.expiresAt, l.ExpiresAt, tt.want)
+		}
+	}
+
+	// A date keeps the license valid until the end of that day
+	l := &license.License{ExpiresAt: tests[0].want}
+	if err := l.Validate(time.Date(2026, 1, 1, 23, 59, 59, 0, time.UTC)); err != nil {
+		t.Errorf("Validate on the last day error = %v", err)
+	}
+	if err := l.Validate(time.Date(2026, 1, 2, 0, 0, 1, 0, time.UTC)); err == nil {
+		t.Error("Validate after the last day succeeded")
+	}
+}
+
+func TestLicenseHandlers(t *testing.T) {
+	h := newLicenseHandler(t)
+	if err := h.Licenses.AddBuiltin(&license.License{ID: "default", TokenHash: license.HashToken("legacy")}); err != nil {
+		t.Fatalf("AddBuiltin error = %v", err)
+	}
+
+	// Steps run in order on the same registry
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		method     string
+		id         string
+		body       string
+		wantStatus int
+	}{
+		{"issue", h.IssueLicense, http.MethodPost, "", `{"id": "acme", "customer": "ACME Corp"}`, http.StatusCreated},
+		{"issue existing", h.IssueLicense, http.MethodPost, "", `{"id": "acme", "customer": "ACME Corp"}`, http.StatusConflict},
+		{"issue builtin", h.IssueLicense, http.MethodPost, "", `{"id": "default", "customer": "Default"}`, http.StatusConflict},
+		{"issue invalid ID", h.IssueLicense, http.MethodPost, "", `{"id": "-acme", "customer": "ACME Corp"}`, http.StatusBadRequest},
+		{"issue without customer", h.IssueLicense, http.MethodPost, "", `{"id": "globex"}`, http.StatusBadRequest},
+		{"issue empty customer", h.IssueLicense, http.MethodPost, "", `{"id": "globex", "customer": " "}`, http.StatusBadRequest},
+		{"issue invalid channel", h.IssueLicense, http.MethodPost, "", `{"id": "globex", "customer": "Globex", "channel": "nightly"}`, http.StatusBadRequest},
+		{"issue invalid versions", h.IssueLicense, http.MethodPost, "", `{"id": "globex", "customer": "Globex", "versions": ">=one"}`, http.StatusBadRequest},
+		{"issue unknown field", h.IssueLicense, http.MethodPost, "", `{"id": "globex", "customer": "Globex", "plan": "gold"}`, http.StatusBadRequest},
+		{"issue malformed body", h.IssueLicense, http.MethodPost, "", `{"id": `, http.StatusBadRequest},
+		{"patch", h.UpdateLicense, http.MethodPatch, "acme", `{"customer": "ACME Inc", "integrations": ["zabbix*"]}`, http.StatusOK},
+		{"patch nothing", h.UpdateLicense, http.MethodPatch, "acme", `{}`, http.StatusOK},
+		{"patch unknown", h.UpdateLicense, http.MethodPatch, "globex", `{"customer": "Globex"}`, http.StatusNotFound},
+		{"patch ID", h.UpdateLicense, http.MethodPatch, "acme", `{"id": "globex"}`, http.StatusBadRequest},
+		{"patch invalid expiry", h.UpdateLicense, http.MethodPatch, "acme", `{"expires_at": "tomorrow"}`, http.StatusBadRequest},
+		{"patch builtin", h.UpdateLicense, http.MethodPatch, "default", `{"customer": "Default"}`, http.StatusConflict},
+		{"rotate", h.RotateLicense, http.MethodPost, "acme", "", http.StatusOK},
+		{"rotate unknown", h.RotateLicense, http.MethodPost, "globex", "", http.StatusNotFound},
+		{"rotate builtin", h.RotateLicense, http.MethodPost, "default", "", http.StatusConflict},
+		{"suspend", h.SuspendLicense, http.MethodPost, "acme", "", http.StatusOK},
+		{"suspend unknown", h.SuspendLicense, http.MethodPost, "globex", "", http.StatusNotFound},
+		{"suspend builtin", h.SuspendLicense, http.MethodPost, "default", "", http.StatusConflict},
+		{"resume", h.ResumeLicense, http.MethodPost, "acme", "", http.StatusOK},
+		{"resume unknown", h.ResumeLicense, http.MethodPost, "globex", "", http.StatusNotFound},
+		{"revoke invalid ID", h.RevokeLicense, http.MethodDelete, "-acme", "", http.StatusBadRequest},
+		{"revoke builtin", h.RevokeLicense, http.MethodDelete, "default", "", http.StatusConflict},
+		{"revoke", h.RevokeLicense, http.MethodDelete, "acme", "", http.StatusOK},
+		{"revoke again", h.RevokeLicense, http.MethodDelete, "acme", "", http.StatusOK},
+		{"patch revoked", h.UpdateLicense, http.MethodPatch, "acme", `{"customer": "ACME Corp"}`, http.StatusConflict},
+		{"rotate revoked", h.RotateLicense, http.MethodPost, "acme", "", http.StatusConflict},
+		{"suspend revoked", h.SuspendLicense, http.MethodPost, "acme", "", http.StatusConflict},
+		{"resume revoked", h.ResumeLicense, http.MethodPost, "acme", "", http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		status, l := callLicense(t, tt.handler, tt.method, tt.id, tt.body)
+		if status != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, status, tt.wantStatus)
+			continue
+		}
+		if l != nil && l.TokenHash != "" {
+			t.Errorf("%s: response carries the token hash", tt.name)
+		}
+	}
+
+	l, ok := h.Licenses.Get("acme")
+	if !ok || l.Customer != "ACME Inc" || !l.Revoked || l.Suspended || len(l.Integrations) != 1 {
+		t.Errorf("acme = %+v, want the patched customer, revoked", l)
+	}
+	if _, ok := h.Licenses.Get("globex"); ok {
+		t.Error("registry holds globex after refused requests")
+	}
+}
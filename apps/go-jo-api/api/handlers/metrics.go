@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/metrics"
+)
+
+// MetricsHandler serves Prometheus metrics
+type MetricsHandler struct {
+	*BaseHandler
+}
+
+// NewMetricsHandler creates a new metrics handler
+func NewMetricsHandler(base *BaseHandler) *MetricsHandler {
+	return &MetricsHandler{
+		BaseHandler: base,
+	}
+}
+
+// MetricsAuthMiddleware requires the metrics token when one is configured.
+// Customer licenses are not accepted.
+func (h *MetricsHandler) MetricsAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := h.Config.Metrics.Token
+		if token == "" {
+			next(w, r)
+			return
+		}
+
+		if !presentsToken(r, token) {
+			h.SendErrorResponse(w, r, http.StatusUnauthorized, "Invalid metrics token")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// GetMetrics handles GET /metrics - Prometheus text format
+func (h *MetricsHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metrics.ContentType)
+	metrics.Write(w)
+}
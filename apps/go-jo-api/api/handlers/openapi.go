@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/openapi"
+)
+
+// OpenAPIHandler serves the OpenAPI document of the API
+type OpenAPIHandler struct {
+	*BaseHandler
+}
+
+// NewOpenAPIHandler creates a new OpenAPI handler
+func NewOpenAPIHandler(base *BaseHandler) *OpenAPIHandler {
+	return &OpenAPIHandler{
+		BaseHandler: base,
+	}
+}
+
+// GetOpenAPI handles GET /v1/openapi.json - OpenAPI 3 document (no auth required)
+func (h *OpenAPIHandler) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.Spec())
+}
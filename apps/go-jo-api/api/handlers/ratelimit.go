@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/license"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/metrics"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/ratelimit"
+)
+
+// Rate limit budgets, each with its own token bucket per license
+const (
+	BudgetList     = "list"
+	BudgetDownload = "download"
+)
+
+// concurrencyRetryAfter is suggested to clients refused for running too many downloads
+const concurrencyRetryAfter = 10 * time.Second
+
+// RateLimitMiddleware takes a token from the license's bucket for the budget
+// and answers 429 with Retry-After when it is empty. Must run after AuthMiddleware.
+func (h *BaseHandler) RateLimitMiddleware(budget string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lic := h.LicenseFromRequest(r)
+		if !h.Config.RateLimit.Enabled || lic == nil {
+			next(w, r)
+			return
+		}
+
+		if ok, wait := h.RateLimiter.Allow(budget+":"+lic.ID, h.rateFor(lic, budget)); !ok {
+			metrics.RateLimited.Inc(budget)
+			h.sendTooManyRequests(w, r, wait, fmt.Sprintf("Rate limit exceeded for license %s, retry in %s", lic.ID, wait.Round(time.Second)))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// DownloadConcurrencyMiddleware caps the simultaneous downloads of a license.
+// Must run after AuthMiddleware.
+func (h *BaseHandler) DownloadConcurrencyMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lic := h.LicenseFromRequest(r)
+		if !h.Config.RateLimit.Enabled || lic == nil {
+			next(w, r)
+			return
+		}
+
+		max := h.maxConcurrentDownloads(lic)
+		release, ok := h.Downloads.Acquire(lic.ID, max)
+		if !ok {
+			metrics.RateLimited.Inc("concurrent_downloads")
+			h.sendTooManyRequests(w, r, concurrencyRetryAfter, fmt.Sprintf("License %s already has %d downloads in progress", lic.ID, max))
+			return
+		}
+		defer release()
+
+		next(w, r)
+	}
+}
+
+// rateFor returns the budget of a license, applying its overrides to the global rate
+func (h *BaseHandler) rateFor(lic *license.License, budget string) ratelimit.Rate {
+	global := h.Config.RateLimit.List
+	if budget == BudgetDownload {
+		global = h.Config.RateLimit.Download
+	}
+	rate := ratelimit.Rate{PerMinute: global.PerMinute, Burst: global.Burst}
+
+	if overrides := lic.RateLimits; overrides != nil {
+		perMinute, burst := overrides.ListPerMinute, overrides.ListBurst
+		if budget == BudgetDownload {
+			perMinute, burst = overrides.DownloadPerMinute, overrides.DownloadBurst
+		}
+		if perMinute != 0 {
+			rate.PerMinute = perMinute
+		}
+		if burst != 0 {
+			rate.Burst = burst
+		}
+	}
+	return rate
+}
+
+// maxConcurrentDownloads returns the download cap of a license
+func (h *BaseHandler) maxConcurrentDownloads(lic *license.License) int {
+	if lic.RateLimits != nil && lic.RateLimits.MaxConcurrentDownloads != 0 {
+		return lic.RateLimits.MaxConcurrentDownloads
+	}
+	return h.Config.RateLimit.MaxConcurrentDownloads
+}
+
+// sendTooManyRequests answers 429 with a Retry-After in whole seconds
+func (h *BaseHandler) sendTooManyRequests(w http.ResponseWriter, r *http.Request, wait time.Duration, message string) {
+	setRetryAfter(w, wait)
+	h.SendErrorResponse(w, r, http.StatusTooManyRequests, message)
+}
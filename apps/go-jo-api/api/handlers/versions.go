@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"net/http"
 	"sort"
-	"strings"
 
+	"github.com/gorilla/mux"
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/license"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/source"
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/semver"
 )
 
 // VersionsHandler handles version-related requests
@@ -16,75 +20,143 @@ type VersionsHandler struct {
 }
 
 // NewVersionsHandler creates a new versions handler
-func NewVersionsHandler(config *domain.Config) *VersionsHandler {
+func NewVersionsHandler(base *BaseHandler) *VersionsHandler {
 	return &VersionsHandler{
-		BaseHandler: NewBaseHandler(config),
+		BaseHandler: base,
 	}
 }
 
 // GetVersions handles GET /versions - Get all available tagged versions of go-jo
 func (h *VersionsHandler) GetVersions(w http.ResponseWriter, r *http.Request) {
-	log.Printf("Fetching versions for repository: %s", h.Config.GetGoJoRepo())
+	logging.Logger(r.Context()).Info("Fetching versions", "source", h.Source.Name())
 
-	releases, err := h.fetchGitHubReleases(h.Config.GetGoJoRepo())
+	releases, err := h.listReleases(r.Context(), h.LicenseFromRequest(r))
 	if err != nil {
-		h.SendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch releases: "+err.Error())
+		h.SendSourceError(w, r, "Failed to fetch releases", err)
 		return
 	}
 
-	var versions []string
+	response := domain.VersionResponse{
+		Versions: []string{},
+		Latest:   latestStable(releases),
+		Releases: []domain.VersionInfo{},
+	}
 	for _, release := range releases {
-		if !release.Draft {
-			versions = append(versions, release.TagName)
+		response.Versions = append(response.Versions, release.Version)
+		response.Releases = append(response.Releases, domain.VersionInfo{
+			Version:    release.Version,
+			Prerelease: isPrerelease(release),
+		})
+	}
+
+	h.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// GetVersion handles GET /versions/{version} - Notes, publish date, assets
+// and commit of a version. "latest" resolves to the latest stable version.
+func (h *VersionsHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lic := h.LicenseFromRequest(r)
+	version := mux.Vars(r)["version"]
+
+	if version == "latest" {
+		latest, err := h.GetLatestVersion(ctx, lic)
+		if err != nil {
+			h.SendSourceError(w, r, "Failed to get latest version", err)
+			return
 		}
+		version = latest
 	}
 
-	// Sort versions (newest first)
-	sort.Slice(versions, func(i, j int) bool {
-		return h.compareVersions(versions[i], versions[j]) > 0
-	})
+	release, err := h.Source.GetVersion(ctx, version)
+	if err != nil {
+		h.SendSourceError(w, r, "Failed to fetch release", err)
+		return
+	}
 
-	response := domain.VersionResponse{Versions: versions}
-	h.SendJSONResponse(w, http.StatusOK, response)
+	// Versions outside the license's range or channel are not described either
+	if !lic.AllowsVersion(release.Version, isPrerelease(*release)) {
+		h.SendErrorResponse(w, r, http.StatusForbidden, fmt.Sprintf("License %s is not entitled to version %s", lic.ID, release.Version))
+		return
+	}
+
+	commit, err := h.Source.GetVersionCommit(ctx, release.Version)
+	if err != nil {
+		h.SendSourceError(w, r, "Failed to resolve release commit", err)
+		return
+	}
+
+	details := domain.VersionDetails{
+		Version:    release.Version,
+		Prerelease: isPrerelease(*release),
+		Commit:     commit,
+		Notes:      release.Notes,
+		Assets:     []domain.AssetInfo{},
+	}
+	if !release.PublishedAt.IsZero() {
+		publishedAt := release.PublishedAt.UTC()
+		details.PublishedAt = &publishedAt
+	}
+	for _, asset := range release.Assets {
+		details.Assets = append(details.Assets, domain.AssetInfo{Name: asset.Name, Size: asset.Size})
+	}
+
+	h.SendJSONResponse(w, http.StatusOK, details)
 }
 
-// GetLatestVersion returns the latest non-draft version
-func (h *VersionsHandler) GetLatestVersion() (string, error) {
-	releases, err := h.fetchGitHubReleases(h.Config.GetGoJoRepo())
+// GetLatestVersion returns the highest stable version the license may receive
+func (h *VersionsHandler) GetLatestVersion(ctx context.Context, lic *license.License) (string, error) {
+	releases, err := h.listReleases(ctx, lic)
 	if err != nil {
 		return "", err
 	}
 
+	latest := latestStable(releases)
+	if latest == "" {
+		return "", fmt.Errorf("latest version %w, no stable release is available", source.ErrNotFound)
+	}
+	return latest, nil
+}
+
+// listReleases returns the releases the license may receive, newest first
+func (h *VersionsHandler) listReleases(ctx context.Context, lic *license.License) ([]domain.Release, error) {
+	releases, err := h.Source.ListVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var allowed []domain.Release
 	for _, release := range releases {
-		if !release.Draft {
-			return release.TagName, nil
+		if lic.AllowsVersion(release.Version, isPrerelease(release)) {
+			allowed = append(allowed, release)
 		}
 	}
 
-	return "", fmt.Errorf("no releases found")
-}
-
-// fetchGitHubReleases fetches releases from GitHub API
-func (h *VersionsHandler) fetchGitHubReleases(repo string) ([]domain.GitHubRelease, error) {
-	url := fmt.Sprintf("%s/repos/%s/releases", h.Config.GetGitHubAPIBaseURL(), repo)
+	// Sort versions (newest first)
+	sort.SliceStable(allowed, func(i, j int) bool {
+		return h.compareVersions(allowed[i].Version, allowed[j].Version) > 0
+	})
 
-	var releases []domain.GitHubRelease
-	err := h.FetchFromGitHub(url, &releases)
-	return releases, err
+	return allowed, nil
 }
 
-// compareVersions provides basic version comparison
+// compareVersions compares two release tags by semantic version precedence
 func (h *VersionsHandler) compareVersions(v1, v2 string) int {
-	// Simple version comparison (for proper semver, use a library)
-	// Remove 'v' prefix and compare
-	clean1 := strings.TrimPrefix(v1, "v")
-	clean2 := strings.TrimPrefix(v2, "v")
+	return semver.CompareStrings(v1, v2)
+}
 
-	if clean1 == clean2 {
-		return 0
-	}
-	if clean1 > clean2 {
-		return 1
+// isPrerelease reports whether a release is flagged as prerelease by its
+// source or carries a semver prerelease suffix
+func isPrerelease(release domain.Release) bool {
+	return release.Prerelease || semver.IsPrereleaseString(release.Version)
+}
+
+// latestStable returns the first stable release of a list sorted newest first
+func latestStable(releases []domain.Release) string {
+	for _, release := range releases {
+		if !isPrerelease(release) {
+			return release.Version
+		}
 	}
-	return -1
+	return ""
 }
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/license"
+)
+
+func TestVersionEntitlements(t *testing.T) {
+	src := newFakeSource()
+	src.releases = append(src.releases,
+		domain.Release{Version: "v0.9.0"},
+		domain.Release{Version: "v1.2.0", Prerelease: true},
+		domain.Release{Version: "v2.0.0"},
+	)
+	h := NewVersionsHandler(newTestBase(t, src))
+
+	tests := []struct {
+		name     string
+		versions string
+		channel  string
+		want     []string
+	}{
+		{"unrestricted", "", "", []string{"v2.0.0", "v1.2.0", "v1.1.0-rc.1", "v1.0.0", "v0.9.0"}},
+		{"stable channel", "", license.ChannelStable, []string{"v2.0.0", "v1.0.0", "v0.9.0"}},
+		{"version range", ">=1.0.0 <2.0.0", "", []string{"v1.2.0", "v1.1.0-rc.1", "v1.0.0"}},
+		{"range and stable channel", ">=1.0.0 <2.0.0", license.ChannelStable, []string{"v1.0.0"}},
+		{"nothing matches", ">=3.0.0", "", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lic := &license.License{ID: "acme", Versions: tt.versions, Channel: tt.channel}
+
+			w := httptest.NewRecorder()
+			h.GetVersions(w, withLicenseOf(httptest.NewRequest(http.MethodGet, "/v1/versions", nil), lic))
+			if w.Code != http.StatusOK {
+				t.Fatalf("list status = %d, body %s", w.Code, w.Body)
+			}
+			var response domain.VersionResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decoding %s: %v", w.Body, err)
+			}
+			if !reflect.DeepEqual(response.Versions, tt.want) {
+				t.Errorf("versions = %q, want %q", response.Versions, tt.want)
+			}
+
+			// Every version left out of the list is refused on its own
+			for _, release := range src.releases {
+				r := httptest.NewRequest(http.MethodGet, "/v1/versions/"+release.Version, nil)
+				r = mux.SetURLVars(withLicenseOf(r, lic), map[string]string{"version": release.Version})
+				w := httptest.NewRecorder()
+				h.GetVersion(w, r)
+
+				wantStatus := http.StatusForbidden
+				for _, version := range tt.want {
+					if version == release.Version {
+						wantStatus = http.StatusOK
+					}
+				}
+				if w.Code != wantStatus {
+					t.Errorf("version %s status = %d, want %d", release.Version, w.Code, wantStatus)
+				}
+			}
+		})
+	}
+}
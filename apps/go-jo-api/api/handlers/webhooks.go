@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/audit"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/cache"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/source"
+)
+
+const (
+	// maxWebhookPayloadSize bounds the payload read before its signature is
+	// checked. Release and push deliveries are a few kilobytes, far below
+	// the 25MB GitHub allows.
+	maxWebhookPayloadSize = 1024 * 1024
+	// prebuildTimeout bounds the assembly of the packages of a new release
+	prebuildTimeout = 30 * time.Minute
+	// popularityWindow is how far back downloads count to pick the most
+	// popular integrations
+	popularityWindow = 30 * 24 * time.Hour
+)
+
+// Status of a webhook delivery in WebhookResponse
+const (
+	webhookProcessed = "processed"
+	webhookIgnored   = "ignored"
+	webhookPong      = "pong"
+)
+
+// WebhookHandler handles webhooks sent by GitHub
+type WebhookHandler struct {
+	*BaseHandler
+	downloadHandler *DownloadHandler
+
+	// prebuilding runs one release prebuild at a time
+	prebuilding sync.Mutex
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(base *BaseHandler, downloadHandler *DownloadHandler) *WebhookHandler {
+	return &WebhookHandler{
+		BaseHandler:     base,
+		downloadHandler: downloadHandler,
+	}
+}
+
+// GitHubWebhook handles POST /webhooks/github - React to release events of
+// the go-jo repository and push events of the docker-environments repository
+// by refreshing the listings and evicting stale cached packages. Deliveries
+// must be signed with the webhook secret.
+func (h *WebhookHandler) GitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	secret := h.Config.Webhooks.GitHub.Secret
+	if secret == "" {
+		h.SendErrorResponse(w, r, http.StatusForbidden, "GitHub webhooks are disabled, set webhooks.github.secret or GITHUB_WEBHOOK_SECRET")
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, "Failed to read payload: "+err.Error())
+		return
+	}
+
+	if !validSignature(secret, payload, r.Header.Get("X-Hub-Signature-256")) {
+		h.SendErrorResponse(w, r, http.StatusUnauthorized, "Invalid webhook signature")
+		return
+	}
+
+	response := domain.WebhookResponse{
+		Event:  r.Header.Get("X-GitHub-Event"),
+		Status: webhookIgnored,
+	}
+	if response.Event == "ping" {
+		response.Status = webhookPong
+		h.SendJSONResponse(w, http.StatusOK, response)
+		return
+	}
+
+	var event domain.GitHubWebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		h.SendErrorResponse(w, r, http.StatusBadRequest, "Invalid payload: "+err.Error())
+		return
+	}
+
+	logger := logging.Logger(r.Context()).With("event", response.Event, "delivery", r.Header.Get("X-GitHub-Delivery"),
+		"repository", event.Repository.FullName, "action", event.Action)
+
+	switch {
+	case response.Event == "release" && sameRepository(event.Repository.FullName, h.Config.GetGoJoRepo()) && event.Release != nil:
+		h.handleRelease(logger, &event, &response)
+	case response.Event == "push" && sameRepository(event.Repository.FullName, h.Config.GetDockerEnvRepo()):
+		h.handlePush(logger, &event, &response)
+	default:
+		logger.Info("Ignoring webhook")
+	}
+
+	h.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// handleRelease refreshes the version listing. The packages of a deleted or
+// unpublished release are evicted, and a newly published stable release is
+// assembled for the most popular integrations in the background.
+func (h *WebhookHandler) handleRelease(logger *slog.Logger, event *domain.GitHubWebhookEvent, response *domain.WebhookResponse) {
+	response.Status = webhookProcessed
+	h.invalidateListings()
+
+	release := event.Release
+	switch event.Action {
+	case "deleted", "unpublished":
+		for _, asset := range release.Assets {
+			response.Evicted += h.Cache.EvictPackage(strconv.Itoa(asset.ID))
+		}
+	case "published", "released":
+		if !release.Draft && !release.Prerelease && h.Config.Webhooks.GitHub.PrebuildIntegrations > 0 {
+			count := h.Config.Webhooks.GitHub.PrebuildIntegrations
+			response.Prebuild = h.Background.Go(func(ctx context.Context) {
+				h.prebuild(ctx, logger, release.TagName, count)
+			})
+		}
+	}
+
+	logger.Info("Release webhook processed", "version", release.TagName, "evicted", response.Evicted, "prebuild", response.Prebuild)
+}
+
+// handlePush refreshes the integration listing and evicts the packages of
+// the pushed branch built from an older commit, or all of them when the
+// branch was deleted
+func (h *WebhookHandler) handlePush(logger *slog.Logger, event *domain.GitHubWebhookEvent, response *domain.WebhookResponse) {
+	branch, ok := strings.CutPrefix(event.Ref, "refs/heads/")
+	if !ok {
+		logger.Info("Ignoring push of a ref that is not a branch", "ref", event.Ref)
+		return
+	}
+
+	response.Status = webhookProcessed
+	h.invalidateListings()
+
+	keep := event.After
+	if event.Deleted {
+		keep = ""
+	}
+	response.Evicted = h.Cache.EvictIntegration(branch, keep)
+
+	logger.Info("Push webhook processed", "integration", branch, "commit", event.After, "evicted", response.Evicted)
+}
+
+// invalidateListings drops the listings kept in memory by the artifact source
+func (h *WebhookHandler) invalidateListings() {
+	if invalidator, ok := h.Source.(source.Invalidator); ok {
+		invalidator.Invalidate()
+	}
+}
+
+// prebuild assembles the packages of a release for the most downloaded
+// integrations, skipping packages already cached. It stops when ctx is
+// cancelled on shutdown.
+func (h *WebhookHandler) prebuild(ctx context.Context, logger *slog.Logger, version string, count int) {
+	h.prebuilding.Lock()
+	defer h.prebuilding.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	integrations, err := h.popularIntegrations(count)
+	if err != nil {
+		logger.Error("Failed to find popular integrations", "error", err)
+		return
+	}
+	if len(integrations) == 0 {
+		logger.Info("No downloads in the audit log, nothing to prebuild", "version", version)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, prebuildTimeout)
+	defer cancel()
+
+	release, err := h.Source.GetVersion(ctx, version)
+	if err != nil {
+		logger.Error("Failed to prebuild release", "version", version, "error", err)
+		return
+	}
+	if release.AppPackage == nil {
+		logger.Warn("Not prebuilding release without a .deb file", "version", version)
+		return
+	}
+
+	for _, name := range integrations {
+		if ctx.Err() != nil {
+			logger.Info("Prebuild stopped", "version", version, "error", ctx.Err())
+			return
+		}
+
+		integration, err := h.Source.GetIntegration(ctx, name)
+		if err != nil {
+			logger.Error("Failed to prebuild package", "version", version, "integration", name, "error", err)
+			continue
+		}
+
+		key := cache.Key{PackageID: release.AppPackage.ID, Integration: name, Commit: integration.Commit}
+		if cached, ok := h.Cache.Open(key); ok {
+			cached.Close()
+			continue
+		}
+
+		start := time.Now()
+		built, err := h.downloadHandler.assemblePackage(ctx, key, release.AppPackage, integration)
+		if err != nil {
+			logger.Error("Failed to prebuild package", "version", version, "integration", name, "error", err)
+			continue
+		}
+		built.Close()
+
+		logger.Info("Prebuilt package", "version", version, "integration", name, "commit", integration.Commit,
+			"duration_ms", time.Since(start).Milliseconds())
+	}
+}
+
+// popularIntegrations returns the integrations with the most successful
+// downloads in the audit log over the popularity window, at most count. Only
+// the number of downloads per integration is kept while reading the log.
+func (h *WebhookHandler) popularIntegrations(count int) ([]string, error) {
+	downloads := make(map[string]int)
+	err := h.Audit.Scan(audit.Filter{
+		Kind: audit.KindDownload,
+		From: time.Now().Add(-popularityWindow),
+	}, func(entry *audit.Entry) bool {
+		if entry.Result == audit.ResultSuccess && entry.Integration != "" {
+			downloads[entry.Integration]++
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	integrations := make([]string, 0, len(downloads))
+	for name := range downloads {
+		integrations = append(integrations, name)
+	}
+	sort.Slice(integrations, func(i, j int) bool {
+		if downloads[integrations[i]] != downloads[integrations[j]] {
+			return downloads[integrations[i]] > downloads[integrations[j]]
+		}
+		return integrations[i] < integrations[j]
+	})
+
+	if len(integrations) > count {
+		integrations = integrations[:count]
+	}
+	return integrations, nil
+}
+
+// validSignature checks an X-Hub-Signature-256 header against the
+// HMAC-SHA256 of the payload
+func validSignature(secret string, payload []byte, header string) bool {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// sameRepository compares "owner/name" repository names, which GitHub
+// treats case-insensitively
+func sameRepository(a, b string) bool {
+	return a != "" && strings.EqualFold(a, b)
+}
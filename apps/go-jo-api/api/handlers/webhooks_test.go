@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sign returns the X-Hub-Signature-256 header of a payload
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidSignature(t *testing.T) {
+	const secret = "webhook-secret"
+	payload := []byte(`{"action":"published"}`)
+	valid := sign(secret, payload)
+
+	tests := []struct {
+		name    string
+		payload []byte
+		header  string
+		want    bool
+	}{
+		{"valid", payload, valid, true},
+		{"empty payload", nil, sign(secret, nil), true},
+		{"uppercase hex", payload, "sha256=" + strings.ToUpper(strings.TrimPrefix(valid, "sha256=")), true},
+		{"missing header", payload, "", false},
+		{"missing prefix", payload, strings.TrimPrefix(valid, "sha256="), false},
+		{"sha1 header", payload, "sha1=" + strings.TrimPrefix(valid, "sha256="), false},
+		{"wrong secret", payload, sign("other-secret", payload), false},
+		{"modified payload", []byte(`{"action":"deleted"}`), valid, false},
+		{"truncated signature", payload, valid[:len(valid)-2], false},
+		{"invalid hex", payload, valid[:len(valid)-1] + "z", false},
+		{"prefix only", payload, "sha256=", false},
+	}
+
+	for _, tt := range tests {
+		if got := validSignature(secret, tt.payload, tt.header); got != tt.want {
+			t.Errorf("%s: validSignature = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGitHubWebhookPayloadSize(t *testing.T) {
+	base := newTestBase(t, newFakeSource())
+	base.Config.Webhooks.GitHub.Secret = "webhook-secret"
+	h := NewWebhookHandler(base, NewDownloadHandler(base, NewVersionsHandler(base)))
+
+	deliver := func(payload []byte) int {
+		r := httptest.NewRequest(http.MethodPost, "/v1/webhooks/github", bytes.NewReader(payload))
+		r.Header.Set("X-GitHub-Event", "ping")
+		r.Header.Set("X-Hub-Signature-256", sign("webhook-secret", payload))
+		w := httptest.NewRecorder()
+		h.GitHubWebhook(w, r)
+		return w.Code
+	}
+
+	if status := deliver([]byte(`{"zen":"Keep it simple."}`)); status != http.StatusOK {
+		t.Errorf("ping status = %d, want 200", status)
+	}
+	if status := deliver(bytes.Repeat([]byte(" "), maxWebhookPayloadSize+1)); status != http.StatusBadRequest {
+		t.Errorf("oversized payload status = %d, want 400", status)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package health
+
+// FreeSpace is not implemented on this platform, only the write test runs
+func FreeSpace(dir string) (uint64, error) {
+	return 0, errUnsupported
+}
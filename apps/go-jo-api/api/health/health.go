@@ -0,0 +1,35 @@
+// Package health provides the checks behind the readiness endpoint
+package health
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var errUnsupported = errors.New("free space check not supported on this platform")
+
+// CheckDir verifies that dir is writable and has at least minFree bytes available
+func CheckDir(dir string, minFree uint64) error {
+	file, err := os.CreateTemp(dir, ".ready-*")
+	if err != nil {
+		return fmt.Errorf("not writable: %w", err)
+	}
+	name := file.Name()
+	file.Close()
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("failed to remove test file: %w", err)
+	}
+
+	free, err := FreeSpace(dir)
+	if err == errUnsupported {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read free space: %w", err)
+	}
+	if free < minFree {
+		return fmt.Errorf("%d MB free, %d MB required", free/(1024*1024), minFree/(1024*1024))
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package license
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the resolved license
+func NewContext(ctx context.Context, l *License) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the license resolved for the current request
+func FromContext(ctx context.Context) (*License, bool) {
+	l, ok := ctx.Value(contextKey{}).(*License)
+	return l, ok
+}
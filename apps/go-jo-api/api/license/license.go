@@ -0,0 +1,151 @@
+package license
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/licensefile"
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/semver"
+)
+
+// Release channels a license can be restricted to
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta"
+)
+
+// License validation errors
+var (
+	ErrInvalidToken = errors.New("invalid license token")
+	ErrExpired      = errors.New("license has expired")
+	ErrRevoked      = errors.New("license has been revoked")
+	ErrSuspended    = errors.New("license has been suspended")
+)
+
+// License represents a customer license accepted by the API
+type License struct {
+	ID           string      `json:"id"`
+	Customer     string      `json:"customer"`
+	TokenHash    string      `json:"token_hash,omitempty"`
+	CreatedAt    time.Time   `json:"created_at,omitzero"`
+	ExpiresAt    time.Time   `json:"expires_at,omitzero"`
+	Revoked      bool        `json:"revoked"`
+	Suspended    bool        `json:"suspended,omitempty"`
+	Integrations []string    `json:"integrations,omitempty"`
+	Versions     string      `json:"versions,omitempty"`
+	Channel      string      `json:"channel,omitempty"`
+	RateLimits   *RateLimits `json:"rate_limits,omitempty"`
+}
+
+// RateLimits overrides the global rate limits for one license. Zero fields
+// use the global value, negative fields disable that limit.
+type RateLimits struct {
+	ListPerMinute          float64 `json:"list_per_minute,omitempty"`
+	ListBurst              int     `json:"list_burst,omitempty"`
+	DownloadPerMinute      float64 `json:"download_per_minute,omitempty"`
+	DownloadBurst          int     `json:"download_burst,omitempty"`
+	MaxConcurrentDownloads int     `json:"max_concurrent_downloads,omitempty"`
+}
+
+// FromPayload builds a license from a verified signed license file
+func FromPayload(p *licensefile.Payload) *License {
+	return &License{
+		ID:           p.ID,
+		Customer:     p.Customer,
+		CreatedAt:    p.IssuedAt,
+		ExpiresAt:    p.ExpiresAt,
+		Integrations: p.Integrations,
+		Versions:     p.Versions,
+		Channel:      p.Channel,
+	}
+}
+
+// CheckEntitlements validates the version range and channel of the license
+func (l *License) CheckEntitlements() error {
+	if _, err := semver.ParseConstraint(l.Versions); err != nil {
+		return err
+	}
+
+	switch l.Channel {
+	case "", ChannelStable, ChannelBeta:
+		return nil
+	}
+	return fmt.Errorf("invalid channel %q (expected %q or %q)", l.Channel, ChannelStable, ChannelBeta)
+}
+
+// Validate checks that the license is usable at the given time
+func (l *License) Validate(now time.Time) error {
+	if l.Revoked {
+		return ErrRevoked
+	}
+	if l.Suspended {
+		return ErrSuspended
+	}
+	if !l.ExpiresAt.IsZero() && now.After(l.ExpiresAt) {
+		return ErrExpired
+	}
+	return nil
+}
+
+// AllowsIntegration reports whether the license is entitled to an integration.
+// Integrations are matched against the license's glob patterns; a license
+// without patterns is entitled to every integration.
+func (l *License) AllowsIntegration(name string) bool {
+	if len(l.Integrations) == 0 {
+		return true
+	}
+
+	for _, pattern := range l.Integrations {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsVersion reports whether the license may receive a release.
+// The "stable" channel excludes prereleases, as told by the caller, and the
+// license's version range must be satisfied. Licenses with an unusable
+// version range or unparsable tags are refused rather than left unrestricted.
+func (l *License) AllowsVersion(tag string, prerelease bool) bool {
+	if l.Channel == ChannelStable && prerelease {
+		return false
+	}
+
+	if l.Versions == "" {
+		return true
+	}
+
+	constraint, err := semver.ParseConstraint(l.Versions)
+	if err != nil {
+		return false
+	}
+
+	version, err := semver.Parse(tag)
+	if err != nil {
+		return false
+	}
+
+	return constraint.Check(version)
+}
+
+// Clone returns a copy of the license that can be handed out safely
+func (l *License) Clone() *License {
+	clone := *l
+	clone.Integrations = append([]string(nil), l.Integrations...)
+	if l.RateLimits != nil {
+		rateLimits := *l.RateLimits
+		clone.RateLimits = &rateLimits
+	}
+	return &clone
+}
+
+// HashToken returns the hex encoded SHA-256 hash stored for a license token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,68 @@
+package license
+
+import "testing"
+
+func TestAllowsIntegration(t *testing.T) {
+	tests := []struct {
+		name         string
+		integrations []string
+		integration  string
+		want         bool
+	}{
+		{"no patterns", nil, "zabbix", true},
+		{"exact name", []string{"zabbix"}, "zabbix", true},
+		{"other name", []string{"zabbix"}, "grafana", false},
+		{"prefix is not a match", []string{"zabbix"}, "zabbix-agent", false},
+		{"star", []string{"zabbix*"}, "zabbix-agent", true},
+		{"star matches empty", []string{"zabbix*"}, "zabbix", true},
+		{"star outside pattern", []string{"zabbix*"}, "grafana-zabbix", false},
+		{"question mark", []string{"grafana-v?"}, "grafana-v9", true},
+		{"question mark is one character", []string{"grafana-v?"}, "grafana-v10", false},
+		{"character class", []string{"[gz]*"}, "grafana", true},
+		{"second pattern", []string{"zabbix*", "grafana"}, "grafana", true},
+		{"star stops at slash", []string{"customer*"}, "customer/acme", false},
+		{"path pattern", []string{"customer/*"}, "customer/acme", true},
+		{"malformed pattern", []string{"[zabbix"}, "zabbix", false},
+		{"malformed pattern before a match", []string{"[zabbix", "zabbix"}, "zabbix", true},
+	}
+
+	for _, tt := range tests {
+		l := &License{Integrations: tt.integrations}
+		if got := l.AllowsIntegration(tt.integration); got != tt.want {
+			t.Errorf("%s: AllowsIntegration(%q) with %q = %v, want %v", tt.name, tt.integration, tt.integrations, got, tt.want)
+		}
+	}
+}
+
+func TestAllowsVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		versions   string
+		channel    string
+		tag        string
+		prerelease bool
+		want       bool
+	}{
+		{"unrestricted", "", "", "v1.0.0", false, true},
+		{"unrestricted prerelease", "", "", "v1.1.0-rc.1", true, true},
+		{"beta channel prerelease", "", ChannelBeta, "v1.1.0-rc.1", true, true},
+		{"stable channel release", "", ChannelStable, "v1.0.0", false, true},
+		{"stable channel prerelease", "", ChannelStable, "v1.1.0-rc.1", true, false},
+		{"stable channel flagged release", "", ChannelStable, "v1.1.0", true, false},
+		{"stable channel within range", ">=1.0.0", ChannelStable, "v1.1.0-rc.1", true, false},
+		{"within range", ">=1.0.0 <2.0.0", "", "v1.4.2", false, true},
+		{"lower bound", ">=1.0.0 <2.0.0", "", "v1.0.0", false, true},
+		{"below range", ">=1.0.0 <2.0.0", "", "v0.9.9", false, false},
+		{"above range", ">=1.0.0 <2.0.0", "", "v2.0.0", false, false},
+		{"prerelease below range", ">=1.1.0", "", "v1.1.0-rc.1", true, false},
+		{"unparsable tag", ">=1.0.0", "", "nightly", false, false},
+		{"unusable range", ">=one", "", "v1.0.0", false, false},
+	}
+
+	for _, tt := range tests {
+		l := &License{Versions: tt.versions, Channel: tt.channel}
+		if got := l.AllowsVersion(tt.tag, tt.prerelease); got != tt.want {
+			t.Errorf("%s: AllowsVersion(%q, %v) = %v, want %v", tt.name, tt.tag, tt.prerelease, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package license
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/licensefile"
+)
+
+// storeFile is the on-disk format of the license registry
+type storeFile struct {
+	Licenses []*License `json:"licenses"`
+}
+
+// Registry errors
+var (
+	ErrNotFound = errors.New("license not found")
+	ErrExists   = errors.New("license already exists")
+	ErrBuiltin  = errors.New("license is built in and can't be changed at runtime")
+)
+
+// tokenSize is the number of random bytes of an issued token
+const tokenSize = 32
+
+// entry keeps a license together with its decoded token hash
+type entry struct {
+	license *License
+	hash    []byte
+	// builtin licenses come from the configuration, not the registry file
+	builtin bool
+}
+
+// Store holds every license known to the API and the key trusted for
+// signed license files. Changes made at runtime are saved to the registry
+// file the store was loaded from.
+type Store struct {
+	mu        sync.RWMutex
+	path      string
+	entries   map[string]*entry
+	publicKey ed25519.PublicKey
+}
+
+// NewStore creates an empty license store
+func NewStore() *Store {
+	return &Store{
+		entries: make(map[string]*entry),
+	}
+}
+
+// LoadStore creates a license store from a JSON registry file.
+// A missing file results in an empty store.
+func LoadStore(path string) (*Store, error) {
+	store := NewStore()
+	store.path = path
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read license file: %w", err)
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse license file %s: %w", path, err)
+	}
+
+	for _, l := range file.Licenses {
+		if err := store.Add(l); err != nil {
+			return nil, fmt.Errorf("invalid license in %s: %w", path, err)
+		}
+	}
+
+	return store, nil
+}
+
+// Add registers a license in the store
+func (s *Store) Add(l *License) error {
+	return s.add(l, false)
+}
+
+// AddBuiltin registers a license from the configuration. Built-in licenses
+// are not saved to the registry file and can't be changed at runtime.
+func (s *Store) AddBuiltin(l *License) error {
+	return s.add(l, true)
+}
+
+func (s *Store) add(l *License, builtin bool) error {
+	e, err := newEntry(l)
+	if err != nil {
+		return err
+	}
+	e.builtin = builtin
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[l.ID]; exists {
+		return fmt.Errorf("duplicate license ID: %s", l.ID)
+	}
+	s.entries[l.ID] = e
+	return nil
+}
+
+// newEntry validates a license and decodes its token hash
+func newEntry(l *License) (*entry, error) {
+	if l.ID == "" {
+		return nil, fmt.Errorf("license ID is required")
+	}
+
+	hash, err := hex.DecodeString(strings.TrimSpace(l.TokenHash))
+	if err != nil || len(hash) != sha256.Size {
+		return nil, fmt.Errorf("license %s: token_hash must be a hex encoded SHA-256 hash", l.ID)
+	}
+
+	if err := l.CheckEntitlements(); err != nil {
+		return nil, fmt.Errorf("license %s: %w", l.ID, err)
+	}
+
+	return &entry{license: l.Clone(), hash: hash}, nil
+}
+
+// Issue adds a new license with a freshly generated token and saves the
+// registry. The token is returned once; only its hash is kept.
+func (s *Store) Issue(l *License) (*License, string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	l = l.Clone()
+	l.TokenHash = HashToken(token)
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	}
+
+	e, err := newEntry(l)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[l.ID]; exists {
+		return nil, "", fmt.Errorf("%w: %s", ErrExists, l.ID)
+	}
+
+	s.entries[l.ID] = e
+	if err := s.save(); err != nil {
+		delete(s.entries, l.ID)
+		return nil, "", err
+	}
+	return e.license.Clone(), token, nil
+}
+
+// Update applies change to a copy of a license and saves the registry.
+// The store is left untouched when change or saving fails.
+func (s *Store) Update(id string, change func(l *License) error) (*License, error) {
+	return s.update(id, change, nil)
+}
+
+// UpdateOrBlock applies change like Update, but registers a license holding
+// only the ID when none exists. Signed license files have no registry entry,
+// so this is how they are revoked or suspended. The new entry gets the hash
+// of a discarded token and can't be authenticated with on its own.
+func (s *Store) UpdateOrBlock(id string, change func(l *License) error) (*License, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.update(id, change, &License{
+		ID:        id,
+		TokenHash: HashToken(token),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	})
+}
+
+// update applies change to the license with the given ID, or to missing
+// when there is none and missing is set
+func (s *Store) update(id string, change func(l *License) error, missing *License) (*License, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.entries[id]
+	var l *License
+	switch {
+	case ok && previous.builtin:
+		return nil, fmt.Errorf("%w: %s", ErrBuiltin, id)
+	case ok:
+		l = previous.license.Clone()
+	case missing != nil:
+		l = missing.Clone()
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	if err := change(l); err != nil {
+		return nil, err
+	}
+	l.ID = id
+
+	e, err := newEntry(l)
+	if err != nil {
+		return nil, err
+	}
+
+	s.entries[id] = e
+	if err := s.save(); err != nil {
+		if ok {
+			s.entries[id] = previous
+		} else {
+			delete(s.entries, id)
+		}
+		return nil, err
+	}
+	return e.license.Clone(), nil
+}
+
+// Rotate replaces the token of a license and returns the new token once.
+// The previous token stops working immediately.
+func (s *Store) Rotate(id string) (*License, string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	l, err := s.Update(id, func(l *License) error {
+		if l.Revoked {
+			return ErrRevoked
+		}
+		l.TokenHash = HashToken(token)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return l, token, nil
+}
+
+// IsBuiltin reports whether a license comes from the configuration
+func (s *Store) IsBuiltin(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.entries[id]
+	return ok && e.builtin
+}
+
+// save writes the registry file atomically: the licenses go to a temp file
+// in the same directory, which then replaces the registry. Built-in licenses
+// are left out. Must be called with the lock held.
+func (s *Store) save() error {
+	if s.path == "" {
+		return errors.New("no license file configured")
+	}
+
+	file := storeFile{Licenses: []*License{}}
+	for _, e := range s.entries {
+		if !e.builtin {
+			file.Licenses = append(file.Licenses, e.license)
+		}
+	}
+	sort.Slice(file.Licenses, func(i, j int) bool {
+		return file.Licenses[i].ID < file.Licenses[j].ID
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	temp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to save license file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to save license file: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to save license file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to save license file: %w", err)
+	}
+	if err := os.Rename(temp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save license file: %w", err)
+	}
+	return nil
+}
+
+// generateToken returns a random hex encoded license token
+func generateToken() (string, error) {
+	buf := make([]byte, tokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// SetPublicKey configures the key used to verify signed license files
+func (s *Store) SetPublicKey(key ed25519.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.publicKey = key
+}
+
+// HasPublicKey reports whether signed license files are accepted
+func (s *Store) HasPublicKey() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.publicKey != nil
+}
+
+// Get returns the license with the given ID
+func (s *Store) Get(id string) (*License, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.entries[id]
+	if !ok {
+		return nil, false
+	}
+	return e.license.Clone(), true
+}
+
+// List returns all licenses sorted by ID
+func (s *Store) List() []*License {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	licenses := make([]*License, 0, len(s.entries))
+	for _, e := range s.entries {
+		licenses = append(licenses, e.license.Clone())
+	}
+	sort.Slice(licenses, func(i, j int) bool {
+		return licenses[i].ID < licenses[j].ID
+	})
+	return licenses
+}
+
+// Len returns the number of licenses in the store
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// Authenticate resolves the license owning the presented token.
+// Signed license files are verified against the public key without a registry
+// lookup; every other token is compared in constant time against each stored
+// hash so the lookup does not leak which licenses exist.
+func (s *Store) Authenticate(token string) (*License, error) {
+	s.mu.RLock()
+	publicKey := s.publicKey
+	s.mu.RUnlock()
+
+	if publicKey != nil && licensefile.IsSigned(token) {
+		return s.authenticateSigned(token, publicKey)
+	}
+
+	presented := sha256.Sum256([]byte(token))
+
+	s.mu.RLock()
+	var match *License
+	for _, e := range s.entries {
+		if subtle.ConstantTimeCompare(presented[:], e.hash) == 1 {
+			match = e.license.Clone()
+		}
+	}
+	s.mu.RUnlock()
+
+	if match == nil {
+		return nil, ErrInvalidToken
+	}
+	if err := match.Validate(time.Now()); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+// authenticateSigned verifies a signed license file. A registry entry with the
+// same ID is only consulted to honour revocations, suspensions and rate limits.
+func (s *Store) authenticateSigned(token string, publicKey ed25519.PublicKey) (*License, error) {
+	payload, err := licensefile.Verify(token, publicKey, time.Now())
+	if err != nil {
+		if errors.Is(err, licensefile.ErrExpired) {
+			return nil, ErrExpired
+		}
+		return nil, err
+	}
+
+	lic := FromPayload(payload)
+
+	// A registry entry with the same ID can revoke, suspend or throttle a signed license
+	if stored, ok := s.Get(payload.ID); ok {
+		if stored.Revoked {
+			return nil, ErrRevoked
+		}
+		if stored.Suspended {
+			return nil, ErrSuspended
+		}
+		lic.RateLimits = stored.RateLimits
+	}
+
+	return lic, nil
+}
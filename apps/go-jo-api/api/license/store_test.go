@@ -0,0 +1,391 @@
+package license
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/licensefile"
+)
+
+// newTestStore returns a store saving to a registry file in a temp dir
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	store, err := LoadStore(filepath.Join(t.TempDir(), "licenses.json"))
+	if err != nil {
+		t.Fatalf("LoadStore error = %v", err)
+	}
+	return store
+}
+
+// issue issues a license and returns its token
+func issue(t *testing.T, store *Store, l *License) string {
+	t.Helper()
+
+	_, token, err := store.Issue(l)
+	if err != nil {
+		t.Fatalf("Issue(%s) error = %v", l.ID, err)
+	}
+	return token
+}
+
+func TestAuthenticate(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	otherKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	sign := func(payload *licensefile.Payload) string {
+		signed, err := licensefile.Sign(payload, privateKey)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return signed
+	}
+
+	store := newTestStore(t)
+	store.SetPublicKey(publicKey)
+	if !store.HasPublicKey() {
+		t.Error("HasPublicKey = false with a key")
+	}
+	acme := issue(t, store, &License{ID: "acme", Customer: "ACME Corp"})
+	expired := issue(t, store, &License{ID: "expired", ExpiresAt: time.Now().Add(-time.Hour)})
+	suspended := issue(t, store, &License{ID: "suspended", Suspended: true})
+	revoked := issue(t, store, &License{ID: "revoked", Revoked: true})
+
+	limits := &RateLimits{ListPerMinute: 5}
+	for _, id := range []string{"signed-revoked", "signed-suspended", "signed-limited"} {
+		if _, err := store.UpdateOrBlock(id, func(l *License) error {
+			l.Revoked = id == "signed-revoked"
+			l.Suspended = id == "signed-suspended"
+			if id == "signed-limited" {
+				l.RateLimits = limits
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("UpdateOrBlock(%s) error = %v", id, err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantErr    error
+		wantID     string
+		wantLimits *RateLimits
+	}{
+		{name: "token", token: acme, wantID: "acme"},
+		{name: "unknown token", token: "not-a-token", wantErr: ErrInvalidToken},
+		{name: "empty token", token: "", wantErr: ErrInvalidToken},
+		{name: "token hash", token: HashToken(acme), wantErr: ErrInvalidToken},
+		{name: "expired", token: expired, wantErr: ErrExpired},
+		{name: "suspended", token: suspended, wantErr: ErrSuspended},
+		{name: "revoked", token: revoked, wantErr: ErrRevoked},
+		{name: "signed", token: sign(&licensefile.Payload{ID: "offline", Customer: "Offline Corp"}), wantID: "offline"},
+		{name: "signed expired", token: sign(&licensefile.Payload{ID: "offline", ExpiresAt: time.Now().Add(-time.Hour)}), wantErr: ErrExpired},
+		{name: "signed revoked by ID", token: sign(&licensefile.Payload{ID: "signed-revoked"}), wantErr: ErrRevoked},
+		{name: "signed suspended by ID", token: sign(&licensefile.Payload{ID: "signed-suspended"}), wantErr: ErrSuspended},
+		{name: "signed rate limits by ID", token: sign(&licensefile.Payload{ID: "signed-limited"}), wantID: "signed-limited", wantLimits: limits},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := store.Authenticate(tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate error = %v", err)
+			}
+			if l.ID != tt.wantID {
+				t.Errorf("license = %s, want %s", l.ID, tt.wantID)
+			}
+			if (l.RateLimits == nil) != (tt.wantLimits == nil) || (tt.wantLimits != nil && *l.RateLimits != *tt.wantLimits) {
+				t.Errorf("rate limits = %v, want %v", l.RateLimits, tt.wantLimits)
+			}
+		})
+	}
+
+	// Without a public key signed licenses are plain, unknown tokens
+	store.SetPublicKey(nil)
+	if store.HasPublicKey() {
+		t.Error("HasPublicKey = true after removing the key")
+	}
+	if _, err := store.Authenticate(sign(&licensefile.Payload{ID: "offline"})); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate without a public key error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	// A signature from another key is refused
+	store.SetPublicKey(otherKey)
+	if _, err := store.Authenticate(sign(&licensefile.Payload{ID: "offline"})); !errors.Is(err, licensefile.ErrInvalidSignature) {
+		t.Errorf("Authenticate with another key error = %v, want %v", err, licensefile.ErrInvalidSignature)
+	}
+}
+
+func TestIssue(t *testing.T) {
+	store := newTestStore(t)
+
+	tests := []struct {
+		name    string
+		license *License
+		wantErr bool
+	}{
+		{name: "valid", license: &License{ID: "acme", Customer: "ACME Corp", Versions: ">=1.0", Channel: ChannelStable}},
+		{name: "duplicate", license: &License{ID: "acme"}, wantErr: true},
+		{name: "missing ID", license: &License{Customer: "Nobody"}, wantErr: true},
+		{name: "invalid versions", license: &License{ID: "bad-versions", Versions: ">=one"}, wantErr: true},
+		{name: "invalid channel", license: &License{ID: "bad-channel", Channel: "nightly"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issued, token, err := store.Issue(tt.license)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Issue succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Issue error = %v", err)
+			}
+
+			if len(token) != 2*tokenSize || issued.TokenHash != HashToken(token) {
+				t.Errorf("token = %q with hash %q, want a %d byte hex token and its hash", token, issued.TokenHash, tokenSize)
+			}
+			if issued.CreatedAt.IsZero() {
+				t.Error("CreatedAt is not set")
+			}
+			if l, err := store.Authenticate(token); err != nil || l.ID != tt.license.ID {
+				t.Errorf("Authenticate = %v, %v, want %s", l, err, tt.license.ID)
+			}
+		})
+	}
+
+	if _, _, err := store.Issue(&License{ID: "acme"}); !errors.Is(err, ErrExists) {
+		t.Errorf("Issue of an existing ID error = %v, want %v", err, ErrExists)
+	}
+
+	// Issued licenses are saved to the registry file
+	reloaded, err := LoadStore(store.path)
+	if err != nil {
+		t.Fatalf("LoadStore error = %v", err)
+	}
+	if l, ok := reloaded.Get("acme"); !ok || l.Customer != "ACME Corp" {
+		t.Errorf("reloaded license = %v, %v, want acme", l, ok)
+	}
+}
+
+func TestIssueWithoutRegistryFile(t *testing.T) {
+	store := NewStore()
+	if _, _, err := store.Issue(&License{ID: "acme"}); err == nil {
+		t.Fatal("Issue succeeded without a registry file")
+	}
+	if store.Len() != 0 {
+		t.Errorf("store holds %d licenses after a failed save, want 0", store.Len())
+	}
+}
+
+func TestRotate(t *testing.T) {
+	store := newTestStore(t)
+	old := issue(t, store, &License{ID: "acme"})
+	issue(t, store, &License{ID: "revoked", Revoked: true})
+
+	rotated, token, err := store.Rotate("acme")
+	if err != nil {
+		t.Fatalf("Rotate error = %v", err)
+	}
+	if token == old || rotated.TokenHash != HashToken(token) {
+		t.Errorf("Rotate returned token %q with hash %q, want a new token", token, rotated.TokenHash)
+	}
+	if _, err := store.Authenticate(old); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate with the old token error = %v, want %v", err, ErrInvalidToken)
+	}
+	if l, err := store.Authenticate(token); err != nil || l.ID != "acme" {
+		t.Errorf("Authenticate with the new token = %v, %v, want acme", l, err)
+	}
+
+	tests := []struct {
+		id      string
+		wantErr error
+	}{
+		{"revoked", ErrRevoked},
+		{"missing", ErrNotFound},
+	}
+	for _, tt := range tests {
+		if _, _, err := store.Rotate(tt.id); !errors.Is(err, tt.wantErr) {
+			t.Errorf("Rotate(%s) error = %v, want %v", tt.id, err, tt.wantErr)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	store := newTestStore(t)
+	issue(t, store, &License{ID: "acme", Customer: "ACME Corp"})
+
+	changeFailed := errors.New("change failed")
+	tests := []struct {
+		name         string
+		id           string
+		change       func(l *License) error
+		wantErr      bool
+		wantCustomer string
+	}{
+		{
+			name:         "change",
+			id:           "acme",
+			change:       func(l *License) error { l.Customer = "ACME Inc"; return nil },
+			wantCustomer: "ACME Inc",
+		},
+		{
+			name:         "ID can't change",
+			id:           "acme",
+			change:       func(l *License) error { l.ID = "other"; return nil },
+			wantCustomer: "ACME Inc",
+		},
+		{
+			name:         "failed change",
+			id:           "acme",
+			change:       func(l *License) error { l.Customer = "Lost"; return changeFailed },
+			wantErr:      true,
+			wantCustomer: "ACME Inc",
+		},
+		{
+			name:         "invalid result",
+			id:           "acme",
+			change:       func(l *License) error { l.Channel = "nightly"; return nil },
+			wantErr:      true,
+			wantCustomer: "ACME Inc",
+		},
+		{
+			name:    "missing",
+			id:      "missing",
+			change:  func(l *License) error { return nil },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.Update(tt.id, tt.change); (err != nil) != tt.wantErr {
+				t.Fatalf("Update error = %v, want error %v", err, tt.wantErr)
+			}
+
+			l, ok := store.Get(tt.id)
+			if tt.wantCustomer == "" {
+				if ok {
+					t.Errorf("Update created %s", tt.id)
+				}
+				return
+			}
+			if !ok || l.Customer != tt.wantCustomer || l.Channel != "" {
+				t.Errorf("license = %+v, want customer %q", l, tt.wantCustomer)
+			}
+		})
+	}
+
+	if _, ok := store.Get("other"); ok {
+		t.Error("Update renamed the license")
+	}
+	if _, err := store.Update("missing", func(l *License) error { return nil }); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update(missing) error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := store.Update("acme", func(l *License) error { return changeFailed }); !errors.Is(err, changeFailed) {
+		t.Errorf("Update error = %v, want the error of the change", err)
+	}
+}
+
+func TestUpdateOrBlock(t *testing.T) {
+	store := newTestStore(t)
+	issue(t, store, &License{ID: "acme", Customer: "ACME Corp"})
+
+	revoke := func(l *License) error {
+		l.Revoked = true
+		return nil
+	}
+
+	// An existing license is updated in place
+	l, err := store.UpdateOrBlock("acme", revoke)
+	if err != nil || !l.Revoked || l.Customer != "ACME Corp" {
+		t.Fatalf("UpdateOrBlock(acme) = %+v, %v, want acme revoked", l, err)
+	}
+
+	// A signed license without a registry entry gets one holding its state
+	l, err = store.UpdateOrBlock("offline", revoke)
+	if err != nil {
+		t.Fatalf("UpdateOrBlock(offline) error = %v", err)
+	}
+	if !l.Revoked || l.ID != "offline" || l.TokenHash == "" || l.CreatedAt.IsZero() {
+		t.Errorf("UpdateOrBlock(offline) = %+v, want a revoked entry", l)
+	}
+
+	reloaded, err := LoadStore(store.path)
+	if err != nil {
+		t.Fatalf("LoadStore error = %v", err)
+	}
+	if l, ok := reloaded.Get("offline"); !ok || !l.Revoked {
+		t.Errorf("reloaded offline = %+v, %v, want it revoked", l, ok)
+	}
+
+	// A failed change leaves no entry behind
+	if _, err := store.UpdateOrBlock("other", func(l *License) error { return ErrRevoked }); !errors.Is(err, ErrRevoked) {
+		t.Errorf("UpdateOrBlock error = %v, want %v", err, ErrRevoked)
+	}
+	if _, ok := store.Get("other"); ok {
+		t.Error("failed UpdateOrBlock left an entry")
+	}
+}
+
+func TestBuiltinLicensesCantChange(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.AddBuiltin(&License{ID: "default", TokenHash: HashToken("legacy")}); err != nil {
+		t.Fatalf("AddBuiltin error = %v", err)
+	}
+	issue(t, store, &License{ID: "acme"})
+
+	noop := func(l *License) error { return nil }
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"Update", func() error { _, err := store.Update("default", noop); return err }},
+		{"UpdateOrBlock", func() error { _, err := store.UpdateOrBlock("default", noop); return err }},
+		{"Rotate", func() error { _, _, err := store.Rotate("default"); return err }},
+	}
+	for _, tt := range tests {
+		if err := tt.call(); !errors.Is(err, ErrBuiltin) {
+			t.Errorf("%s error = %v, want %v", tt.name, err, ErrBuiltin)
+		}
+	}
+
+	if _, _, err := store.Issue(&License{ID: "default"}); !errors.Is(err, ErrExists) {
+		t.Errorf("Issue over a built-in license error = %v, want %v", err, ErrExists)
+	}
+	if !store.IsBuiltin("default") || store.IsBuiltin("acme") {
+		t.Error("IsBuiltin does not tell built-in licenses apart")
+	}
+	if l, err := store.Authenticate("legacy"); err != nil || l.ID != "default" {
+		t.Errorf("Authenticate(legacy) = %v, %v, want default", l, err)
+	}
+
+	// Built-in licenses are left out of the registry file
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := LoadStore(store.path)
+	if err != nil {
+		t.Fatalf("LoadStore error = %v", err)
+	}
+	if _, ok := reloaded.Get("default"); ok {
+		t.Errorf("registry file holds the built-in license: %s", data)
+	}
+}
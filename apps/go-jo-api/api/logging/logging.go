@@ -0,0 +1,137 @@
+// Package logging provides structured (JSON) logging for go-jo-api: an
+// access-log middleware that assigns request IDs and a request scoped logger
+// for handlers.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// RequestIDHeader carries the request ID, both from clients and in responses
+const RequestIDHeader = "X-Request-ID"
+
+// RequestInfo describes the request being served. Handlers fill in what they
+// learn (e.g. the license) so it appears in the access log.
+type RequestInfo struct {
+	ID        string
+	Route     string
+	LicenseID string
+	// Error explains a response that failed after its status was sent
+	Error string
+}
+
+type contextKey struct{}
+
+// Setup makes JSON on stdout the default log output, also for the standard log package
+func Setup() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+	log.SetFlags(0)
+}
+
+// NewContext returns a copy of ctx carrying the request info
+func NewContext(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// FromContext returns the request info stored in ctx, if any
+func FromContext(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(contextKey{}).(*RequestInfo)
+	return info
+}
+
+// SetLicenseID records the license making the request
+func SetLicenseID(ctx context.Context, licenseID string) {
+	if info := FromContext(ctx); info != nil {
+		info.LicenseID = licenseID
+	}
+}
+
+// SetError records why a response failed after its status was sent, for
+// the access log
+func SetError(ctx context.Context, message string) {
+	if info := FromContext(ctx); info != nil {
+		info.Error = message
+	}
+}
+
+// Logger returns the default logger annotated with the request ID and license of ctx
+func Logger(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if info := FromContext(ctx); info != nil {
+		logger = logger.With("request_id", info.ID)
+		if info.LicenseID != "" {
+			logger = logger.With("license_id", info.LicenseID)
+		}
+	}
+	return logger
+}
+
+// Middleware assigns or propagates the X-Request-ID of every request and
+// writes one access-log line once the response is done, also when the
+// handler aborts it with a panic. route returns the route template the
+// request matched, or "" if none did.
+func Middleware(next http.Handler, route func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		info := &RequestInfo{ID: requestID(r.Header.Get(RequestIDHeader)), Route: route(r)}
+		w.Header().Set(RequestIDHeader, info.ID)
+
+		recorder := WrapResponseWriter(w)
+		defer func() {
+			attrs := []slog.Attr{
+				slog.String("request_id", info.ID),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", info.Route),
+				slog.Int("status", recorder.Status()),
+				slog.Int64("bytes", recorder.Bytes()),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("license_id", info.LicenseID),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("user_agent", r.UserAgent()),
+			}
+			if info.Error != "" {
+				attrs = append(attrs, slog.String("error", info.Error))
+			}
+			slog.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
+		}()
+
+		next.ServeHTTP(recorder, r.WithContext(NewContext(r.Context(), info)))
+	})
+}
+
+// requestID returns the client supplied ID when it is reasonable, or a new random one
+func requestID(supplied string) string {
+	if isValidRequestID(supplied) {
+		return supplied
+	}
+
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// isValidRequestID accepts short IDs made of URL safe characters, so client
+// input can't forge log content
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsValidRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"", false},
+		{"abc123", true},
+		{"0f8fad5b-d9cb-469f-a165-70867728950e", true},
+		{"trace_1.2:3", true},
+		{strings.Repeat("a", 128), true},
+		{strings.Repeat("a", 129), false},
+		{"with space", false},
+		{"line\nbreak", false},
+		{`quote"`, false},
+		{"slash/", false},
+		{"ünïcode", false},
+	}
+
+	for _, tt := range tests {
+		if got := isValidRequestID(tt.id); got != tt.want {
+			t.Errorf("isValidRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+// captureLog sends the default logger to a buffer for the rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetLicenseID(r.Context(), "acme")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	}), func(r *http.Request) string { return "/v1/teapot" })
+
+	tests := []struct {
+		name     string
+		supplied string
+		echoed   bool
+	}{
+		{"valid ID", "client-id.42", true},
+		{"no ID", "", false},
+		{"invalid ID", "forged\n{\"level\":\"ERROR\"}", false},
+		{"too long ID", strings.Repeat("a", 129), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLog(t)
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/teapot?q=1", nil)
+			if tt.supplied != "" {
+				r.Header.Set(RequestIDHeader, tt.supplied)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			id := w.Header().Get(RequestIDHeader)
+			if tt.echoed && id != tt.supplied {
+				t.Errorf("%s = %q, want the supplied %q", RequestIDHeader, id, tt.supplied)
+			}
+			if !tt.echoed && (id == tt.supplied || len(id) != 32 || !isValidRequestID(id)) {
+				t.Errorf("%s = %q, want a new random ID", RequestIDHeader, id)
+			}
+
+			var line struct {
+				Msg       string `json:"msg"`
+				RequestID string `json:"request_id"`
+				Method    string `json:"method"`
+				Path      string `json:"path"`
+				Route     string `json:"route"`
+				Status    int    `json:"status"`
+				Bytes     int64  `json:"bytes"`
+				LicenseID string `json:"license_id"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatalf("access line %q is not one JSON object: %v", buf, err)
+			}
+			if line.Msg != "request" || line.RequestID != id || line.Method != http.MethodGet || line.Path != "/v1/teapot" ||
+				line.Route != "/v1/teapot" || line.Status != http.StatusTeapot || line.Bytes != int64(len("short and stout")) ||
+				line.LicenseID != "acme" {
+				t.Errorf("access line = %+v", line)
+			}
+		})
+	}
+}
+
+func TestLoggerFields(t *testing.T) {
+	buf := captureLog(t)
+
+	ctx := NewContext(t.Context(), &RequestInfo{ID: "req-1"})
+	Logger(ctx).Info("before")
+	SetLicenseID(ctx, "acme")
+	Logger(ctx).Info("after")
+	Logger(t.Context()).Info("outside")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`"request_id":"req-1"}`,
+		`"request_id":"req-1","license_id":"acme"}`,
+		`"msg":"outside"}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("log lines = %q", lines)
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Errorf("line %d = %s, want it to end with %s", i, lines[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"io"
+	"net/http"
+)
+
+// ResponseRecorder records the status and size of a response while passing
+// it through to the client
+type ResponseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WrapResponseWriter returns a recorder for w, reusing w if it already is one
+func WrapResponseWriter(w http.ResponseWriter) *ResponseRecorder {
+	if recorder, ok := w.(*ResponseRecorder); ok {
+		return recorder
+	}
+	return &ResponseRecorder{ResponseWriter: w}
+}
+
+// Status returns the response status, 200 if the handler wrote nothing explicit
+func (r *ResponseRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// Bytes returns the number of body bytes written
+func (r *ResponseRecorder) Bytes() int64 {
+	return r.bytes
+}
+
+// WriteHeader records the status and sends the header
+func (r *ResponseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write counts the bytes written
+func (r *ResponseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom keeps the sendfile optimisation of the underlying writer for
+// http.ServeContent while counting the bytes copied
+func (r *ResponseRecorder) ReadFrom(src io.Reader) (int64, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := io.Copy(r.ResponseWriter, src)
+	r.bytes += n
+	return n, err
+}
+
+// Flush sends buffered data to the client
+func (r *ResponseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (r *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	w := httptest.NewRecorder()
+	recorder := WrapResponseWriter(w)
+	if WrapResponseWriter(recorder) != recorder {
+		t.Error("WrapResponseWriter wrapped a recorder again")
+	}
+	if recorder.Status() != http.StatusOK {
+		t.Errorf("status before writing = %d, want 200", recorder.Status())
+	}
+
+	recorder.WriteHeader(http.StatusPartialContent)
+	recorder.WriteHeader(http.StatusInternalServerError)
+	recorder.Write([]byte("abc"))
+	n, err := recorder.ReadFrom(strings.NewReader("defgh"))
+	if err != nil || n != 5 {
+		t.Fatalf("ReadFrom = %d, %v", n, err)
+	}
+
+	if recorder.Status() != http.StatusPartialContent || recorder.Bytes() != 8 {
+		t.Errorf("status, bytes = %d, %d, want 206, 8", recorder.Status(), recorder.Bytes())
+	}
+	if w.Body.String() != "abcdefgh" {
+		t.Errorf("body = %q", w.Body)
+	}
+
+	// The write before any explicit status is a 200
+	implicit := WrapResponseWriter(httptest.NewRecorder())
+	implicit.ReadFrom(strings.NewReader("x"))
+	if implicit.Status() != http.StatusOK {
+		t.Errorf("status after ReadFrom = %d, want 200", implicit.Status())
+	}
+}
+
+func TestResponseRecorderController(t *testing.T) {
+	w := httptest.NewRecorder()
+	recorder := WrapResponseWriter(w)
+
+	controller := http.NewResponseController(recorder)
+	if err := controller.Flush(); err != nil || !w.Flushed {
+		t.Errorf("Flush error = %v, flushed %v", err, w.Flushed)
+	}
+
+	// Controls the recorder doesn't implement reach the server's writer
+	// through Unwrap
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := WrapResponseWriter(w)
+		if err := http.NewResponseController(recorder).SetWriteDeadline(time.Now().Add(time.Minute)); err != nil {
+			t.Errorf("SetWriteDeadline error = %v", err)
+		}
+		io.Copy(recorder, strings.NewReader("streamed"))
+		http.NewResponseController(recorder).Flush()
+		if recorder.Bytes() != int64(len("streamed")) {
+			t.Errorf("bytes = %d", recorder.Bytes())
+		}
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "streamed" {
+		t.Errorf("body = %q", body)
+	}
+}
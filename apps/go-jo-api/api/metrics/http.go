@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+)
+
+// Middleware counts requests and measures their latency per route template.
+// It expects to run inside logging.Middleware, which provides the route.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		recorder := logging.WrapResponseWriter(w)
+		next.ServeHTTP(recorder, r)
+
+		// Unmatched paths share one label so scanners can't blow up the series count
+		route := "unmatched"
+		if info := logging.FromContext(r.Context()); info != nil && info.Route != "" {
+			route = info.Route
+		}
+
+		HTTPRequests.Inc(route, r.Method, strconv.Itoa(recorder.Status()))
+		HTTPRequestDuration.Observe(time.Since(start).Seconds(), route, r.Method)
+	})
+}
+
+// transport instruments the requests made to an upstream
+type transport struct {
+	upstream string
+	next     http.RoundTripper
+}
+
+// NewTransport wraps next (http.DefaultTransport when nil) so every request
+// is counted and timed under the given upstream name
+func NewTransport(upstream string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{upstream: upstream, next: next}
+}
+
+// RoundTrip performs the request and records its outcome
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	UpstreamDuration.Observe(time.Since(start).Seconds(), t.upstream)
+
+	if err != nil {
+		UpstreamRequests.Inc(t.upstream, "error")
+		UpstreamErrors.Inc(t.upstream)
+		return nil, err
+	}
+
+	UpstreamRequests.Inc(t.upstream, strconv.Itoa(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		UpstreamErrors.Inc(t.upstream)
+	}
+	return resp, nil
+}
@@ -0,0 +1,46 @@
+package metrics
+
+// Metrics exported by go-jo-api
+var (
+	HTTPRequests = NewCounter("gojo_http_requests_total",
+		"HTTP requests served, by route template, method and status code.",
+		"route", "method", "status")
+	HTTPRequestDuration = NewHistogram("gojo_http_request_duration_seconds",
+		"Time spent serving HTTP requests, by route template and method.",
+		DefaultBuckets, "route", "method")
+
+	DownloadBytes = NewCounter("gojo_download_bytes_total",
+		"Bytes of download packages sent to clients, by version and integration.",
+		"version", "integration")
+	DownloadDuration = NewHistogram("gojo_download_duration_seconds",
+		"Time spent serving download packages, by version and integration.",
+		DownloadBuckets, "version", "integration")
+	DownloadsInFlight = NewGauge("gojo_downloads_in_flight",
+		"Download requests currently being served.")
+
+	UpstreamRequests = NewCounter("gojo_upstream_requests_total",
+		"Requests made to the artifact source, by upstream and status code (\"error\" when no response was received).",
+		"upstream", "code")
+	UpstreamErrors = NewCounter("gojo_upstream_errors_total",
+		"Failed requests to the artifact source (transport errors and 4xx/5xx responses), by upstream.",
+		"upstream")
+	UpstreamDuration = NewHistogram("gojo_upstream_request_duration_seconds",
+		"Time until the artifact source answered with response headers, by upstream.",
+		DefaultBuckets, "upstream")
+
+	CacheHits = NewCounter("gojo_cache_hits_total",
+		"Downloads served from the package cache.")
+	CacheMisses = NewCounter("gojo_cache_misses_total",
+		"Downloads that had to assemble the package.")
+
+	RateLimited = NewCounter("gojo_rate_limited_total",
+		"Requests refused with 429, by exhausted budget (list, download or concurrent_downloads).",
+		"budget")
+
+	BuildInfo = NewGauge("gojo_build_info",
+		"Build information of the running go-jo-api, always 1.",
+		"version", "commit")
+)
+
+// DownloadBuckets suit package downloads, which take seconds to minutes
+var DownloadBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"bytes"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares the text written by a collector with testdata/<name>.golden
+func checkGolden(t *testing.T, name string, c collector) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := c.write(&buf); err != nil {
+		t.Fatalf("write error = %v", err)
+	}
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v (run with -update to create it)", err)
+	}
+	if got := buf.String(); got != string(want) {
+		t.Errorf("%s output differs from %s\ngot:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
+
+// The families below are built without NewX so they stay out of the registry
+
+func TestCounterFormat(t *testing.T) {
+	c := &Counter{family: family{metricName: "test_requests_total", help: "Requests served.", labels: []string{"route", "status"}}, series: make(map[string]float64)}
+	c.Inc("/v1/versions", "200")
+	c.Inc("/v1/versions", "200")
+	c.Inc("/v1/download/{app_version}/{integration}", "404")
+	c.Add(0.5, "/v1/versions", "500")
+	c.Add(-3, "/v1/versions", "500")
+
+	checkGolden(t, "counter", c)
+}
+
+func TestUnlabelledCounterFormat(t *testing.T) {
+	c := &Counter{family: family{metricName: "test_hits_total", help: "Cache hits."}, series: make(map[string]float64)}
+	checkGolden(t, "counter_empty", c)
+
+	c.Add(1e6)
+	c.Inc()
+	checkGolden(t, "counter_unlabelled", c)
+}
+
+func TestGaugeFormat(t *testing.T) {
+	g := &Gauge{family: family{metricName: "test_in_flight", help: "Requests in flight.", labels: []string{"kind"}}, series: make(map[string]float64)}
+	g.Inc("download")
+	g.Inc("download")
+	g.Dec("download")
+	g.Dec("list")
+	g.Set(math.Inf(1), "unbounded")
+	g.Set(math.NaN(), "unknown")
+	g.Set(0.000125, "small")
+
+	checkGolden(t, "gauge", g)
+}
+
+func TestGaugeFuncFormat(t *testing.T) {
+	size := 1536.0
+	g := &GaugeFunc{family: family{metricName: "test_cache_size_bytes", help: "Disk space used."}, value: func() float64 { return size }}
+	checkGolden(t, "gauge_func", g)
+}
+
+func TestHistogramFormat(t *testing.T) {
+	h := &Histogram{
+		family:  family{metricName: "test_duration_seconds", help: "Time spent.", labels: []string{"route"}},
+		buckets: []float64{0.1, 0.5, 1, 2.5},
+		series:  make(map[string]*histogramSeries),
+	}
+	// Values equal to a bound fall in that bucket
+	for _, v := range []float64{0.05, 0.1, 0.3, 1, 7} {
+		h.Observe(v, "/v1/download")
+	}
+	h.Observe(0.2, "/v1/versions")
+
+	checkGolden(t, "histogram", h)
+}
+
+func TestEscaping(t *testing.T) {
+	c := &Counter{family: family{metricName: "test_escaped_total", help: "Help with a \\ backslash\nand a newline \"quoted\".", labels: []string{"value"}}, series: make(map[string]float64)}
+	c.Inc(`quote " backslash \ newline` + "\n")
+	c.Inc("invalid \xff utf-8")
+
+	checkGolden(t, "escaping", c)
+}
+
+func TestLabelCountMismatch(t *testing.T) {
+	c := &Counter{family: family{metricName: "test_labels_total", labels: []string{"a", "b"}}, series: make(map[string]float64)}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc with a missing label value did not panic")
+		}
+	}()
+	c.Inc("only-a")
+}
+
+func TestRegistry(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf); err != nil {
+		t.Fatalf("Write error = %v", err)
+	}
+
+	// Families are written sorted by name, each with its HELP and TYPE lines
+	var names []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) == 4 && fields[1] == "TYPE" {
+			names = append(names, fields[2])
+		}
+	}
+	if len(names) != len(registry) {
+		t.Fatalf("Write listed %d families, want %d", len(names), len(registry))
+	}
+	for i := 1; i < len(names); i++ {
+		if names[i-1] >= names[i] {
+			t.Errorf("families not sorted: %s before %s", names[i-1], names[i])
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate metric did not panic")
+		}
+	}()
+	NewCounter("gojo_http_requests_total", "Duplicate.")
+}
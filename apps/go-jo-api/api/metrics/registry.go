@@ -0,0 +1,145 @@
+// Package metrics implements the small subset of Prometheus instrumentation
+// go-jo-api needs (counters, gauges and histograms with labels) and writes
+// them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// collector is a metric family that can write itself in text format
+type collector interface {
+	name() string
+	write(w io.Writer) error
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+// register adds a metric family to the registry
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, existing := range registry {
+		if existing.name() == c.name() {
+			panic("metrics: duplicate metric " + c.name())
+		}
+	}
+	registry = append(registry, c)
+}
+
+// Write writes every registered metric in the Prometheus text format
+func Write(w io.Writer) error {
+	registryMu.Lock()
+	collectors := append([]collector(nil), registry...)
+	registryMu.Unlock()
+
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+	for _, c := range collectors {
+		if err := c.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ContentType is the media type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// family holds what every metric type shares: its name, help and label names
+type family struct {
+	metricName string
+	help       string
+	labels     []string
+}
+
+func (f *family) name() string {
+	return f.metricName
+}
+
+// writeHeader writes the HELP and TYPE lines of the family
+func (f *family) writeHeader(w io.Writer, kind string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.metricName, escapeHelp(f.help), f.metricName, kind)
+	return err
+}
+
+// key joins label values into a map key, checking their number. Values are
+// made valid UTF-8 first, which never contains the \xff separator.
+func (f *family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.metricName, len(f.labels), len(values)))
+	}
+
+	valid := make([]string, len(values))
+	for i, value := range values {
+		valid[i] = strings.ToValidUTF8(value, "\uFFFD")
+	}
+	return strings.Join(valid, "\xff")
+}
+
+// labelPairs formats label names and values, plus extra pairs, as {a="x",b="y"}
+func (f *family) labelPairs(values []string, extra ...string) string {
+	var pairs []string
+	for i, label := range f.labels {
+		pairs = append(pairs, label+`="`+escapeLabel(values[i])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sortedKeys returns the keys of a series map in a stable order
+func sortedKeys[T any](series map[string]T) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitKey turns a series key back into its label values
+func splitKey(key string, count int) []string {
+	if count == 0 {
+		return nil
+	}
+	return strings.Split(key, "\xff")
+}
+
+// formatFloat formats a sample value the way Prometheus expects
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeLabel escapes backslashes, quotes and newlines in a label value
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeHelp escapes backslashes and newlines in help text
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+)
+
+// Counter is a monotonically increasing value per label combination
+type Counter struct {
+	family
+	mu     sync.Mutex
+	series map[string]float64
+}
+
+// NewCounter creates and registers a counter
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{family: family{metricName: name, help: help, labels: labels}, series: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+// Inc adds one to the counter of the given label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds a non-negative value to the counter of the given label values
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	c.series[key] += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.writeHeader(w, "counter"); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(c.series) {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", c.metricName, c.labelPairs(splitKey(key, len(c.labels))), formatFloat(c.series[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Gauge is a value that can go up and down per label combination
+type Gauge struct {
+	family
+	mu     sync.Mutex
+	series map[string]float64
+}
+
+// NewGauge creates and registers a gauge
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{family: family{metricName: name, help: help, labels: labels}, series: make(map[string]float64)}
+	register(g)
+	return g
+}
+
+// Set sets the gauge of the given label values
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	key := g.key(labelValues)
+
+	g.mu.Lock()
+	g.series[key] = v
+	g.mu.Unlock()
+}
+
+// Add adds a value, possibly negative, to the gauge of the given label values
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	key := g.key(labelValues)
+
+	g.mu.Lock()
+	g.series[key] += v
+	g.mu.Unlock()
+}
+
+// Inc adds one to the gauge of the given label values
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec subtracts one from the gauge of the given label values
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+func (g *Gauge) write(w io.Writer) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.writeHeader(w, "gauge"); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(g.series) {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", g.metricName, g.labelPairs(splitKey(key, len(g.labels))), formatFloat(g.series[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GaugeFunc is an unlabelled gauge whose value is read when metrics are scraped
+type GaugeFunc struct {
+	family
+	value func() float64
+}
+
+// NewGaugeFunc creates and registers a gauge reading its value from fn
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{family: family{metricName: name, help: help}, value: fn}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) error {
+	if err := g.writeHeader(w, "gauge"); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s %s\n", g.metricName, formatFloat(g.value()))
+	return err
+}
+
+// Histogram counts observations in cumulative buckets per label combination
+type Histogram struct {
+	family
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// DefaultBuckets suit request latencies in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// NewHistogram creates and registers a histogram with the given upper bounds,
+// in increasing order
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		family:  family{metricName: name, help: help, labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	register(h)
+	return h
+}
+
+// Observe records a value for the given label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.writeHeader(w, "histogram"); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		values := splitKey(key, len(h.labels))
+
+		for i, bound := range h.buckets {
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.labelPairs(values, "le", formatFloat(bound)), s.counts[i]); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.labelPairs(values, "le", formatFloat(math.Inf(1))), s.count); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n", h.metricName, h.labelPairs(values), formatFloat(s.sum), h.metricName, h.labelPairs(values), s.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
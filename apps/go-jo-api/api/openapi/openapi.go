@@ -0,0 +1,37 @@
+// Package openapi embeds the hand-maintained OpenAPI 3 document of go-jo-api
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns the OpenAPI document as JSON
+func Spec() []byte {
+	return spec
+}
+
+// Operations returns the documented operations as "METHOD /path" keys
+func Operations() (map[string]bool, error) {
+	var document struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &document); err != nil {
+		return nil, err
+	}
+
+	operations := make(map[string]bool)
+	for path, item := range document.Paths {
+		for method := range item {
+			switch method {
+			case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+				operations[strings.ToUpper(method)+" "+path] = true
+			}
+		}
+	}
+	return operations, nil
+}
@@ -0,0 +1,118 @@
+// Package ratelimit implements per-key token buckets and concurrency caps,
+// used to keep a single license from exhausting the API or its upstream quota.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Rate is a token bucket budget: PerMinute tokens are added every minute up
+// to Burst tokens. A zero PerMinute disables the limit.
+type Rate struct {
+	PerMinute float64
+	Burst     int
+}
+
+// Unlimited reports whether the rate imposes no limit
+func (r Rate) Unlimited() bool {
+	return r.PerMinute <= 0
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps one token bucket per key
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewLimiter creates an empty limiter
+func NewLimiter() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of key. When the bucket is empty it
+// returns false and how long until a token is available.
+func (l *Limiter) Allow(key string, rate Rate) (bool, time.Duration) {
+	if rate.Unlimited() {
+		return true, 0
+	}
+
+	burst := float64(rate.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	perSecond := rate.PerMinute / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+
+	// Refill for the time elapsed since the last request
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	return false, wait
+}
+
+// Concurrency caps the number of simultaneous operations per key
+type Concurrency struct {
+	mu     sync.Mutex
+	active map[string]int
+}
+
+// NewConcurrency creates an empty concurrency cap
+func NewConcurrency() *Concurrency {
+	return &Concurrency{active: make(map[string]int)}
+}
+
+// Acquire starts an operation for key unless max are already running. The
+// returned release func must be called when the operation is done. A max of
+// zero or less disables the cap.
+func (c *Concurrency) Acquire(key string, max int) (release func(), ok bool) {
+	if max <= 0 {
+		return func() {}, true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.active[key] >= max {
+		return nil, false
+	}
+	c.active[key]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			c.active[key]--
+			if c.active[key] <= 0 {
+				delete(c.active, key)
+			}
+		})
+	}, true
+}
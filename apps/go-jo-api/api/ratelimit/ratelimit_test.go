@@ -0,0 +1,184 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable time source for the limiter
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestLimiter() (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewLimiter()
+	l.now = clock.Now
+	return l, clock
+}
+
+func TestLimiterAllow(t *testing.T) {
+	type step struct {
+		advance  time.Duration
+		wantOK   bool
+		wantWait time.Duration
+	}
+
+	tests := []struct {
+		name  string
+		rate  Rate
+		steps []step
+	}{
+		{
+			name: "burst then refill",
+			rate: Rate{PerMinute: 60, Burst: 3},
+			steps: []step{
+				{0, true, 0},
+				{0, true, 0},
+				{0, true, 0},
+				{0, false, time.Second},
+				{500 * time.Millisecond, false, 500 * time.Millisecond},
+				{500 * time.Millisecond, true, 0},
+				{0, false, time.Second},
+			},
+		},
+		{
+			name: "refill is capped at the burst",
+			rate: Rate{PerMinute: 60, Burst: 2},
+			steps: []step{
+				{0, true, 0},
+				{0, true, 0},
+				{time.Hour, true, 0},
+				{0, true, 0},
+				{0, false, time.Second},
+			},
+		},
+		{
+			name: "burst below one allows one request",
+			rate: Rate{PerMinute: 6, Burst: 0},
+			steps: []step{
+				{0, true, 0},
+				{0, false, 10 * time.Second},
+				{10 * time.Second, true, 0},
+			},
+		},
+		{
+			name: "slow rate",
+			rate: Rate{PerMinute: 0.5, Burst: 1},
+			steps: []step{
+				{0, true, 0},
+				{time.Minute, false, time.Minute},
+				{time.Minute, true, 0},
+			},
+		},
+		{
+			name: "unlimited",
+			rate: Rate{PerMinute: 0, Burst: 1},
+			steps: []step{
+				{0, true, 0},
+				{0, true, 0},
+				{0, true, 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter()
+			for i, step := range tt.steps {
+				clock.now = clock.now.Add(step.advance)
+				ok, wait := l.Allow("license", tt.rate)
+				if ok != step.wantOK || wait != step.wantWait {
+					t.Errorf("step %d: Allow = %v, %s, want %v, %s", i, ok, wait, step.wantOK, step.wantWait)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterKeysAreIndependent(t *testing.T) {
+	l, _ := newTestLimiter()
+	rate := Rate{PerMinute: 1, Burst: 1}
+
+	if ok, _ := l.Allow("a", rate); !ok {
+		t.Fatal("first request of a refused")
+	}
+	if ok, _ := l.Allow("a", rate); ok {
+		t.Error("second request of a allowed")
+	}
+	if ok, _ := l.Allow("b", rate); !ok {
+		t.Error("first request of b refused after a exhausted its bucket")
+	}
+}
+
+func TestLimiterConcurrentUse(t *testing.T) {
+	l, _ := newTestLimiter()
+	rate := Rate{PerMinute: 1, Burst: 50}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := l.Allow("license", rate); ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 50 {
+		t.Errorf("%d requests allowed, want the burst of 50", allowed)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	c := NewConcurrency()
+
+	first, ok := c.Acquire("license", 2)
+	if !ok {
+		t.Fatal("first Acquire refused")
+	}
+	second, ok := c.Acquire("license", 2)
+	if !ok {
+		t.Fatal("second Acquire refused")
+	}
+	if _, ok := c.Acquire("license", 2); ok {
+		t.Error("third Acquire allowed over the cap of 2")
+	}
+	if release, ok := c.Acquire("other", 2); !ok {
+		t.Error("Acquire of another key refused")
+	} else {
+		release()
+	}
+
+	// Releasing twice frees a single slot
+	first()
+	first()
+	third, ok := c.Acquire("license", 2)
+	if !ok {
+		t.Fatal("Acquire refused after a release")
+	}
+	if _, ok := c.Acquire("license", 2); ok {
+		t.Error("Acquire allowed over the cap after a double release")
+	}
+
+	second()
+	third()
+	if len(c.active) != 0 {
+		t.Errorf("active = %v, want released keys removed", c.active)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, ok := c.Acquire("license", 0); !ok {
+			t.Error("Acquire refused with the cap disabled")
+		}
+	}
+}
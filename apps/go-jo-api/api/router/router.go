@@ -1,10 +1,17 @@
 package router
 
 import (
+	"fmt"
 	"log"
+	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/gorilla/mux"
-	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/handlers"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/logging"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/metrics"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/openapi"
 )
 
 // Router manages the main application router
@@ -13,10 +20,10 @@ type Router struct {
 	subrouterBuilder *SubrouterBuilder
 }
 
-// New creates a new router instance
-func New(config *domain.Config) *Router {
+// New creates a new router instance for handlers sharing the given base dependencies
+func New(base *handlers.BaseHandler) *Router {
 	router := mux.NewRouter()
-	subrouterBuilder := NewSubrouterBuilder(config)
+	subrouterBuilder := NewSubrouterBuilder(base)
 
 	r := &Router{
 		router:           router,
@@ -27,22 +34,130 @@ func New(config *domain.Config) *Router {
 	return r
 }
 
+// APIPrefix is the path prefix of the current API version
+const APIPrefix = "/v1"
+
 // setupRoutes configures all application routes
 func (r *Router) setupRoutes() {
 	log.Println("Setting up API routes...")
 
-	// Build all subrouters
-	r.subrouterBuilder.BuildVersionsSubrouter(r.router)
-	r.subrouterBuilder.BuildIntegrationsSubrouter(r.router)
-	r.subrouterBuilder.BuildDownloadSubrouter(r.router)
-	r.subrouterBuilder.BuildHealthSubrouter(r.router)
+	// Current API version
+	v1Router := r.router.PathPrefix(APIPrefix).Subrouter()
+	r.buildSubrouters(v1Router)
+	r.subrouterBuilder.BuildOpenAPISubrouter(v1Router)
+	r.subrouterBuilder.BuildAdminSubrouter(v1Router)
+	r.subrouterBuilder.BuildWebhooksSubrouter(v1Router)
+
+	// Unversioned paths of earlier releases, kept as deprecated aliases
+	legacyRouter := r.router.NewRoute().Subrouter()
+	legacyRouter.Use(deprecatedAlias)
+	r.buildSubrouters(legacyRouter)
 
 	log.Println("API routes configured successfully")
 }
 
-// GetRouter returns the configured mux router
-func (r *Router) GetRouter() *mux.Router {
-	return r.router
+// buildSubrouters registers the routes shared by /v1 and the deprecated aliases
+func (r *Router) buildSubrouters(router *mux.Router) {
+	r.subrouterBuilder.BuildVersionsSubrouter(router)
+	r.subrouterBuilder.BuildIntegrationsSubrouter(router)
+	r.subrouterBuilder.BuildDownloadSubrouter(router)
+	r.subrouterBuilder.BuildHealthSubrouter(router)
+	if r.subrouterBuilder.config.Metrics.Enabled {
+		r.subrouterBuilder.BuildMetricsSubrouter(router)
+	}
+}
+
+// deprecatedAlias marks responses of unversioned paths as deprecated and
+// points clients to the /v1 route
+func deprecatedAlias(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", APIPrefix, req.URL.EscapedPath()))
+		next.ServeHTTP(w, req)
+	})
+}
+
+// VerifyOpenAPI checks that every registered route is documented in the
+// OpenAPI document, and that the document has no route the router lacks.
+// Deprecated aliases are checked through their /v1 route.
+func (r *Router) VerifyOpenAPI() error {
+	documented, err := openapi.Operations()
+	if err != nil {
+		return fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	registered := make(map[string]bool)
+	missing := make(map[string]bool)
+	r.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		pathTemplate, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		if !strings.HasPrefix(pathTemplate, APIPrefix+"/") {
+			pathTemplate = APIPrefix + pathTemplate
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			missing[pathTemplate+" (no methods)"] = true
+			return nil
+		}
+		for _, method := range methods {
+			operation := method + " " + pathTemplate
+			registered[operation] = true
+			if !documented[operation] {
+				missing[operation] = true
+			}
+		}
+		return nil
+	})
+
+	var undocumented []string
+	for operation := range missing {
+		undocumented = append(undocumented, operation)
+	}
+
+	var unregistered []string
+	for operation := range documented {
+		if !registered[operation] && !r.optionalOperation(operation) {
+			unregistered = append(unregistered, operation)
+		}
+	}
+
+	if len(undocumented) > 0 || len(unregistered) > 0 {
+		sort.Strings(undocumented)
+		sort.Strings(unregistered)
+		return fmt.Errorf("routes missing from the OpenAPI document: %v; documented routes not registered: %v", undocumented, unregistered)
+	}
+	return nil
+}
+
+// optionalOperation reports documented operations that are only registered
+// when enabled in the configuration
+func (r *Router) optionalOperation(operation string) bool {
+	return operation == "GET "+APIPrefix+"/metrics" && !r.subrouterBuilder.config.Metrics.Enabled
+}
+
+// Handler returns the router wrapped with the access log and metrics middlewares
+func (r *Router) Handler() http.Handler {
+	return logging.Middleware(metrics.Middleware(r.router), r.routeTemplate)
+}
+
+// routeTemplate returns the path template of the route matching the request
+func (r *Router) routeTemplate(req *http.Request) string {
+	var match mux.RouteMatch
+	if !r.router.Match(req, &match) || match.Route == nil {
+		return ""
+	}
+
+	template, err := match.Route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return template
 }
 
 // GetSubrouterBuilder returns the subrouter builder for accessing handlers
@@ -0,0 +1,74 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/audit"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/cache"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/handlers"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/license"
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/source"
+)
+
+// newTestRouter builds the router on an empty local source
+func newTestRouter(t *testing.T, metricsEnabled bool) *Router {
+	t.Helper()
+
+	dir := t.TempDir()
+	src, err := source.NewLocalSource(domain.LocalSourceConfig{ReleasesDir: dir, IntegrationsDir: dir})
+	if err != nil {
+		t.Fatalf("NewLocalSource error = %v", err)
+	}
+	packageCache, err := cache.New(filepath.Join(dir, "cache"), 1024*1024)
+	if err != nil {
+		t.Fatalf("cache.New error = %v", err)
+	}
+	auditLog, err := audit.Open("")
+	if err != nil {
+		t.Fatalf("audit.Open error = %v", err)
+	}
+
+	config := &domain.Config{Metrics: domain.MetricsConfig{Enabled: metricsEnabled}}
+	return New(handlers.NewBaseHandler(config, license.NewStore(), packageCache, src, auditLog))
+}
+
+// Every route must be documented in the OpenAPI document, and every
+// documented route registered
+func TestVerifyOpenAPI(t *testing.T) {
+	for _, metricsEnabled := range []bool{true, false} {
+		if err := newTestRouter(t, metricsEnabled).VerifyOpenAPI(); err != nil {
+			t.Errorf("VerifyOpenAPI with metrics enabled %v: %v", metricsEnabled, err)
+		}
+	}
+}
+
+func TestDeprecatedAliases(t *testing.T) {
+	handler := newTestRouter(t, false).Handler()
+
+	tests := []struct {
+		path     string
+		wantLink string
+	}{
+		{"/v1/health", ""},
+		{"/health", `</v1/health>; rel="successor-version"`},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s status = %d, want 200", tt.path, rec.Code)
+		}
+		if deprecated, want := rec.Header().Get("Deprecation") == "true", tt.wantLink != ""; deprecated != want {
+			t.Errorf("GET %s deprecated = %v, want %v", tt.path, deprecated, want)
+		}
+		if link := rec.Header().Get("Link"); link != tt.wantLink {
+			t.Errorf("GET %s Link = %q, want %q", tt.path, link, tt.wantLink)
+		}
+	}
+}
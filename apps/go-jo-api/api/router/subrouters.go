@@ -1,6 +1,8 @@
 package router
 
 import (
+	"net/http"
+
 	"github.com/gorilla/mux"
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/handlers"
@@ -13,22 +15,34 @@ type SubrouterBuilder struct {
 	integrationsHandler *handlers.IntegrationsHandler
 	downloadHandler     *handlers.DownloadHandler
 	healthHandler       *handlers.HealthHandler
+	metricsHandler      *handlers.MetricsHandler
+	openAPIHandler      *handlers.OpenAPIHandler
+	adminHandler        *handlers.AdminHandler
+	webhookHandler      *handlers.WebhookHandler
 }
 
 // NewSubrouterBuilder creates a new subrouter builder
-func NewSubrouterBuilder(config *domain.Config) *SubrouterBuilder {
-	// Initialize handlers
-	versionsHandler := handlers.NewVersionsHandler(config)
-	integrationsHandler := handlers.NewIntegrationsHandler(config)
-	downloadHandler := handlers.NewDownloadHandler(config, versionsHandler)
-	healthHandler := handlers.NewHealthHandler(config)
+func NewSubrouterBuilder(base *handlers.BaseHandler) *SubrouterBuilder {
+	// Initialize handlers sharing the same base dependencies
+	versionsHandler := handlers.NewVersionsHandler(base)
+	integrationsHandler := handlers.NewIntegrationsHandler(base)
+	downloadHandler := handlers.NewDownloadHandler(base, versionsHandler)
+	healthHandler := handlers.NewHealthHandler(base)
+	metricsHandler := handlers.NewMetricsHandler(base)
+	openAPIHandler := handlers.NewOpenAPIHandler(base)
+	adminHandler := handlers.NewAdminHandler(base)
+	webhookHandler := handlers.NewWebhookHandler(base, downloadHandler)
 
 	return &SubrouterBuilder{
-		config:              config,
+		config:              base.Config,
 		versionsHandler:     versionsHandler,
 		integrationsHandler: integrationsHandler,
 		downloadHandler:     downloadHandler,
 		healthHandler:       healthHandler,
+		metricsHandler:      metricsHandler,
+		openAPIHandler:      openAPIHandler,
+		adminHandler:        adminHandler,
+		webhookHandler:      webhookHandler,
 	}
 }
 
@@ -37,7 +51,12 @@ func (sb *SubrouterBuilder) BuildVersionsSubrouter(router *mux.Router) {
 	versionsRouter := router.PathPrefix("/versions").Subrouter()
 
 	// GET /versions - Get all available tagged versions
-	versionsRouter.HandleFunc("", sb.versionsHandler.AuthMiddleware(sb.versionsHandler.GetVersions)).Methods("GET")
+	versionsRouter.HandleFunc("", sb.versionsHandler.AuthMiddleware(
+		sb.versionsHandler.RateLimitMiddleware(handlers.BudgetList, sb.versionsHandler.GetVersions))).Methods("GET")
+
+	// GET /versions/{version} - Release notes and details of a version
+	versionsRouter.HandleFunc("/{version}", sb.versionsHandler.AuthMiddleware(
+		sb.versionsHandler.RateLimitMiddleware(handlers.BudgetList, sb.versionsHandler.GetVersion))).Methods("GET")
 }
 
 // BuildIntegrationsSubrouter builds the integrations subrouter
@@ -45,7 +64,8 @@ func (sb *SubrouterBuilder) BuildIntegrationsSubrouter(router *mux.Router) {
 	integrationsRouter := router.PathPrefix("/integrations").Subrouter()
 
 	// GET /integrations - Get all available integrations
-	integrationsRouter.HandleFunc("", sb.integrationsHandler.AuthMiddleware(sb.integrationsHandler.GetIntegrations)).Methods("GET")
+	integrationsRouter.HandleFunc("", sb.integrationsHandler.AuthMiddleware(
+		sb.integrationsHandler.RateLimitMiddleware(handlers.BudgetList, sb.integrationsHandler.GetIntegrations))).Methods("GET")
 }
 
 // BuildDownloadSubrouter builds the download subrouter
@@ -53,15 +73,79 @@ func (sb *SubrouterBuilder) BuildDownloadSubrouter(router *mux.Router) {
 	downloadRouter := router.PathPrefix("/download").Subrouter()
 
 	// GET /download/{app_version}/{integration} - Download combined package
-	downloadRouter.HandleFunc("/{app_version}/{integration}", sb.downloadHandler.AuthMiddleware(sb.downloadHandler.DownloadPackage)).Methods("GET")
+	downloadRouter.HandleFunc("/{app_version}/{integration}", sb.downloadHandler.AuditMiddleware(
+		sb.downloadHandler.AuthMiddleware(sb.downloadHandler.RateLimitMiddleware(handlers.BudgetDownload,
+			sb.downloadHandler.DownloadConcurrencyMiddleware(sb.downloadHandler.DownloadPackage))))).Methods("GET")
 }
 
 // BuildHealthSubrouter builds the health check subrouter
 func (sb *SubrouterBuilder) BuildHealthSubrouter(router *mux.Router) {
 	healthRouter := router.PathPrefix("/health").Subrouter()
 
-	// GET /health - Health check (no auth required)
+	// GET /health - Liveness check (no auth required)
 	healthRouter.HandleFunc("", sb.healthHandler.HealthCheck).Methods("GET")
+
+	// GET /health/ready - Readiness check of upstream, disk and config (no auth required)
+	healthRouter.HandleFunc("/ready", sb.healthHandler.ReadinessCheck).Methods("GET")
+}
+
+// BuildMetricsSubrouter builds the Prometheus metrics subrouter
+func (sb *SubrouterBuilder) BuildMetricsSubrouter(router *mux.Router) {
+	metricsRouter := router.PathPrefix("/metrics").Subrouter()
+
+	// GET /metrics - Prometheus metrics (metrics token when configured)
+	metricsRouter.HandleFunc("", sb.metricsHandler.MetricsAuthMiddleware(sb.metricsHandler.GetMetrics)).Methods("GET")
+}
+
+// BuildOpenAPISubrouter builds the route serving the OpenAPI document
+func (sb *SubrouterBuilder) BuildOpenAPISubrouter(router *mux.Router) {
+	// GET /openapi.json - OpenAPI 3 document (no auth required)
+	router.HandleFunc("/openapi.json", sb.openAPIHandler.GetOpenAPI).Methods("GET")
+}
+
+// BuildAdminSubrouter builds the administration subrouter
+func (sb *SubrouterBuilder) BuildAdminSubrouter(router *mux.Router) {
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+
+	// Every admin request is recorded in the audit log (admin token required)
+	admin := func(action string, handler http.HandlerFunc) http.HandlerFunc {
+		return sb.adminHandler.AdminAuditMiddleware(action, sb.adminHandler.AdminAuthMiddleware(handler))
+	}
+
+	// GET /admin/audit - Download audit log as JSON or CSV
+	adminRouter.HandleFunc("/audit", admin("audit.export", sb.adminHandler.GetAuditLog)).Methods("GET")
+
+	// GET /admin/licenses - List licenses
+	adminRouter.HandleFunc("/licenses", admin("license.list", sb.adminHandler.ListLicenses)).Methods("GET")
+
+	// POST /admin/licenses - Issue a license, returning its token once
+	adminRouter.HandleFunc("/licenses", admin("license.issue", sb.adminHandler.IssueLicense)).Methods("POST")
+
+	// GET /admin/licenses/{id} - Get a license
+	adminRouter.HandleFunc("/licenses/{id}", admin("license.get", sb.adminHandler.GetLicense)).Methods("GET")
+
+	// PATCH /admin/licenses/{id} - Change customer, expiry, entitlements or rate limits
+	adminRouter.HandleFunc("/licenses/{id}", admin("license.update", sb.adminHandler.UpdateLicense)).Methods("PATCH")
+
+	// DELETE /admin/licenses/{id} - Revoke a license
+	adminRouter.HandleFunc("/licenses/{id}", admin("license.revoke", sb.adminHandler.RevokeLicense)).Methods("DELETE")
+
+	// POST /admin/licenses/{id}/suspend - Suspend a license
+	adminRouter.HandleFunc("/licenses/{id}/suspend", admin("license.suspend", sb.adminHandler.SuspendLicense)).Methods("POST")
+
+	// POST /admin/licenses/{id}/resume - Resume a suspended license
+	adminRouter.HandleFunc("/licenses/{id}/resume", admin("license.resume", sb.adminHandler.ResumeLicense)).Methods("POST")
+
+	// POST /admin/licenses/{id}/rotate - Replace the token of a license, returning it once
+	adminRouter.HandleFunc("/licenses/{id}/rotate", admin("license.rotate", sb.adminHandler.RotateLicense)).Methods("POST")
+}
+
+// BuildWebhooksSubrouter builds the webhooks subrouter
+func (sb *SubrouterBuilder) BuildWebhooksSubrouter(router *mux.Router) {
+	webhooksRouter := router.PathPrefix("/webhooks").Subrouter()
+
+	// POST /webhooks/github - Release and push events (signed with the webhook secret)
+	webhooksRouter.HandleFunc("/github", sb.webhookHandler.GitHubWebhook).Methods("POST")
 }
 
 // GetHandlers returns the initialized handlers for external use if needed
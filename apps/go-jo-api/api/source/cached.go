@@ -0,0 +1,176 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+)
+
+// maxManifestEntries bounds the integration manifests kept in memory
+const maxManifestEntries = 1024
+
+// CachedSource keeps the version and integration listings of another source
+// in memory for a short time, so listing endpoints and "latest" resolution
+// don't reach the upstream on every request. Integration manifests are kept
+// per commit, as they can't change without a new commit. Single releases,
+// integration commits and downloads are always fetched from the source.
+type CachedSource struct {
+	ArtifactSource
+	ttl time.Duration
+
+	versions     listing[domain.Release]
+	integrations listing[domain.Integration]
+
+	manifestsMu sync.Mutex
+	manifests   map[string]manifestEntry
+}
+
+// manifestEntry is a kept manifest, or the not found error of an integration
+// without one
+type manifestEntry struct {
+	manifest *domain.IntegrationManifest
+	err      error
+}
+
+// listing is a cached result with its expiry. fetching serialises refreshes
+// so concurrent requests don't all reach the upstream, and generation keeps
+// a refresh started before an invalidation from being cached.
+type listing[T any] struct {
+	fetching   sync.Mutex
+	mu         sync.Mutex
+	items      []T
+	expires    time.Time
+	generation uint64
+}
+
+// NewCachedSource wraps a source, keeping its listings for ttl. A ttl of
+// zero or less disables the cache.
+func NewCachedSource(source ArtifactSource, ttl time.Duration) *CachedSource {
+	return &CachedSource{
+		ArtifactSource: source,
+		ttl:            ttl,
+	}
+}
+
+// ListVersions returns the cached releases, refreshing them once expired
+func (s *CachedSource) ListVersions(ctx context.Context) ([]domain.Release, error) {
+	return s.versions.get(ctx, s.ttl, s.ArtifactSource.ListVersions)
+}
+
+// ListIntegrations returns the cached integrations, refreshing them once expired
+func (s *CachedSource) ListIntegrations(ctx context.Context) ([]domain.Integration, error) {
+	return s.integrations.get(ctx, s.ttl, s.ArtifactSource.ListIntegrations)
+}
+
+// GetIntegrationManifest returns the kept manifest of the integration
+// commit, reading it from the source the first time. Missing manifests are
+// kept too, other errors are not.
+func (s *CachedSource) GetIntegrationManifest(ctx context.Context, integration *domain.Integration) (*domain.IntegrationManifest, error) {
+	if s.ttl <= 0 {
+		return s.ArtifactSource.GetIntegrationManifest(ctx, integration)
+	}
+
+	key := integration.Name + "@" + integration.Commit
+
+	s.manifestsMu.Lock()
+	entry, ok := s.manifests[key]
+	s.manifestsMu.Unlock()
+	if ok {
+		return entry.manifest, entry.err
+	}
+
+	manifest, err := s.ArtifactSource.GetIntegrationManifest(ctx, integration)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	s.manifestsMu.Lock()
+	defer s.manifestsMu.Unlock()
+
+	if s.manifests == nil {
+		s.manifests = make(map[string]manifestEntry)
+	}
+	if len(s.manifests) >= maxManifestEntries {
+		// Manifests of older commits are no longer requested, start over
+		clear(s.manifests)
+	}
+	s.manifests[key] = manifestEntry{manifest: manifest, err: err}
+
+	return manifest, err
+}
+
+// Invalidate drops the cached listings, e.g. after a new release
+func (s *CachedSource) Invalidate() {
+	s.versions.invalidate()
+	s.integrations.invalidate()
+}
+
+// Check verifies the wrapped source when it supports checks
+func (s *CachedSource) Check(ctx context.Context) error {
+	if checker, ok := s.ArtifactSource.(Checker); ok {
+		return checker.Check(ctx)
+	}
+	return nil
+}
+
+// get returns a copy of the cached items, fetching them when expired.
+// Errors are not cached.
+func (l *listing[T]) get(ctx context.Context, ttl time.Duration, fetch func(context.Context) ([]T, error)) ([]T, error) {
+	if ttl <= 0 {
+		return fetch(ctx)
+	}
+
+	if items, ok := l.current(); ok {
+		return items, nil
+	}
+
+	l.fetching.Lock()
+	defer l.fetching.Unlock()
+
+	// Another request may have refreshed the listing in the meantime
+	if items, ok := l.current(); ok {
+		return items, nil
+	}
+
+	l.mu.Lock()
+	generation := l.generation
+	l.mu.Unlock()
+
+	items, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	if l.generation == generation {
+		l.items = items
+		l.expires = time.Now().Add(ttl)
+	}
+	l.mu.Unlock()
+
+	return slices.Clone(items), nil
+}
+
+// current returns a copy of the cached items unless they expired
+func (l *listing[T]) current() ([]T, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.expires.IsZero() || time.Now().After(l.expires) {
+		return nil, false
+	}
+	return slices.Clone(l.items), true
+}
+
+func (l *listing[T]) invalidate() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.items = nil
+	l.expires = time.Time{}
+	l.generation++
+}
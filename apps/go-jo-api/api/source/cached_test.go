@@ -0,0 +1,227 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+)
+
+// countingSource counts the listings and manifests fetched from it. Other
+// methods are not used by these tests.
+type countingSource struct {
+	ArtifactSource
+
+	versionFetches     atomic.Int32
+	integrationFetches atomic.Int32
+	manifestFetches    atomic.Int32
+
+	// fail makes the next fetches fail with this error
+	fail error
+	// listing, when set, is called by ListVersions before it returns
+	listing func()
+}
+
+func (s *countingSource) ListVersions(ctx context.Context) ([]domain.Release, error) {
+	s.versionFetches.Add(1)
+	if s.listing != nil {
+		s.listing()
+	}
+	if s.fail != nil {
+		return nil, s.fail
+	}
+	return []domain.Release{{Version: "v1.0.0"}, {Version: "v1.1.0"}}, nil
+}
+
+func (s *countingSource) ListIntegrations(ctx context.Context) ([]domain.Integration, error) {
+	s.integrationFetches.Add(1)
+	return []domain.Integration{{Name: "zabbix", Commit: "aaa111"}}, nil
+}
+
+func (s *countingSource) GetIntegrationManifest(ctx context.Context, integration *domain.Integration) (*domain.IntegrationManifest, error) {
+	s.manifestFetches.Add(1)
+	if s.fail != nil {
+		return nil, s.fail
+	}
+	if integration.Name == "bare" {
+		return nil, ErrNotFound
+	}
+	return &domain.IntegrationManifest{Name: integration.Name + " at " + integration.Commit}, nil
+}
+
+// expire makes the cached listings of s expire
+func expire(s *CachedSource) {
+	for _, expires := range []*time.Time{&s.versions.expires, &s.integrations.expires} {
+		if !expires.IsZero() {
+			*expires = time.Now().Add(-time.Second)
+		}
+	}
+}
+
+func TestCachedSourceListings(t *testing.T) {
+	upstream := &countingSource{}
+	s := NewCachedSource(upstream, time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		versions, err := s.ListVersions(ctx)
+		if err != nil || len(versions) != 2 {
+			t.Fatalf("ListVersions = %+v, %v", versions, err)
+		}
+		// Callers get a copy they may change
+		versions[0].Version = "changed"
+	}
+	if n := upstream.versionFetches.Load(); n != 1 {
+		t.Errorf("version fetches = %d, want 1 within the TTL", n)
+	}
+	if versions, _ := s.ListVersions(ctx); versions[0].Version != "v1.0.0" {
+		t.Errorf("cached versions = %+v, changed by a caller", versions)
+	}
+
+	s.ListIntegrations(ctx)
+	s.ListIntegrations(ctx)
+	if n := upstream.integrationFetches.Load(); n != 1 {
+		t.Errorf("integration fetches = %d, want 1 within the TTL", n)
+	}
+
+	expire(s)
+	s.ListVersions(ctx)
+	s.ListIntegrations(ctx)
+	if upstream.versionFetches.Load() != 2 || upstream.integrationFetches.Load() != 2 {
+		t.Errorf("fetches after expiry = %d, %d, want 2, 2", upstream.versionFetches.Load(), upstream.integrationFetches.Load())
+	}
+
+	// Invalidate drops both listings before they expire
+	s.Invalidate()
+	s.ListVersions(ctx)
+	s.ListIntegrations(ctx)
+	if upstream.versionFetches.Load() != 3 || upstream.integrationFetches.Load() != 3 {
+		t.Errorf("fetches after Invalidate = %d, %d, want 3, 3", upstream.versionFetches.Load(), upstream.integrationFetches.Load())
+	}
+}
+
+func TestCachedSourceErrorsAreNotCached(t *testing.T) {
+	upstream := &countingSource{fail: ErrUpstreamUnavailable}
+	s := NewCachedSource(upstream, time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.ListVersions(ctx); !errors.Is(err, ErrUpstreamUnavailable) {
+			t.Fatalf("ListVersions error = %v, want ErrUpstreamUnavailable", err)
+		}
+	}
+
+	upstream.fail = nil
+	if versions, err := s.ListVersions(ctx); err != nil || len(versions) != 2 {
+		t.Errorf("ListVersions after recovery = %+v, %v", versions, err)
+	}
+	if n := upstream.versionFetches.Load(); n != 3 {
+		t.Errorf("version fetches = %d, want every failed one repeated", n)
+	}
+}
+
+func TestCachedSourceWithoutTTL(t *testing.T) {
+	upstream := &countingSource{}
+	s := NewCachedSource(upstream, 0)
+	ctx := context.Background()
+
+	integration := &domain.Integration{Name: "zabbix", Commit: "aaa111"}
+	for i := 0; i < 2; i++ {
+		s.ListVersions(ctx)
+		s.ListIntegrations(ctx)
+		s.GetIntegrationManifest(ctx, integration)
+	}
+	if upstream.versionFetches.Load() != 2 || upstream.integrationFetches.Load() != 2 || upstream.manifestFetches.Load() != 2 {
+		t.Errorf("fetches = %d, %d, %d, want every call to reach the source",
+			upstream.versionFetches.Load(), upstream.integrationFetches.Load(), upstream.manifestFetches.Load())
+	}
+}
+
+func TestCachedSourceInvalidateDuringRefresh(t *testing.T) {
+	upstream := &countingSource{}
+	s := NewCachedSource(upstream, time.Hour)
+	ctx := context.Background()
+
+	// A release is published while the listing is being fetched
+	upstream.listing = func() {
+		upstream.listing = nil
+		s.Invalidate()
+	}
+	if _, err := s.ListVersions(ctx); err != nil {
+		t.Fatalf("ListVersions error = %v", err)
+	}
+
+	// The listing fetched before the invalidation isn't kept
+	s.ListVersions(ctx)
+	s.ListVersions(ctx)
+	if n := upstream.versionFetches.Load(); n != 2 {
+		t.Errorf("version fetches = %d, want 2", n)
+	}
+}
+
+func TestCachedSourceManifests(t *testing.T) {
+	upstream := &countingSource{}
+	s := NewCachedSource(upstream, time.Hour)
+	ctx := context.Background()
+
+	zabbix := &domain.Integration{Name: "zabbix", Commit: "aaa111"}
+	for i := 0; i < 2; i++ {
+		manifest, err := s.GetIntegrationManifest(ctx, zabbix)
+		if err != nil || manifest.Name != "zabbix at aaa111" {
+			t.Fatalf("GetIntegrationManifest = %+v, %v", manifest, err)
+		}
+	}
+	if n := upstream.manifestFetches.Load(); n != 1 {
+		t.Errorf("manifest fetches = %d, want 1 per commit", n)
+	}
+
+	// Manifests are kept per commit, a new commit is read again
+	moved := &domain.Integration{Name: "zabbix", Commit: "ccc333"}
+	if manifest, _ := s.GetIntegrationManifest(ctx, moved); manifest.Name != "zabbix at ccc333" {
+		t.Errorf("manifest of the new commit = %+v", manifest)
+	}
+
+	// Missing manifests are kept too
+	bare := &domain.Integration{Name: "bare", Commit: "bbb222"}
+	for i := 0; i < 2; i++ {
+		if _, err := s.GetIntegrationManifest(ctx, bare); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetIntegrationManifest(bare) error = %v, want ErrNotFound", err)
+		}
+	}
+
+	// Invalidate leaves the manifests, they only change with the commit
+	s.Invalidate()
+	s.GetIntegrationManifest(ctx, zabbix)
+	if n := upstream.manifestFetches.Load(); n != 3 {
+		t.Errorf("manifest fetches = %d, want 3", n)
+	}
+
+	// Other errors are not kept
+	upstream.fail = ErrUpstreamUnavailable
+	other := &domain.Integration{Name: "grafana", Commit: "ddd444"}
+	s.GetIntegrationManifest(ctx, other)
+	upstream.fail = nil
+	if manifest, err := s.GetIntegrationManifest(ctx, other); err != nil || manifest == nil {
+		t.Errorf("GetIntegrationManifest after a failure = %+v, %v", manifest, err)
+	}
+	if n := upstream.manifestFetches.Load(); n != 5 {
+		t.Errorf("manifest fetches = %d, want 5", n)
+	}
+}
+
+func TestCachedSourceManifestLimit(t *testing.T) {
+	upstream := &countingSource{}
+	s := NewCachedSource(upstream, time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < maxManifestEntries+1; i++ {
+		s.GetIntegrationManifest(ctx, &domain.Integration{Name: "zabbix", Commit: strconv.Itoa(i)})
+	}
+	if len(s.manifests) > maxManifestEntries {
+		t.Errorf("kept %d manifests, want at most %d", len(s.manifests), maxManifestEntries)
+	}
+}
@@ -0,0 +1,113 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// route is a recorded response replayed by a forgeServer. Fixture bodies are
+// read from testdata, other bodies are served as they are.
+type route struct {
+	status  int
+	fixture string
+	body    string
+	header  map[string]string
+}
+
+// recordedRequest is a request received by a forgeServer
+type recordedRequest struct {
+	key   string
+	query string
+	auth  string
+}
+
+// forgeServer replays recorded forge responses. Routes are keyed by escaped
+// path, followed by "?page=N" for pages after the first. "{{server}}" in
+// bodies and headers is replaced by the server URL, and by the URLs set with
+// replace.
+type forgeServer struct {
+	*httptest.Server
+	t      *testing.T
+	dir    string
+	routes map[string]route
+
+	mu           sync.Mutex
+	replacements []string
+	requests     []recordedRequest
+}
+
+func newForgeServer(t *testing.T, dir string, routes map[string]route) *forgeServer {
+	t.Helper()
+	s := &forgeServer{t: t, dir: filepath.Join("testdata", dir), routes: routes}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	s.replace("{{server}}", s.URL)
+	return s
+}
+
+// replace substitutes a placeholder in the replayed bodies and headers
+func (s *forgeServer) replace(placeholder, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replacements = append(s.replacements, placeholder, value)
+}
+
+// received returns the requests received for a route key
+func (s *forgeServer) received(key string) []recordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var requests []recordedRequest
+	for _, request := range s.requests {
+		if request.key == key {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+func (s *forgeServer) serve(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.EscapedPath()
+	if page := r.URL.Query().Get("page"); page != "" && page != "1" {
+		key += "?page=" + page
+	}
+
+	auth := r.Header.Get("Authorization") + r.Header.Get("PRIVATE-TOKEN")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, recordedRequest{key: key, query: r.URL.RawQuery, auth: auth})
+	replacer := strings.NewReplacer(s.replacements...)
+	s.mu.Unlock()
+
+	route, ok := s.routes[key]
+	if !ok {
+		s.t.Errorf("unexpected request %s", key)
+		http.Error(w, "no recorded response", http.StatusInternalServerError)
+		return
+	}
+
+	body := route.body
+	if route.fixture != "" {
+		data, err := os.ReadFile(filepath.Join(s.dir, route.fixture))
+		if err != nil {
+			s.t.Errorf("reading fixture: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		body = string(data)
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	for name, value := range route.header {
+		w.Header().Set(name, replacer.Replace(value))
+	}
+	if route.status != 0 {
+		w.WriteHeader(route.status)
+	}
+	w.Write([]byte(replacer.Replace(body)))
+}
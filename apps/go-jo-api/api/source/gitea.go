@@ -0,0 +1,184 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+)
+
+// GiteaSource serves releases of the go-jo repository and branches of the
+// docker-environments repository through the Gitea v1 API
+type GiteaSource struct {
+	config domain.RemoteSourceConfig
+	api    *restClient
+}
+
+// NewGiteaSource creates a new Gitea artifact source
+func NewGiteaSource(config *domain.Config) (*GiteaSource, error) {
+	gitea := config.Source.Gitea
+	if err := validateRemoteConfig("gitea", gitea); err != nil {
+		return nil, err
+	}
+
+	return &GiteaSource{
+		config: gitea,
+		api: &restClient{
+			name:    "Gitea API",
+			baseURL: gitea.BaseURL,
+			client:  newUpstreamClient(TypeGitea),
+			authorize: func(req *http.Request) {
+				if gitea.Token != "" {
+					req.Header.Set("Authorization", "token "+gitea.Token)
+				}
+			},
+			accept:          "application/json",
+			pageSizeParam:   "limit",
+			perPage:         gitea.GetPerPage(),
+			maxPages:        gitea.GetMaxPages(),
+			timeout:         config.GetRequestTimeout(),
+			downloadTimeout: config.GetSourceDownloadTimeout(),
+		},
+	}, nil
+}
+
+// Name identifies the backend in logs
+func (s *GiteaSource) Name() string {
+	return fmt.Sprintf("gitea %s (%s, %s)", s.config.BaseURL, s.config.Repositories.GoJo, s.config.Repositories.DockerEnvironments)
+}
+
+// ListVersions returns every published release of the go-jo repository
+func (s *GiteaSource) ListVersions(ctx context.Context) ([]domain.Release, error) {
+	releases, err := fetchAll[domain.GitHubReleaseWithAssets](ctx, s.api, s.repoURL(s.config.Repositories.GoJo, "releases"))
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []domain.Release
+	for _, release := range releases {
+		if !release.Draft {
+			versions = append(versions, toGiteaRelease(&release))
+		}
+	}
+	return versions, nil
+}
+
+// GetVersion returns the release of a tag with its app package
+func (s *GiteaSource) GetVersion(ctx context.Context, version string) (*domain.Release, error) {
+	var release domain.GitHubReleaseWithAssets
+	if err := s.api.fetchJSON(ctx, s.repoURL(s.config.Repositories.GoJo, "releases", "tags", version), &release); err != nil {
+		return nil, notFound(err, "version", version)
+	}
+
+	result := toGiteaRelease(&release)
+	return &result, nil
+}
+
+// GetVersionCommit resolves the tag of a release to the commit it points to
+func (s *GiteaSource) GetVersionCommit(ctx context.Context, version string) (string, error) {
+	var tag domain.GiteaTag
+	if err := s.api.fetchJSON(ctx, s.repoURL(s.config.Repositories.GoJo, "tags", version), &tag); err != nil {
+		return "", notFound(err, "version", version)
+	}
+	return tag.Commit.SHA, nil
+}
+
+// ListIntegrations returns the branches of the docker-environments repository
+func (s *GiteaSource) ListIntegrations(ctx context.Context) ([]domain.Integration, error) {
+	branches, err := fetchAll[domain.GiteaBranch](ctx, s.api, s.repoURL(s.config.Repositories.DockerEnvironments, "branches"))
+	if err != nil {
+		return nil, err
+	}
+
+	integrations := make([]domain.Integration, 0, len(branches))
+	for _, branch := range branches {
+		integrations = append(integrations, domain.Integration{Name: branch.Name, Commit: branch.Commit.ID})
+	}
+	return integrations, nil
+}
+
+// GetIntegration resolves a branch to its head commit
+func (s *GiteaSource) GetIntegration(ctx context.Context, name string) (*domain.Integration, error) {
+	var branch domain.GiteaBranch
+	if err := s.api.fetchJSON(ctx, s.repoURL(s.config.Repositories.DockerEnvironments, "branches", name), &branch); err != nil {
+		return nil, notFound(err, "integration", name)
+	}
+	if branch.Commit.ID == "" {
+		return nil, fmt.Errorf("branch %s has no commit", name)
+	}
+	return &domain.Integration{Name: name, Commit: branch.Commit.ID}, nil
+}
+
+// GetIntegrationManifest reads the raw integration.yaml of the integration commit
+func (s *GiteaSource) GetIntegrationManifest(ctx context.Context, integration *domain.Integration) (*domain.IntegrationManifest, error) {
+	manifestURL := s.repoURL(s.config.Repositories.DockerEnvironments, "raw", manifestFile) + "?ref=" + url.QueryEscape(integration.Commit)
+
+	data, err := s.api.fetchRaw(ctx, manifestURL, "", maxManifestSize)
+	if err != nil {
+		return nil, manifestNotFound(err, integration)
+	}
+	return parseManifest(data)
+}
+
+// FetchAppPackage downloads the release attachment
+func (s *GiteaSource) FetchAppPackage(ctx context.Context, pkg *domain.Package) (io.ReadCloser, error) {
+	return s.api.download(ctx, pkg.Location, "")
+}
+
+// FetchIntegrationArchive downloads the zip archive of the integration commit
+func (s *GiteaSource) FetchIntegrationArchive(ctx context.Context, integration *domain.Integration) (io.ReadCloser, error) {
+	return s.api.download(ctx, s.repoURL(s.config.Repositories.DockerEnvironments, "archive", integration.Commit+".zip"), "")
+}
+
+// Check verifies Gitea is reachable and both repositories are accessible with the token
+func (s *GiteaSource) Check(ctx context.Context) error {
+	for _, repo := range []string{s.config.Repositories.GoJo, s.config.Repositories.DockerEnvironments} {
+		var result struct {
+			ID int `json:"id"`
+		}
+		if err := s.api.fetchJSON(ctx, s.repoURL(repo), &result); err != nil {
+			return fmt.Errorf("repository %s: %w", repo, err)
+		}
+	}
+	return nil
+}
+
+// repoURL builds an API URL below a repository, escaping every path segment
+func (s *GiteaSource) repoURL(repo string, segments ...string) string {
+	parts := []string{strings.TrimRight(s.config.BaseURL, "/"), "api/v1/repos", repo}
+	for _, segment := range segments {
+		parts = append(parts, url.PathEscape(segment))
+	}
+	return strings.Join(parts, "/")
+}
+
+// toGiteaRelease converts a Gitea release, picking the go-jo .deb among its attachments
+func toGiteaRelease(release *domain.GitHubReleaseWithAssets) domain.Release {
+	result := domain.Release{
+		Version:    release.TagName,
+		Prerelease: release.Prerelease,
+		Notes:      release.Body,
+	}
+	if release.PublishedAt != nil {
+		result.PublishedAt = *release.PublishedAt
+	}
+
+	for _, asset := range release.Assets {
+		result.Assets = append(result.Assets, domain.Asset{Name: asset.Name, Size: asset.Size})
+
+		if result.AppPackage == nil && strings.HasSuffix(asset.Name, ".deb") && !strings.Contains(asset.Name, "api") {
+			result.AppPackage = &domain.Package{
+				ID:       "gitea-" + strconv.Itoa(asset.ID),
+				Name:     asset.Name,
+				Size:     asset.Size,
+				Location: asset.BrowserDownloadURL,
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,216 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+)
+
+const (
+	giteaGoJo = "/api/v1/repos/org/go-jo"
+	giteaEnvs = "/api/v1/repos/org/docker-environments"
+)
+
+// giteaRoutes replays the Gitea v1 responses recorded in testdata/gitea.
+// Listings are paginated with a Link header.
+func giteaRoutes() map[string]route {
+	return map[string]route{
+		giteaGoJo + "/releases": {fixture: "releases_page1.json", header: map[string]string{
+			"Link":          `<{{server}}` + giteaGoJo + `/releases?limit=2&page=2>; rel="next",<{{server}}` + giteaGoJo + `/releases?limit=2&page=2>; rel="last"`,
+			"X-Total-Count": "3",
+		}},
+		giteaGoJo + "/releases?page=2": {fixture: "releases_page2.json", header: map[string]string{
+			"Link":          `<{{server}}` + giteaGoJo + `/releases?limit=2&page=1>; rel="first",<{{server}}` + giteaGoJo + `/releases?limit=2&page=1>; rel="prev"`,
+			"X-Total-Count": "3",
+		}},
+		giteaGoJo + "/releases/tags/v1.0.0": {fixture: "release_v1.0.0.json"},
+		giteaGoJo + "/releases/tags/v9.9.9": {status: http.StatusNotFound, fixture: "not_found.json"},
+		giteaGoJo + "/tags/v1.0.0":          {fixture: "tag_v1.0.0.json"},
+		giteaGoJo + "/tags/v9.9.9":          {status: http.StatusNotFound, fixture: "not_found.json"},
+		giteaEnvs + "/branches": {fixture: "branches_page1.json", header: map[string]string{
+			"Link": `<{{server}}` + giteaEnvs + `/branches?limit=2&page=2>; rel="next",<{{server}}` + giteaEnvs + `/branches?limit=2&page=2>; rel="last"`,
+		}},
+		giteaEnvs + "/branches?page=2":                                      {fixture: "branches_page2.json"},
+		giteaEnvs + "/branches/zabbix":                                      {fixture: "branch_zabbix.json"},
+		giteaEnvs + "/branches/nginx":                                       {status: http.StatusNotFound, fixture: "not_found.json"},
+		giteaEnvs + "/raw/integration.yaml":                                 {body: "name: Zabbix\ngo_jo:\n  min_version: v1.0.0\n"},
+		giteaEnvs + "/archive/8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c.zip": {body: "zabbix archive"},
+		"/api/v1/repos/org/missing/branches":                                {status: http.StatusNotFound, fixture: "not_found.json"},
+		"/attachments/9e1a4b22":                                             {body: "go-jo 1.0.0"},
+		"/attachments/gone":                                                 {status: http.StatusNotFound, fixture: "not_found.json"},
+		giteaGoJo:                                                           {body: `{"id": 1}`},
+		giteaEnvs:                                                           {body: `{"id": 2}`},
+	}
+}
+
+func newTestGiteaSource(t *testing.T, server *forgeServer, envs string) *GiteaSource {
+	t.Helper()
+	s, err := NewGiteaSource(&domain.Config{Source: domain.SourceConfig{Gitea: domain.RemoteSourceConfig{
+		BaseURL:      server.URL,
+		Token:        "secret",
+		PerPage:      2,
+		Repositories: domain.RepositoriesConfig{GoJo: "org/go-jo", DockerEnvironments: envs},
+	}}})
+	if err != nil {
+		t.Fatalf("NewGiteaSource error = %v", err)
+	}
+	return s
+}
+
+func TestGiteaListVersions(t *testing.T) {
+	server := newForgeServer(t, "gitea", giteaRoutes())
+	s := newTestGiteaSource(t, server, "org/docker-environments")
+
+	versions, err := s.ListVersions(context.Background())
+	if err != nil {
+		t.Fatalf("ListVersions error = %v", err)
+	}
+
+	// v1.2.0 is a draft and is left out
+	if len(versions) != 2 || versions[0].Version != "v1.1.0-rc.1" || versions[1].Version != "v1.0.0" {
+		t.Fatalf("ListVersions = %+v, want v1.1.0-rc.1 and v1.0.0", versions)
+	}
+	if !versions[0].Prerelease || versions[1].Prerelease {
+		t.Errorf("prerelease flags = %v, %v, want true, false", versions[0].Prerelease, versions[1].Prerelease)
+	}
+
+	stable := versions[1]
+	if stable.Notes != "First release" || !stable.PublishedAt.Equal(time.Date(2025, 11, 20, 14, 0, 0, 0, time.UTC)) ||
+		len(stable.Assets) != 2 || stable.Assets[1] != (domain.Asset{Name: "go-jo_1.0.0_amd64.deb", Size: 1024}) {
+		t.Errorf("v1.0.0 = %+v", stable)
+	}
+
+	// The API package is skipped for the app package
+	want := domain.Package{ID: "gitea-292", Name: "go-jo_1.0.0_amd64.deb", Size: 1024, Location: server.URL + "/attachments/9e1a4b22"}
+	if stable.AppPackage == nil || *stable.AppPackage != want {
+		t.Errorf("v1.0.0 app package = %+v, want %+v", stable.AppPackage, want)
+	}
+
+	first := server.received(giteaGoJo + "/releases")
+	if len(first) != 1 || first[0].query != "limit=2" || first[0].auth != "token secret" {
+		t.Errorf("first page requests = %+v, want limit=2 with the token", first)
+	}
+	if second := server.received(giteaGoJo + "/releases?page=2"); len(second) != 1 {
+		t.Errorf("second page requests = %+v, want one following the Link header", second)
+	}
+}
+
+func TestGiteaGetVersion(t *testing.T) {
+	server := newForgeServer(t, "gitea", giteaRoutes())
+	s := newTestGiteaSource(t, server, "org/docker-environments")
+	ctx := context.Background()
+
+	tests := []struct {
+		version    string
+		wantCommit string
+		wantErr    error
+	}{
+		{"v1.0.0", "a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1", nil},
+		{"v9.9.9", "", ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			release, err := s.GetVersion(ctx, tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetVersion error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (release.Version != tt.version || release.AppPackage == nil) {
+				t.Errorf("GetVersion = %+v", release)
+			}
+
+			commit, err := s.GetVersionCommit(ctx, tt.version)
+			if !errors.Is(err, tt.wantErr) || commit != tt.wantCommit {
+				t.Errorf("GetVersionCommit = %q, %v, want %q, %v", commit, err, tt.wantCommit, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGiteaIntegrations(t *testing.T) {
+	server := newForgeServer(t, "gitea", giteaRoutes())
+	s := newTestGiteaSource(t, server, "org/docker-environments")
+	ctx := context.Background()
+
+	integrations, err := s.ListIntegrations(ctx)
+	if err != nil {
+		t.Fatalf("ListIntegrations error = %v", err)
+	}
+	want := []domain.Integration{
+		{Name: "grafana", Commit: "6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a"},
+		{Name: "main", Commit: "7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b"},
+		{Name: "zabbix", Commit: "8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c"},
+	}
+	if len(integrations) != len(want) {
+		t.Fatalf("ListIntegrations = %+v, want %+v", integrations, want)
+	}
+	for i := range want {
+		if integrations[i] != want[i] {
+			t.Errorf("ListIntegrations[%d] = %+v, want %+v", i, integrations[i], want[i])
+		}
+	}
+
+	integration, err := s.GetIntegration(ctx, "zabbix")
+	if err != nil || *integration != want[2] {
+		t.Fatalf("GetIntegration(zabbix) = %+v, %v", integration, err)
+	}
+	if _, err := s.GetIntegration(ctx, "nginx"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetIntegration(nginx) error = %v, want ErrNotFound", err)
+	}
+
+	manifest, err := s.GetIntegrationManifest(ctx, integration)
+	if err != nil || manifest.Name != "Zabbix" || manifest.GoJo.MinVersion != "v1.0.0" {
+		t.Errorf("GetIntegrationManifest = %+v, %v", manifest, err)
+	}
+	if requests := server.received(giteaEnvs + "/raw/integration.yaml"); len(requests) != 1 || requests[0].query != "ref="+want[2].Commit {
+		t.Errorf("manifest requests = %+v, want ref of the commit", requests)
+	}
+
+	missing := newTestGiteaSource(t, server, "org/missing")
+	if _, err := missing.ListIntegrations(ctx); !errors.Is(err, ErrUpstreamUnauthorized) {
+		t.Errorf("ListIntegrations of a missing repository error = %v, want ErrUpstreamUnauthorized", err)
+	}
+	if err := s.Check(ctx); err != nil {
+		t.Errorf("Check error = %v", err)
+	}
+}
+
+func TestGiteaDownloads(t *testing.T) {
+	server := newForgeServer(t, "gitea", giteaRoutes())
+	s := newTestGiteaSource(t, server, "org/docker-environments")
+	ctx := context.Background()
+
+	read := func(body io.ReadCloser, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("download error = %v", err)
+		}
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatalf("reading download: %v", err)
+		}
+		return string(data)
+	}
+
+	if got := read(s.FetchAppPackage(ctx, &domain.Package{Location: server.URL + "/attachments/9e1a4b22"})); got != "go-jo 1.0.0" {
+		t.Errorf("FetchAppPackage = %q", got)
+	}
+	if requests := server.received("/attachments/9e1a4b22"); len(requests) != 1 || requests[0].auth != "token secret" {
+		t.Errorf("attachment requests = %+v, want the token", requests)
+	}
+
+	integration := &domain.Integration{Name: "zabbix", Commit: "8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c"}
+	if got := read(s.FetchIntegrationArchive(ctx, integration)); got != "zabbix archive" {
+		t.Errorf("FetchIntegrationArchive = %q", got)
+	}
+
+	if _, err := s.FetchAppPackage(ctx, &domain.Package{Location: server.URL + "/attachments/gone"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FetchAppPackage of a missing attachment error = %v, want ErrNotFound", err)
+	}
+}
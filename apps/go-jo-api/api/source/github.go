@@ -0,0 +1,189 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+)
+
+// GitHubSource serves releases of the go-jo repository and branches of the
+// docker-environments repository through the GitHub v3 API
+type GitHubSource struct {
+	config *domain.Config
+	api    *restClient
+}
+
+// NewGitHubSource creates a new GitHub artifact source
+func NewGitHubSource(config *domain.Config) *GitHubSource {
+	return &GitHubSource{
+		config: config,
+		api: &restClient{
+			name:    "GitHub API",
+			baseURL: config.GetGitHubAPIBaseURL(),
+			client:  newUpstreamClient(TypeGitHub),
+			authorize: func(req *http.Request) {
+				req.Header.Set("Authorization", "token "+config.GitHubToken)
+			},
+			accept:          "application/vnd.github.v3+json",
+			pageSizeParam:   "per_page",
+			perPage:         config.GetGitHubPerPage(),
+			maxPages:        config.GetGitHubMaxPages(),
+			timeout:         config.GetRequestTimeout(),
+			downloadTimeout: config.GetSourceDownloadTimeout(),
+		},
+	}
+}
+
+// Name identifies the backend in logs
+func (s *GitHubSource) Name() string {
+	return fmt.Sprintf("github (%s, %s)", s.config.GetGoJoRepo(), s.config.GetDockerEnvRepo())
+}
+
+// ListVersions returns every published release of the go-jo repository
+func (s *GitHubSource) ListVersions(ctx context.Context) ([]domain.Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases", s.config.GetGitHubAPIBaseURL(), s.config.GetGoJoRepo())
+
+	releases, err := fetchAll[domain.GitHubReleaseWithAssets](ctx, s.api, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []domain.Release
+	for _, release := range releases {
+		if !release.Draft {
+			versions = append(versions, s.toRelease(&release))
+		}
+	}
+	return versions, nil
+}
+
+// GetVersion returns the release of a tag with its app package
+func (s *GitHubSource) GetVersion(ctx context.Context, version string) (*domain.Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases/tags/%s", s.config.GetGitHubAPIBaseURL(), s.config.GetGoJoRepo(), url.PathEscape(version))
+
+	var release domain.GitHubReleaseWithAssets
+	if err := s.api.fetchJSON(ctx, url, &release); err != nil {
+		return nil, notFound(err, "version", version)
+	}
+
+	result := s.toRelease(&release)
+	return &result, nil
+}
+
+// GetVersionCommit resolves the tag of a release to the commit it points to
+func (s *GitHubSource) GetVersionCommit(ctx context.Context, version string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/commits/%s", s.config.GetGitHubAPIBaseURL(), s.config.GetGoJoRepo(), url.PathEscape(version))
+
+	var commit domain.GitHubCommit
+	if err := s.api.fetchJSON(ctx, url, &commit); err != nil {
+		return "", notFound(err, "version", version)
+	}
+	return commit.SHA, nil
+}
+
+// ListIntegrations returns the branches of the docker-environments repository
+func (s *GitHubSource) ListIntegrations(ctx context.Context) ([]domain.Integration, error) {
+	url := fmt.Sprintf("%s/repos/%s/branches", s.config.GetGitHubAPIBaseURL(), s.config.GetDockerEnvRepo())
+
+	branches, err := fetchAll[domain.GitHubBranch](ctx, s.api, url)
+	if err != nil {
+		return nil, err
+	}
+
+	integrations := make([]domain.Integration, 0, len(branches))
+	for _, branch := range branches {
+		integrations = append(integrations, domain.Integration{Name: branch.Name, Commit: branch.Commit.SHA})
+	}
+	return integrations, nil
+}
+
+// GetIntegration resolves a branch to its head commit
+func (s *GitHubSource) GetIntegration(ctx context.Context, name string) (*domain.Integration, error) {
+	url := fmt.Sprintf("%s/repos/%s/branches/%s", s.config.GetGitHubAPIBaseURL(), s.config.GetDockerEnvRepo(), url.PathEscape(name))
+
+	var branch domain.GitHubBranch
+	if err := s.api.fetchJSON(ctx, url, &branch); err != nil {
+		return nil, notFound(err, "integration", name)
+	}
+	if branch.Commit.SHA == "" {
+		return nil, fmt.Errorf("branch %s has no commit", name)
+	}
+	return &domain.Integration{Name: name, Commit: branch.Commit.SHA}, nil
+}
+
+// GetIntegrationManifest reads the raw integration.yaml of the integration commit
+func (s *GitHubSource) GetIntegrationManifest(ctx context.Context, integration *domain.Integration) (*domain.IntegrationManifest, error) {
+	url := fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s", s.config.GetGitHubAPIBaseURL(), s.config.GetDockerEnvRepo(), manifestFile, url.QueryEscape(integration.Commit))
+
+	data, err := s.api.fetchRaw(ctx, url, "application/vnd.github.raw", maxManifestSize)
+	if err != nil {
+		return nil, manifestNotFound(err, integration)
+	}
+	return parseManifest(data)
+}
+
+// FetchAppPackage downloads a release asset by ID (works for private repos)
+func (s *GitHubSource) FetchAppPackage(ctx context.Context, pkg *domain.Package) (io.ReadCloser, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases/assets/%s", s.config.GetGitHubAPIBaseURL(), s.config.GetGoJoRepo(), pkg.ID)
+	return s.api.download(ctx, url, "application/octet-stream")
+}
+
+// FetchIntegrationArchive downloads the zipball of the integration commit (works for private repos)
+func (s *GitHubSource) FetchIntegrationArchive(ctx context.Context, integration *domain.Integration) (io.ReadCloser, error) {
+	url := fmt.Sprintf("%s/repos/%s/zipball/%s", s.config.GetGitHubAPIBaseURL(), s.config.GetDockerEnvRepo(), integration.Commit)
+	return s.api.download(ctx, url, "application/vnd.github.v3+json")
+}
+
+// Check verifies GitHub is reachable and accepts the token. The rate_limit
+// endpoint does not count against the quota, and is always requested
+// unconditionally so the remaining quota is current.
+func (s *GitHubSource) Check(ctx context.Context) error {
+	var rateLimit struct {
+		Resources struct {
+			Core struct {
+				Remaining int   `json:"remaining"`
+				Reset     int64 `json:"reset"`
+			} `json:"core"`
+		} `json:"resources"`
+	}
+
+	if err := s.api.fetchCurrent(ctx, s.config.GetGitHubAPIBaseURL()+"/rate_limit", &rateLimit); err != nil {
+		return err
+	}
+	if core := rateLimit.Resources.Core; core.Remaining == 0 {
+		return fmt.Errorf("GitHub rate limit exhausted until %s", time.Unix(core.Reset, 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// toRelease converts a GitHub release, picking the go-jo .deb among its assets
+func (s *GitHubSource) toRelease(release *domain.GitHubReleaseWithAssets) domain.Release {
+	result := domain.Release{
+		Version:    release.TagName,
+		Prerelease: release.Prerelease,
+		Notes:      release.Body,
+	}
+	if release.PublishedAt != nil {
+		result.PublishedAt = *release.PublishedAt
+	}
+
+	for _, asset := range release.Assets {
+		result.Assets = append(result.Assets, domain.Asset{Name: asset.Name, Size: asset.Size})
+
+		if result.AppPackage == nil && strings.HasSuffix(asset.Name, ".deb") && !strings.Contains(asset.Name, "api") {
+			result.AppPackage = &domain.Package{
+				ID:   strconv.Itoa(asset.ID),
+				Name: asset.Name,
+				Size: asset.Size,
+			}
+		}
+	}
+	return result
+}
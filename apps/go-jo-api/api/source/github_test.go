@@ -0,0 +1,246 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+)
+
+const (
+	githubGoJo = "/repos/org/go-jo"
+	githubEnvs = "/repos/org/docker-environments"
+)
+
+// githubRoutes replays the GitHub v3 responses recorded in testdata/github.
+// Listings are paginated with a Link header.
+func githubRoutes() map[string]route {
+	return map[string]route{
+		githubGoJo + "/releases": {fixture: "releases_page1.json", header: map[string]string{
+			"Link": `<{{server}}` + githubGoJo + `/releases?per_page=2&page=2>; rel="next", <{{server}}` + githubGoJo + `/releases?per_page=2&page=2>; rel="last"`,
+		}},
+		githubGoJo + "/releases?page=2": {fixture: "releases_page2.json", header: map[string]string{
+			"Link": `<{{server}}` + githubGoJo + `/releases?per_page=2&page=1>; rel="prev", <{{server}}` + githubGoJo + `/releases?per_page=2&page=1>; rel="first"`,
+		}},
+		githubGoJo + "/releases/tags/v1.0.0": {fixture: "release_v1.0.0.json"},
+		githubGoJo + "/releases/tags/v9.9.9": {status: http.StatusNotFound, fixture: "not_found.json"},
+		githubGoJo + "/commits/v1.0.0":       {fixture: "commit_v1.0.0.json"},
+		githubGoJo + "/commits/v9.9.9":       {status: http.StatusUnprocessableEntity, body: `{"message": "No commit found for SHA: v9.9.9"}`},
+		githubGoJo + "/releases/assets/1002": {body: "go-jo 1.0.0"},
+		githubGoJo + "/releases/assets/9999": {status: http.StatusNotFound, fixture: "not_found.json"},
+		githubEnvs + "/branches": {fixture: "branches_page1.json", header: map[string]string{
+			"Link": `<{{server}}` + githubEnvs + `/branches?per_page=2&page=2>; rel="next", <{{server}}` + githubEnvs + `/branches?per_page=2&page=2>; rel="last"`,
+		}},
+		githubEnvs + "/branches?page=2":                                  {fixture: "branches_page2.json"},
+		githubEnvs + "/branches/zabbix":                                  {fixture: "branch_zabbix.json"},
+		githubEnvs + "/branches/nginx":                                   {status: http.StatusNotFound, fixture: "not_found.json"},
+		githubEnvs + "/contents/integration.yaml":                        {body: "name: Zabbix\ngo_jo:\n  min_version: v1.0.0\n"},
+		githubEnvs + "/zipball/8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c": {body: "zabbix archive"},
+		"/repos/org/missing/branches":                                    {status: http.StatusNotFound, fixture: "not_found.json"},
+	}
+}
+
+func newTestGitHubSource(server *forgeServer, envs string, maxPages int) *GitHubSource {
+	return NewGitHubSource(&domain.Config{
+		GitHubToken: "secret",
+		GitHub: domain.GitHubConfig{
+			APIBaseURL:   server.URL,
+			PerPage:      2,
+			MaxPages:     maxPages,
+			Repositories: domain.RepositoriesConfig{GoJo: "org/go-jo", DockerEnvironments: envs},
+		},
+	})
+}
+
+func TestNextPageURL(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{"empty", "", ""},
+		{"next and last", `<https://api.github.com/repositories/1/releases?page=2>; rel="next", <https://api.github.com/repositories/1/releases?page=5>; rel="last"`, "https://api.github.com/repositories/1/releases?page=2"},
+		{"next after prev", `<https://example.com/x?page=1>; rel="prev", <https://example.com/x?page=3>; rel="next"`, "https://example.com/x?page=3"},
+		{"last page", `<https://example.com/x?page=1>; rel="first", <https://example.com/x?page=4>; rel="prev"`, ""},
+		{"no spaces", `<https://example.com/x?page=2>;rel="next"`, "https://example.com/x?page=2"},
+		{"extra parameters", `<https://example.com/x?page=2>; title="page 2"; rel="next"`, "https://example.com/x?page=2"},
+		{"unquoted rel", `<https://example.com/x?page=2>; rel=next`, ""},
+		{"no parameters", `<https://example.com/x?page=2>`, ""},
+	}
+
+	for _, tt := range tests {
+		if got := nextPageURL(tt.link); got != tt.want {
+			t.Errorf("%s: nextPageURL = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGitHubListVersions(t *testing.T) {
+	server := newForgeServer(t, "github", githubRoutes())
+	s := newTestGitHubSource(server, "org/docker-environments", 0)
+
+	versions, err := s.ListVersions(context.Background())
+	if err != nil {
+		t.Fatalf("ListVersions error = %v", err)
+	}
+
+	// v1.2.0 is a draft and is left out
+	if len(versions) != 2 || versions[0].Version != "v1.1.0-rc.1" || versions[1].Version != "v1.0.0" {
+		t.Fatalf("ListVersions = %+v, want v1.1.0-rc.1 and v1.0.0", versions)
+	}
+	if !versions[0].Prerelease || versions[1].Prerelease {
+		t.Errorf("prerelease flags = %v, %v, want true, false", versions[0].Prerelease, versions[1].Prerelease)
+	}
+
+	// A release without a .deb has no app package
+	if versions[0].AppPackage != nil || len(versions[0].Assets) != 1 {
+		t.Errorf("v1.1.0-rc.1 = %+v, want its checksums and no app package", versions[0])
+	}
+
+	stable := versions[1]
+	if stable.Notes != "First release" || !stable.PublishedAt.Equal(time.Date(2025, 11, 20, 14, 0, 0, 0, time.UTC)) ||
+		len(stable.Assets) != 3 || stable.Assets[2] != (domain.Asset{Name: "go-jo_1.0.0_arm64.deb", Size: 1000}) {
+		t.Errorf("v1.0.0 = %+v", stable)
+	}
+
+	// The API package is skipped, and the first app package is picked
+	want := domain.Package{ID: "1002", Name: "go-jo_1.0.0_amd64.deb", Size: 1024}
+	if stable.AppPackage == nil || *stable.AppPackage != want {
+		t.Errorf("v1.0.0 app package = %+v, want %+v", stable.AppPackage, want)
+	}
+
+	first := server.received(githubGoJo + "/releases")
+	if len(first) != 1 || first[0].query != "per_page=2" || first[0].auth != "token secret" {
+		t.Errorf("first page requests = %+v, want per_page=2 with the token", first)
+	}
+	if second := server.received(githubGoJo + "/releases?page=2"); len(second) != 1 || second[0].query != "per_page=2&page=2" {
+		t.Errorf("second page requests = %+v, want one following the Link header", second)
+	}
+}
+
+func TestGitHubMaxPages(t *testing.T) {
+	server := newForgeServer(t, "github", githubRoutes())
+	s := newTestGitHubSource(server, "org/docker-environments", 1)
+
+	versions, err := s.ListVersions(context.Background())
+	if err != nil {
+		t.Fatalf("ListVersions error = %v", err)
+	}
+	if len(versions) != 1 || versions[0].Version != "v1.1.0-rc.1" {
+		t.Errorf("ListVersions = %+v, want the first page only", versions)
+	}
+	if second := server.received(githubGoJo + "/releases?page=2"); len(second) != 0 {
+		t.Errorf("second page requests = %+v, want none past max_pages", second)
+	}
+}
+
+func TestGitHubGetVersion(t *testing.T) {
+	server := newForgeServer(t, "github", githubRoutes())
+	s := newTestGitHubSource(server, "org/docker-environments", 0)
+	ctx := context.Background()
+
+	release, err := s.GetVersion(ctx, "v1.0.0")
+	if err != nil {
+		t.Fatalf("GetVersion error = %v", err)
+	}
+	if release.Version != "v1.0.0" || release.AppPackage == nil || release.AppPackage.ID != "1002" {
+		t.Errorf("GetVersion = %+v", release)
+	}
+	if _, err := s.GetVersion(ctx, "v9.9.9"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetVersion(v9.9.9) error = %v, want ErrNotFound", err)
+	}
+
+	commit, err := s.GetVersionCommit(ctx, "v1.0.0")
+	if err != nil || commit != "a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1" {
+		t.Errorf("GetVersionCommit = %q, %v", commit, err)
+	}
+
+	// GitHub answers 422 for a ref without a commit
+	if commit, err := s.GetVersionCommit(ctx, "v9.9.9"); err == nil {
+		t.Errorf("GetVersionCommit(v9.9.9) = %q, want an error", commit)
+	}
+}
+
+func TestGitHubIntegrations(t *testing.T) {
+	server := newForgeServer(t, "github", githubRoutes())
+	s := newTestGitHubSource(server, "org/docker-environments", 0)
+	ctx := context.Background()
+
+	integrations, err := s.ListIntegrations(ctx)
+	if err != nil {
+		t.Fatalf("ListIntegrations error = %v", err)
+	}
+	want := []domain.Integration{
+		{Name: "grafana", Commit: "6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a"},
+		{Name: "main", Commit: "7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b"},
+		{Name: "zabbix", Commit: "8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c"},
+	}
+	if len(integrations) != len(want) {
+		t.Fatalf("ListIntegrations = %+v, want %+v", integrations, want)
+	}
+	for i := range want {
+		if integrations[i] != want[i] {
+			t.Errorf("ListIntegrations[%d] = %+v, want %+v", i, integrations[i], want[i])
+		}
+	}
+
+	integration, err := s.GetIntegration(ctx, "zabbix")
+	if err != nil || *integration != want[2] {
+		t.Fatalf("GetIntegration(zabbix) = %+v, %v", integration, err)
+	}
+	if _, err := s.GetIntegration(ctx, "nginx"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetIntegration(nginx) error = %v, want ErrNotFound", err)
+	}
+
+	manifest, err := s.GetIntegrationManifest(ctx, integration)
+	if err != nil || manifest.Name != "Zabbix" || manifest.GoJo.MinVersion != "v1.0.0" {
+		t.Errorf("GetIntegrationManifest = %+v, %v", manifest, err)
+	}
+	if requests := server.received(githubEnvs + "/contents/integration.yaml"); len(requests) != 1 || requests[0].query != "ref="+want[2].Commit {
+		t.Errorf("manifest requests = %+v, want ref of the commit", requests)
+	}
+
+	missing := newTestGitHubSource(server, "org/missing", 0)
+	if _, err := missing.ListIntegrations(ctx); !errors.Is(err, ErrUpstreamUnauthorized) {
+		t.Errorf("ListIntegrations of a missing repository error = %v, want ErrUpstreamUnauthorized", err)
+	}
+}
+
+func TestGitHubDownloads(t *testing.T) {
+	server := newForgeServer(t, "github", githubRoutes())
+	s := newTestGitHubSource(server, "org/docker-environments", 0)
+	ctx := context.Background()
+
+	read := func(body io.ReadCloser, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("download error = %v", err)
+		}
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatalf("reading download: %v", err)
+		}
+		return string(data)
+	}
+
+	if got := read(s.FetchAppPackage(ctx, &domain.Package{ID: "1002"})); got != "go-jo 1.0.0" {
+		t.Errorf("FetchAppPackage = %q", got)
+	}
+	if requests := server.received(githubGoJo + "/releases/assets/1002"); len(requests) != 1 || requests[0].auth != "token secret" {
+		t.Errorf("asset requests = %+v, want the token", requests)
+	}
+
+	integration := &domain.Integration{Name: "zabbix", Commit: "8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c"}
+	if got := read(s.FetchIntegrationArchive(ctx, integration)); got != "zabbix archive" {
+		t.Errorf("FetchIntegrationArchive = %q", got)
+	}
+
+	if _, err := s.FetchAppPackage(ctx, &domain.Package{ID: "9999"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FetchAppPackage of a missing asset error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,207 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/semver"
+)
+
+// GitLabSource serves releases of the go-jo project and branches of the
+// docker-environments project through the GitLab v4 API
+type GitLabSource struct {
+	config domain.RemoteSourceConfig
+	api    *restClient
+}
+
+// NewGitLabSource creates a new GitLab artifact source
+func NewGitLabSource(config *domain.Config) (*GitLabSource, error) {
+	gitlab := config.Source.GitLab
+	if err := validateRemoteConfig("gitlab", gitlab); err != nil {
+		return nil, err
+	}
+
+	return &GitLabSource{
+		config: gitlab,
+		api: &restClient{
+			name:    "GitLab API",
+			baseURL: gitlab.BaseURL,
+			client:  newUpstreamClient(TypeGitLab),
+			authorize: func(req *http.Request) {
+				if gitlab.Token != "" {
+					req.Header.Set("PRIVATE-TOKEN", gitlab.Token)
+				}
+			},
+			accept:          "application/json",
+			pageSizeParam:   "per_page",
+			perPage:         gitlab.GetPerPage(),
+			maxPages:        gitlab.GetMaxPages(),
+			timeout:         config.GetRequestTimeout(),
+			downloadTimeout: config.GetSourceDownloadTimeout(),
+		},
+	}, nil
+}
+
+// Name identifies the backend in logs
+func (s *GitLabSource) Name() string {
+	return fmt.Sprintf("gitlab %s (%s, %s)", s.config.BaseURL, s.config.Repositories.GoJo, s.config.Repositories.DockerEnvironments)
+}
+
+// ListVersions returns every released release of the go-jo project
+func (s *GitLabSource) ListVersions(ctx context.Context) ([]domain.Release, error) {
+	releases, err := fetchAll[domain.GitLabRelease](ctx, s.api, s.projectURL(s.config.Repositories.GoJo, "releases"))
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []domain.Release
+	for _, release := range releases {
+		if !release.UpcomingRelease {
+			versions = append(versions, toGitLabRelease(&release))
+		}
+	}
+	return versions, nil
+}
+
+// GetVersion returns the release of a tag with its app package
+func (s *GitLabSource) GetVersion(ctx context.Context, version string) (*domain.Release, error) {
+	var release domain.GitLabRelease
+	if err := s.api.fetchJSON(ctx, s.projectURL(s.config.Repositories.GoJo, "releases", version), &release); err != nil {
+		return nil, notFound(err, "version", version)
+	}
+	if release.UpcomingRelease {
+		return nil, fmt.Errorf("version %s %w (not released yet)", version, ErrNotFound)
+	}
+
+	result := toGitLabRelease(&release)
+	return &result, nil
+}
+
+// GetVersionCommit returns the commit of a release, which GitLab includes
+// in the release itself
+func (s *GitLabSource) GetVersionCommit(ctx context.Context, version string) (string, error) {
+	var release domain.GitLabRelease
+	if err := s.api.fetchJSON(ctx, s.projectURL(s.config.Repositories.GoJo, "releases", version), &release); err != nil {
+		return "", notFound(err, "version", version)
+	}
+	return release.Commit.ID, nil
+}
+
+// ListIntegrations returns the branches of the docker-environments project
+func (s *GitLabSource) ListIntegrations(ctx context.Context) ([]domain.Integration, error) {
+	branches, err := fetchAll[domain.GitLabBranch](ctx, s.api, s.projectURL(s.config.Repositories.DockerEnvironments, "repository", "branches"))
+	if err != nil {
+		return nil, err
+	}
+
+	integrations := make([]domain.Integration, 0, len(branches))
+	for _, branch := range branches {
+		integrations = append(integrations, domain.Integration{Name: branch.Name, Commit: branch.Commit.ID})
+	}
+	return integrations, nil
+}
+
+// GetIntegration resolves a branch to its head commit
+func (s *GitLabSource) GetIntegration(ctx context.Context, name string) (*domain.Integration, error) {
+	var branch domain.GitLabBranch
+	if err := s.api.fetchJSON(ctx, s.projectURL(s.config.Repositories.DockerEnvironments, "repository", "branches", name), &branch); err != nil {
+		return nil, notFound(err, "integration", name)
+	}
+	if branch.Commit.ID == "" {
+		return nil, fmt.Errorf("branch %s has no commit", name)
+	}
+	return &domain.Integration{Name: name, Commit: branch.Commit.ID}, nil
+}
+
+// GetIntegrationManifest reads the raw integration.yaml of the integration commit
+func (s *GitLabSource) GetIntegrationManifest(ctx context.Context, integration *domain.Integration) (*domain.IntegrationManifest, error) {
+	manifestURL := s.projectURL(s.config.Repositories.DockerEnvironments, "repository", "files", manifestFile, "raw") + "?ref=" + url.QueryEscape(integration.Commit)
+
+	data, err := s.api.fetchRaw(ctx, manifestURL, "", maxManifestSize)
+	if err != nil {
+		return nil, manifestNotFound(err, integration)
+	}
+	return parseManifest(data)
+}
+
+// FetchAppPackage downloads the release asset link
+func (s *GitLabSource) FetchAppPackage(ctx context.Context, pkg *domain.Package) (io.ReadCloser, error) {
+	return s.api.download(ctx, pkg.Location, "")
+}
+
+// FetchIntegrationArchive downloads the zip archive of the integration commit
+func (s *GitLabSource) FetchIntegrationArchive(ctx context.Context, integration *domain.Integration) (io.ReadCloser, error) {
+	archiveURL := s.projectURL(s.config.Repositories.DockerEnvironments, "repository", "archive.zip") + "?sha=" + url.QueryEscape(integration.Commit)
+	return s.api.download(ctx, archiveURL, "")
+}
+
+// Check verifies GitLab is reachable and both projects are accessible with the token
+func (s *GitLabSource) Check(ctx context.Context) error {
+	for _, project := range []string{s.config.Repositories.GoJo, s.config.Repositories.DockerEnvironments} {
+		var result struct {
+			ID int `json:"id"`
+		}
+		if err := s.api.fetchJSON(ctx, s.projectURL(project), &result); err != nil {
+			return fmt.Errorf("project %s: %w", project, err)
+		}
+	}
+	return nil
+}
+
+// projectURL builds an API URL below a project, escaping every path segment
+func (s *GitLabSource) projectURL(project string, segments ...string) string {
+	parts := []string{strings.TrimRight(s.config.BaseURL, "/"), "api/v4/projects", url.PathEscape(project)}
+	for _, segment := range segments {
+		parts = append(parts, url.PathEscape(segment))
+	}
+	return strings.Join(parts, "/")
+}
+
+// toGitLabRelease converts a GitLab release, picking the go-jo .deb among its
+// asset links. GitLab has no prerelease flag, so it is derived from the tag.
+func toGitLabRelease(release *domain.GitLabRelease) domain.Release {
+	result := domain.Release{
+		Version:    release.TagName,
+		Prerelease: semver.IsPrereleaseString(release.TagName),
+		Notes:      release.Description,
+	}
+	if release.ReleasedAt != nil {
+		result.PublishedAt = *release.ReleasedAt
+	}
+
+	// Asset links don't report their size
+	for _, link := range release.Assets.Links {
+		result.Assets = append(result.Assets, domain.Asset{Name: link.Name})
+
+		if result.AppPackage == nil && strings.HasSuffix(link.Name, ".deb") && !strings.Contains(link.Name, "api") {
+			location := link.DirectAssetURL
+			if location == "" {
+				location = link.URL
+			}
+
+			result.AppPackage = &domain.Package{
+				ID:       "gitlab-" + strconv.Itoa(link.ID),
+				Name:     link.Name,
+				Location: location,
+			}
+		}
+	}
+	return result
+}
+
+// validateRemoteConfig checks that a forge source has everything it needs
+func validateRemoteConfig(name string, config domain.RemoteSourceConfig) error {
+	if config.BaseURL == "" {
+		return fmt.Errorf("source.%s.base_url is required", name)
+	}
+	if config.Repositories.GoJo == "" || config.Repositories.DockerEnvironments == "" {
+		return fmt.Errorf("source.%s.repositories.go_jo and docker_environments are required", name)
+	}
+	return nil
+}
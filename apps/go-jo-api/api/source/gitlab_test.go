@@ -0,0 +1,246 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+)
+
+const (
+	gitLabProjects = "/api/v4/projects/group%2Fgo-jo"
+	gitLabEnvs     = "/api/v4/projects/group%2Fdocker-environments"
+)
+
+// gitLabRoutes replays the GitLab v4 responses recorded in testdata/gitlab.
+// Releases are paginated with X-Next-Page only, branches with a Link header.
+func gitLabRoutes() map[string]route {
+	return map[string]route{
+		gitLabProjects + "/releases": {fixture: "releases_page1.json", header: map[string]string{
+			"X-Page": "1", "X-Next-Page": "2", "X-Per-Page": "2",
+		}},
+		gitLabProjects + "/releases?page=2": {fixture: "releases_page2.json", header: map[string]string{
+			"X-Page": "2", "X-Next-Page": "", "X-Per-Page": "2",
+		}},
+		gitLabProjects + "/releases/v1.1.0": {fixture: "release_v1.1.0.json"},
+		gitLabProjects + "/releases/v1.2.0": {fixture: "release_v1.2.0.json"},
+		gitLabProjects + "/releases/v9.9.9": {status: http.StatusNotFound, fixture: "not_found.json"},
+		gitLabEnvs + "/repository/branches": {fixture: "branches_page1.json", header: map[string]string{
+			"Link": `<{{server}}` + gitLabEnvs + `/repository/branches?page=2&per_page=2>; rel="next", <{{server}}` + gitLabEnvs + `/repository/branches?page=2&per_page=2>; rel="last"`,
+		}},
+		gitLabEnvs + "/repository/branches?page=2": {fixture: "branches_page2.json", header: map[string]string{
+			"Link": `<{{server}}` + gitLabEnvs + `/repository/branches?page=1&per_page=2>; rel="first"`,
+		}},
+		gitLabEnvs + "/repository/branches/zabbix":                       {fixture: "branch_zabbix.json"},
+		gitLabEnvs + "/repository/branches/nginx":                        {status: http.StatusNotFound, fixture: "branch_not_found.json"},
+		gitLabEnvs + "/repository/archive.zip":                           {body: "zabbix archive"},
+		"/group/go-jo/-/releases/v1.1.0/downloads/go-jo_1.1.0_amd64.deb": {body: "go-jo 1.1.0"},
+		"/api/v4/projects/group%2Fmissing/repository/branches":           {status: http.StatusNotFound, fixture: "not_found.json"},
+		gitLabEnvs + "/repository/files/integration.yaml/raw":            {body: "name: Zabbix\nports: [10051]\n"},
+		"/api/v4/projects/group%2Fgo-jo":                                 {body: `{"id": 1}`},
+		"/api/v4/projects/group%2Fdocker-environments":                   {body: `{"id": 2}`},
+		"/api/v4/projects/group%2Fmissing":                               {status: http.StatusNotFound, fixture: "not_found.json"},
+		"/uploads/gone.deb":                                              {status: http.StatusNotFound, fixture: "not_found.json"},
+	}
+}
+
+func newTestGitLabSource(t *testing.T, server *forgeServer, envs string) *GitLabSource {
+	t.Helper()
+	s, err := NewGitLabSource(&domain.Config{Source: domain.SourceConfig{GitLab: domain.RemoteSourceConfig{
+		BaseURL:      server.URL,
+		Token:        "secret",
+		PerPage:      2,
+		Repositories: domain.RepositoriesConfig{GoJo: "group/go-jo", DockerEnvironments: envs},
+	}}})
+	if err != nil {
+		t.Fatalf("NewGitLabSource error = %v", err)
+	}
+	return s
+}
+
+func TestGitLabListVersions(t *testing.T) {
+	server := newForgeServer(t, "gitlab", gitLabRoutes())
+	external := newForgeServer(t, "gitlab", nil)
+	server.replace("{{external}}", external.URL)
+	s := newTestGitLabSource(t, server, "group/docker-environments")
+
+	versions, err := s.ListVersions(context.Background())
+	if err != nil {
+		t.Fatalf("ListVersions error = %v", err)
+	}
+
+	// v1.2.0 is an upcoming release and is left out
+	if len(versions) != 2 || versions[0].Version != "v1.1.0" || versions[1].Version != "v1.0.0" {
+		t.Fatalf("ListVersions = %+v, want v1.1.0 and v1.0.0", versions)
+	}
+
+	latest := versions[0]
+	if latest.Prerelease || latest.Notes != "## Changes\n\n- Grafana integration" ||
+		!latest.PublishedAt.Equal(time.Date(2026, 2, 10, 9, 30, 0, 0, time.UTC)) || len(latest.Assets) != 2 {
+		t.Errorf("v1.1.0 = %+v", latest)
+	}
+	want := domain.Package{
+		ID:       "gitlab-12",
+		Name:     "go-jo_1.1.0_amd64.deb",
+		Location: server.URL + "/group/go-jo/-/releases/v1.1.0/downloads/go-jo_1.1.0_amd64.deb",
+	}
+	if latest.AppPackage == nil || *latest.AppPackage != want {
+		t.Errorf("v1.1.0 app package = %+v, want %+v", latest.AppPackage, want)
+	}
+
+	// Links without a direct asset URL fall back to their URL
+	if pkg := versions[1].AppPackage; pkg == nil || pkg.Location != external.URL+"/files/go-jo_1.0.0_amd64.deb" {
+		t.Errorf("v1.0.0 app package = %+v, want the link url", pkg)
+	}
+
+	first := server.received(gitLabProjects + "/releases")
+	if len(first) != 1 || first[0].query != "per_page=2" || first[0].auth != "secret" {
+		t.Errorf("first page requests = %+v, want per_page=2 with the token", first)
+	}
+	if second := server.received(gitLabProjects + "/releases?page=2"); len(second) != 1 {
+		t.Errorf("second page requests = %+v, want one following X-Next-Page", second)
+	}
+}
+
+func TestGitLabGetVersion(t *testing.T) {
+	server := newForgeServer(t, "gitlab", gitLabRoutes())
+	s := newTestGitLabSource(t, server, "group/docker-environments")
+
+	tests := []struct {
+		version string
+		wantErr error
+	}{
+		{"v1.1.0", nil},
+		{"v1.2.0", ErrNotFound},
+		{"v9.9.9", ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			release, err := s.GetVersion(context.Background(), tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetVersion error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (release.Version != tt.version || release.AppPackage == nil) {
+				t.Errorf("GetVersion = %+v", release)
+			}
+		})
+	}
+
+	commit, err := s.GetVersionCommit(context.Background(), "v1.1.0")
+	if err != nil || commit != "b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2" {
+		t.Errorf("GetVersionCommit = %q, %v", commit, err)
+	}
+	if _, err := s.GetVersionCommit(context.Background(), "v9.9.9"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetVersionCommit error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestGitLabIntegrations(t *testing.T) {
+	server := newForgeServer(t, "gitlab", gitLabRoutes())
+	s := newTestGitLabSource(t, server, "group/docker-environments")
+	ctx := context.Background()
+
+	integrations, err := s.ListIntegrations(ctx)
+	if err != nil {
+		t.Fatalf("ListIntegrations error = %v", err)
+	}
+	want := []domain.Integration{
+		{Name: "grafana", Commit: "6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a6a"},
+		{Name: "main", Commit: "7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b7b"},
+		{Name: "zabbix", Commit: "8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c"},
+	}
+	if len(integrations) != len(want) {
+		t.Fatalf("ListIntegrations = %+v, want %+v", integrations, want)
+	}
+	for i := range want {
+		if integrations[i] != want[i] {
+			t.Errorf("ListIntegrations[%d] = %+v, want %+v", i, integrations[i], want[i])
+		}
+	}
+
+	integration, err := s.GetIntegration(ctx, "zabbix")
+	if err != nil || *integration != want[2] {
+		t.Errorf("GetIntegration(zabbix) = %+v, %v", integration, err)
+	}
+	if _, err := s.GetIntegration(ctx, "nginx"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetIntegration(nginx) error = %v, want ErrNotFound", err)
+	}
+
+	manifest, err := s.GetIntegrationManifest(ctx, integration)
+	if err != nil || manifest.Name != "Zabbix" {
+		t.Errorf("GetIntegrationManifest = %+v, %v", manifest, err)
+	}
+	if requests := server.received(gitLabEnvs + "/repository/files/integration.yaml/raw"); len(requests) != 1 ||
+		requests[0].query != "ref="+want[2].Commit {
+		t.Errorf("manifest requests = %+v, want ref of the commit", requests)
+	}
+
+	// A project hidden from the token answers 404
+	missing := newTestGitLabSource(t, server, "group/missing")
+	if _, err := missing.ListIntegrations(ctx); !errors.Is(err, ErrUpstreamUnauthorized) {
+		t.Errorf("ListIntegrations of a missing project error = %v, want ErrUpstreamUnauthorized", err)
+	}
+	if err := missing.Check(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Check of a missing project error = %v, want ErrNotFound", err)
+	}
+	if err := s.Check(ctx); err != nil {
+		t.Errorf("Check error = %v", err)
+	}
+}
+
+func TestGitLabDownloads(t *testing.T) {
+	server := newForgeServer(t, "gitlab", gitLabRoutes())
+	external := newForgeServer(t, "gitlab", map[string]route{
+		"/files/go-jo_1.0.0_amd64.deb": {body: "go-jo 1.0.0"},
+	})
+	s := newTestGitLabSource(t, server, "group/docker-environments")
+	ctx := context.Background()
+
+	read := func(body io.ReadCloser, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("download error = %v", err)
+		}
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatalf("reading download: %v", err)
+		}
+		return string(data)
+	}
+
+	asset := server.URL + "/group/go-jo/-/releases/v1.1.0/downloads/go-jo_1.1.0_amd64.deb"
+	if got := read(s.FetchAppPackage(ctx, &domain.Package{Location: asset})); got != "go-jo 1.1.0" {
+		t.Errorf("FetchAppPackage = %q", got)
+	}
+	if requests := server.received("/group/go-jo/-/releases/v1.1.0/downloads/go-jo_1.1.0_amd64.deb"); len(requests) != 1 || requests[0].auth != "secret" {
+		t.Errorf("asset requests = %+v, want the token", requests)
+	}
+
+	// The token is never sent to external asset links
+	if got := read(s.FetchAppPackage(ctx, &domain.Package{Location: external.URL + "/files/go-jo_1.0.0_amd64.deb"})); got != "go-jo 1.0.0" {
+		t.Errorf("FetchAppPackage(external) = %q", got)
+	}
+	if requests := external.received("/files/go-jo_1.0.0_amd64.deb"); len(requests) != 1 || requests[0].auth != "" {
+		t.Errorf("external asset requests = %+v, want no token", requests)
+	}
+
+	integration := &domain.Integration{Name: "zabbix", Commit: "8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c"}
+	if got := read(s.FetchIntegrationArchive(ctx, integration)); got != "zabbix archive" {
+		t.Errorf("FetchIntegrationArchive = %q", got)
+	}
+	if requests := server.received(gitLabEnvs + "/repository/archive.zip"); len(requests) != 1 ||
+		requests[0].query != "sha="+url.QueryEscape(integration.Commit) {
+		t.Errorf("archive requests = %+v, want the sha of the commit", requests)
+	}
+
+	if _, err := s.FetchAppPackage(ctx, &domain.Package{Location: server.URL + "/uploads/gone.deb"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FetchAppPackage of a missing asset error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,463 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/metrics"
+)
+
+// cancelOnClose releases a request context once the streamed body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels its request context
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// nextPageURL extracts the rel="next" URL from a Link header
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, found := strings.Cut(part, ";")
+		if !found {
+			continue
+		}
+
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// withQueryParam returns the URL with a query parameter set
+func withQueryParam(rawURL, key, value string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := parsed.Query()
+	query.Set(key, value)
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// Limits of the upstream client
+const (
+	// maxResponseSize bounds API responses kept in memory
+	maxResponseSize = 32 * 1024 * 1024
+	// maxETagEntries bounds the responses kept for conditional requests
+	maxETagEntries = 1024
+	// maxRateLimitRetries is the number of times a request hitting a
+	// secondary rate limit is retried
+	maxRateLimitRetries = 2
+	// maxBackoff is the longest wait before retrying a rate limited request;
+	// longer waits are reported to the caller instead
+	maxBackoff = 30 * time.Second
+	// defaultBackoff is used when a rate limited response doesn't say how long to wait
+	defaultBackoff = time.Minute
+)
+
+// upstreamTransport is shared by every upstream client so connections to
+// the same host are kept alive and reused across requests
+var upstreamTransport = newUpstreamTransport()
+
+func newUpstreamTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 16
+	return transport
+}
+
+// newUpstreamClient returns an HTTP client on the shared transport, counted
+// and timed under the given upstream name
+func newUpstreamClient(upstream string) *http.Client {
+	return &http.Client{Transport: metrics.NewTransport(upstream, upstreamTransport)}
+}
+
+// RateLimitError reports that an upstream refuses requests until its rate
+// limit resets
+type RateLimitError struct {
+	Upstream   string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s rate limit exceeded, retry in %s", e.Upstream, e.RetryAfter.Round(time.Second))
+}
+
+// restClient makes authenticated requests to the REST API of a forge
+// (GitHub, GitLab, Gitea). Successful responses with an ETag are kept, so
+// repeated requests are conditional and a 304 is answered from the kept copy.
+// Once the upstream refuses requests because of its rate limit, further
+// requests fail fast with a RateLimitError until the limit resets.
+type restClient struct {
+	name      string
+	baseURL   string
+	client    *http.Client
+	authorize func(req *http.Request)
+	// accept is the Accept header of API requests
+	accept string
+	// pageSizeParam is the query parameter holding the page size
+	pageSizeParam   string
+	perPage         int
+	maxPages        int
+	timeout         time.Duration
+	downloadTimeout time.Duration
+
+	mu           sync.Mutex
+	etags        map[string]*etagEntry
+	blockedUntil time.Time
+}
+
+// etagEntry is a response kept to answer conditional requests
+type etagEntry struct {
+	etag   string
+	body   []byte
+	header http.Header
+}
+
+// fetchAll fetches every page of a listing by following the Link header, or
+// the X-Next-Page header GitLab sends when it leaves the Link header out.
+// At most maxPages pages are fetched.
+func fetchAll[T any](ctx context.Context, c *restClient, listURL string) ([]T, error) {
+	next, err := withQueryParam(listURL, c.pageSizeParam, strconv.Itoa(c.perPage))
+	if err != nil {
+		return nil, err
+	}
+
+	var all []T
+	for page := 1; next != ""; page++ {
+		if page > c.maxPages {
+			log.Printf("Stopping after %d pages of %s (max_pages reached)", c.maxPages, listURL)
+			break
+		}
+
+		var items []T
+		header, err := c.fetchPage(ctx, next, &items)
+		if errors.Is(err, ErrNotFound) {
+			// Forges answer 404 for repositories hidden from the token
+			return nil, fmt.Errorf("%w: %s not found or not accessible", ErrUpstreamUnauthorized, listURL)
+		}
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+
+		if link := nextPageURL(header.Get("Link")); link != "" {
+			next = link
+		} else if nextPage := header.Get("X-Next-Page"); nextPage != "" {
+			if next, err = withQueryParam(next, "page", nextPage); err != nil {
+				return nil, err
+			}
+		} else {
+			next = ""
+		}
+	}
+
+	return all, nil
+}
+
+// fetchJSON makes an authenticated request to the API
+func (c *restClient) fetchJSON(ctx context.Context, url string, result interface{}) error {
+	_, err := c.fetchPage(ctx, url, result)
+	return err
+}
+
+// fetchCurrent makes an unconditional request to the API, for responses that
+// must not be answered from a kept copy
+func (c *restClient) fetchCurrent(ctx context.Context, url string, result interface{}) error {
+	body, _, err := c.get(ctx, url, false)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, result)
+}
+
+// fetchPage makes an authenticated request to the API and returns the response headers
+func (c *restClient) fetchPage(ctx context.Context, url string, result interface{}) (http.Header, error) {
+	body, header, err := c.get(ctx, url, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// fetchRaw makes an unconditional request for a raw file of at most limit
+// bytes, bounded by the API timeout rather than the download one. An empty
+// accept leaves the Accept header out.
+func (c *restClient) fetchRaw(ctx context.Context, url, accept string, limit int) ([]byte, error) {
+	body, _, err := c.request(ctx, url, accept, limit, false)
+	return body, err
+}
+
+// get makes an authenticated request to the API, conditional when a copy of
+// the response is kept
+func (c *restClient) get(ctx context.Context, url string, conditional bool) ([]byte, http.Header, error) {
+	return c.request(ctx, url, c.accept, maxResponseSize, conditional)
+}
+
+// request makes an authenticated request of at most limit bytes. Requests
+// hitting a short secondary rate limit are retried after the delay the
+// upstream asks for.
+func (c *restClient) request(ctx context.Context, url, accept string, limit int, conditional bool) ([]byte, http.Header, error) {
+	if err := c.checkBlocked(); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		c.authorize(req)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		var kept *etagEntry
+		if conditional {
+			if kept = c.keptResponse(url); kept != nil {
+				req.Header.Set("If-None-Match", kept.etag)
+			}
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, nil, c.unavailable(err)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, c.unavailable(err)
+		}
+		if len(body) > limit {
+			return nil, nil, fmt.Errorf("%s response of %s is larger than %d bytes", c.name, url, limit)
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusNotModified && kept != nil:
+			return kept.body, kept.header, nil
+		case resp.StatusCode == http.StatusOK:
+			if conditional {
+				c.keepResponse(url, resp.Header, body)
+			}
+			return body, resp.Header, nil
+		}
+
+		wait, limited := rateLimitWait(resp, body)
+		if !limited {
+			return nil, nil, c.statusError(resp.StatusCode)
+		}
+
+		if attempt < maxRateLimitRetries && wait <= maxBackoff && fitsDeadline(ctx, wait) {
+			log.Printf("%s rate limit hit, retrying %s in %s", c.name, url, wait)
+			if err := sleep(ctx, wait); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		return nil, nil, c.block(wait)
+	}
+}
+
+// statusError wraps an unexpected response status in the matching source error
+func (c *restClient) statusError(status int) error {
+	switch {
+	case status == http.StatusNotFound:
+		return fmt.Errorf("%w (%s answered %d)", ErrNotFound, c.name, status)
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return fmt.Errorf("%w: %s answered %d", ErrUpstreamUnauthorized, c.name, status)
+	case status >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: %s answered %d", ErrUpstreamUnavailable, c.name, status)
+	default:
+		return fmt.Errorf("%s error: %d", c.name, status)
+	}
+}
+
+// unavailable wraps a failed request to the upstream
+func (c *restClient) unavailable(err error) error {
+	return fmt.Errorf("%w: %s: %v", ErrUpstreamUnavailable, c.name, err)
+}
+
+// keptResponse returns the response kept for a URL, if any
+func (c *restClient) keptResponse(url string) *etagEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.etags[url]
+}
+
+// keepResponse keeps a response with an ETag for conditional requests
+func (c *restClient) keepResponse(url string, header http.Header, body []byte) {
+	etag := header.Get("ETag")
+	if etag == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.etags == nil {
+		c.etags = make(map[string]*etagEntry)
+	}
+	if _, exists := c.etags[url]; !exists && len(c.etags) >= maxETagEntries {
+		// Drop an arbitrary entry, its next request is simply unconditional
+		for key := range c.etags {
+			delete(c.etags, key)
+			break
+		}
+	}
+	c.etags[url] = &etagEntry{etag: etag, body: body, header: header.Clone()}
+}
+
+// checkBlocked fails fast while the upstream rate limit is exhausted
+func (c *restClient) checkBlocked() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if wait := time.Until(c.blockedUntil); wait > 0 {
+		return &RateLimitError{Upstream: c.name, RetryAfter: wait}
+	}
+	return nil
+}
+
+// block refuses requests for the given time and returns the matching error
+func (c *restClient) block(wait time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if until := time.Now().Add(wait); until.After(c.blockedUntil) {
+		c.blockedUntil = until
+	}
+	log.Printf("%s rate limit exhausted, refusing requests for %s", c.name, wait.Round(time.Second))
+	return &RateLimitError{Upstream: c.name, RetryAfter: wait}
+}
+
+// rateLimitWait reports whether a response was refused by a rate limit and
+// how long to wait. Primary limits set X-RateLimit-Remaining to 0 and say
+// when they reset; secondary limits send Retry-After or only explain
+// themselves in the body.
+func rateLimitWait(resp *http.Response, body []byte) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			if wait := time.Until(time.Unix(reset, 0)); wait > 0 {
+				return wait, true
+			}
+			return time.Second, true
+		}
+		return defaultBackoff, true
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || strings.Contains(strings.ToLower(string(body)), "rate limit") {
+		return defaultBackoff, true
+	}
+	return 0, false
+}
+
+// fitsDeadline reports whether waiting still leaves time before the context deadline
+func fitsDeadline(ctx context.Context, wait time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > wait
+}
+
+// sleep waits for the given time unless the context is done first
+func sleep(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// download starts a download and returns the response body. Credentials are
+// only sent to the API host, never to external asset links. An empty accept
+// leaves the Accept header out.
+func (c *restClient) download(ctx context.Context, rawURL, accept string) (io.ReadCloser, error) {
+	if err := c.checkBlocked(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.downloadTimeout)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if sameHost(rawURL, c.baseURL) {
+		c.authorize(req)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, c.unavailable(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		cancel()
+		if wait, limited := rateLimitWait(resp, body); limited {
+			return nil, c.block(wait)
+		}
+		return nil, fmt.Errorf("download failed: %w", c.statusError(resp.StatusCode))
+	}
+
+	return &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}, nil
+}
+
+// sameHost reports whether two URLs point to the same scheme and host
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Scheme == ub.Scheme && ua.Host == ub.Host
+}
@@ -0,0 +1,365 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a REST client of the test server
+func newTestClient(server *httptest.Server) *restClient {
+	return &restClient{
+		name:    "Test API",
+		baseURL: server.URL,
+		client:  server.Client(),
+		authorize: func(req *http.Request) {
+			req.Header.Set("Authorization", "token secret")
+		},
+		accept:          "application/json",
+		pageSizeParam:   "per_page",
+		perPage:         2,
+		maxPages:        10,
+		timeout:         5 * time.Second,
+		downloadTimeout: 5 * time.Second,
+	}
+}
+
+func TestRestClientETag(t *testing.T) {
+	var requests atomic.Int32
+	var conditional atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Link", `<`+r.URL.Path+`?page=2>; rel="next"`)
+		fmt.Fprint(w, `{"version": "v1.0.0"}`)
+	}))
+	defer server.Close()
+	c := newTestClient(server)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		body, header, err := c.get(ctx, server.URL+"/releases", true)
+		if err != nil {
+			t.Fatalf("get %d error = %v", i, err)
+		}
+		// A 304 answers with the kept body and headers
+		if string(body) != `{"version": "v1.0.0"}` || header.Get("Link") == "" {
+			t.Errorf("get %d = %s, %v", i, body, header)
+		}
+	}
+	if requests.Load() != 3 || conditional.Load() != 2 {
+		t.Errorf("requests = %d with %d conditional, want 3 with 2", requests.Load(), conditional.Load())
+	}
+
+	// Unconditional requests neither send nor need the kept ETag
+	if _, _, err := c.get(ctx, server.URL+"/rate_limit", false); err != nil {
+		t.Fatalf("unconditional get error = %v", err)
+	}
+	if conditional.Load() != 2 || c.keptResponse(server.URL+"/rate_limit") != nil {
+		t.Error("unconditional request was conditional or kept")
+	}
+}
+
+func TestRestClientKeepResponse(t *testing.T) {
+	c := &restClient{}
+
+	// Responses without an ETag can't be revalidated
+	c.keepResponse("/no-etag", http.Header{}, []byte("body"))
+	if c.keptResponse("/no-etag") != nil {
+		t.Error("kept a response without an ETag")
+	}
+
+	header := http.Header{"Etag": {`"a"`}}
+	c.keepResponse("/a", header, []byte("first"))
+	header.Set("Etag", `"changed"`)
+	if kept := c.keptResponse("/a"); kept == nil || kept.etag != `"a"` || kept.header.Get("Etag") != `"a"` {
+		t.Errorf("kept = %+v, want a copy of the header", kept)
+	}
+
+	c.keepResponse("/a", http.Header{"Etag": {`"b"`}}, []byte("second"))
+	if kept := c.keptResponse("/a"); string(kept.body) != "second" {
+		t.Errorf("kept body = %s, want the newer response", kept.body)
+	}
+
+	for i := 0; i < maxETagEntries+10; i++ {
+		c.keepResponse("/"+strconv.Itoa(i), http.Header{"Etag": {`"x"`}}, nil)
+	}
+	if len(c.etags) != maxETagEntries {
+		t.Errorf("kept %d responses, want at most %d", len(c.etags), maxETagEntries)
+	}
+}
+
+func TestRestClientRateLimit(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"forbidden", http.StatusForbidden},
+		{"too many requests", http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+			}))
+			defer server.Close()
+			c := newTestClient(server)
+			ctx := context.Background()
+
+			// A wait past maxBackoff is reported rather than retried
+			_, _, err := c.get(ctx, server.URL+"/releases", true)
+			var rateLimitErr *RateLimitError
+			if !errors.As(err, &rateLimitErr) || rateLimitErr.Upstream != "Test API" ||
+				rateLimitErr.RetryAfter < 59*time.Minute || rateLimitErr.RetryAfter > time.Hour {
+				t.Fatalf("get error = %v, want a RateLimitError until the reset", err)
+			}
+
+			// Later calls fail fast until the reset
+			if _, _, err := c.get(ctx, server.URL+"/branches", true); !errors.As(err, &rateLimitErr) {
+				t.Errorf("blocked get error = %v, want a RateLimitError", err)
+			}
+			if _, err := c.download(ctx, server.URL+"/assets/1", ""); !errors.As(err, &rateLimitErr) {
+				t.Errorf("blocked download error = %v, want a RateLimitError", err)
+			}
+			if requests.Load() != 1 {
+				t.Errorf("requests = %d, want none while blocked", requests.Load())
+			}
+
+			// Once the limit reset, requests reach the upstream again
+			c.mu.Lock()
+			c.blockedUntil = time.Now().Add(-time.Second)
+			c.mu.Unlock()
+			if err := c.checkBlocked(); err != nil {
+				t.Errorf("checkBlocked after the reset error = %v", err)
+			}
+			c.get(ctx, server.URL+"/releases", true)
+			if requests.Load() != 2 {
+				t.Errorf("requests = %d, want one after the reset", requests.Load())
+			}
+		})
+	}
+}
+
+func TestRestClientSecondaryRateLimit(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "You have exceeded a secondary rate limit"}`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+	c := newTestClient(server)
+
+	// A short wait is retried within the same call
+	body, _, err := c.get(context.Background(), server.URL+"/releases", true)
+	if err != nil || string(body) != "[]" || requests.Load() != 2 {
+		t.Errorf("get = %s, %v after %d requests, want the retried response", body, err, requests.Load())
+	}
+	if err := c.checkBlocked(); err != nil {
+		t.Errorf("checkBlocked error = %v, want none after a retry", err)
+	}
+}
+
+func TestRestClientBlockKeepsLongestWait(t *testing.T) {
+	c := &restClient{name: "Test API"}
+	c.block(time.Hour)
+	c.block(time.Minute)
+
+	var rateLimitErr *RateLimitError
+	if err := c.checkBlocked(); !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter <= time.Minute {
+		t.Errorf("checkBlocked error = %v, want the longer block", err)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		status      int
+		header      map[string]string
+		body        string
+		wantLimited bool
+		wantWait    time.Duration
+	}{
+		{"server error", http.StatusInternalServerError, map[string]string{"Retry-After": "5"}, "", false, 0},
+		{"not found", http.StatusNotFound, map[string]string{"X-RateLimit-Remaining": "0"}, "", false, 0},
+		{"forbidden", http.StatusForbidden, nil, `{"message": "Resource not accessible by integration"}`, false, 0},
+		{"quota left", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "12"}, "", false, 0},
+		{"retry after", http.StatusForbidden, map[string]string{"Retry-After": "7"}, "", true, 7 * time.Second},
+		{"retry after zero", http.StatusTooManyRequests, map[string]string{"Retry-After": "0"}, "", true, 0},
+		{"retry after date", http.StatusTooManyRequests, map[string]string{"Retry-After": "Wed, 21 Oct 2026 07:28:00 GMT"}, "", true, defaultBackoff},
+		{"reset ahead", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(now.Add(10*time.Minute).Unix(), 10)}, "", true, 10 * time.Minute},
+		{"reset passed", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)}, "", true, time.Second},
+		{"no reset", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0"}, "", true, defaultBackoff},
+		{"too many requests", http.StatusTooManyRequests, nil, "", true, defaultBackoff},
+		{"rate limit in body", http.StatusForbidden, nil, `{"message": "API Rate Limit exceeded for 10.0.0.1"}`, true, defaultBackoff},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		for name, value := range tt.header {
+			resp.Header.Set(name, value)
+		}
+
+		wait, limited := rateLimitWait(resp, []byte(tt.body))
+		if limited != tt.wantLimited {
+			t.Errorf("%s: limited = %v, want %v", tt.name, limited, tt.wantLimited)
+			continue
+		}
+		// Waits until a reset are measured from now, allow some slack
+		if diff := wait - tt.wantWait; diff < -2*time.Second || diff > 2*time.Second {
+			t.Errorf("%s: wait = %s, want %s", tt.name, wait, tt.wantWait)
+		}
+	}
+}
+
+func TestRestClientFetchRaw(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/raw":
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprintf(w, "accept=%s", r.Header.Get("Accept"))
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 65)))
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := newTestClient(server)
+	c.timeout = 100 * time.Millisecond
+	c.downloadTimeout = time.Minute
+	ctx := context.Background()
+
+	for _, accept := range []string{"application/vnd.github.raw", ""} {
+		body, err := c.fetchRaw(ctx, server.URL+"/raw", accept, 64)
+		if err != nil || string(body) != "accept="+accept {
+			t.Errorf("fetchRaw(%q) = %q, %v", accept, body, err)
+		}
+	}
+	if c.keptResponse(server.URL+"/raw") != nil {
+		t.Error("fetchRaw kept the response, want raw files fetched unconditionally")
+	}
+
+	if _, err := c.fetchRaw(ctx, server.URL+"/large", "", 64); err == nil || !strings.Contains(err.Error(), "larger than 64 bytes") {
+		t.Errorf("fetchRaw of an oversized file error = %v, want the size limit", err)
+	}
+
+	// Bounded by the API timeout, not the download one
+	start := time.Now()
+	if _, err := c.fetchRaw(ctx, server.URL+"/slow", "", 64); err == nil {
+		t.Error("fetchRaw of a slow file succeeded, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("fetchRaw took %s, want the API timeout", elapsed)
+	}
+
+	if _, err := c.fetchRaw(ctx, server.URL+"/missing", "", 64); !errors.Is(err, ErrNotFound) {
+		t.Errorf("fetchRaw of a missing file error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRestClientDownloadCredentials(t *testing.T) {
+	var external atomic.Value
+	external.Store("")
+	assets := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		external.Store(r.Header.Get("Authorization"))
+		fmt.Fprint(w, "asset")
+	}))
+	defer assets.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" || r.Header.Get("Accept") != "application/octet-stream" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "package")
+	}))
+	defer server.Close()
+	c := newTestClient(server)
+	ctx := context.Background()
+
+	read := func(rawURL, accept string) string {
+		t.Helper()
+		body, err := c.download(ctx, rawURL, accept)
+		if err != nil {
+			t.Fatalf("download of %s error = %v", rawURL, err)
+		}
+		defer body.Close()
+		data, _ := io.ReadAll(body)
+		return string(data)
+	}
+
+	if got := read(server.URL+"/assets/1", "application/octet-stream"); got != "package" {
+		t.Errorf("API download = %q", got)
+	}
+
+	// Credentials are only sent to the API host
+	if got := read(assets.URL+"/go-jo.deb", ""); got != "asset" || external.Load() != "" {
+		t.Errorf("external download = %q with Authorization %q", got, external.Load())
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	c := &restClient{name: "Test API"}
+
+	tests := []struct {
+		status  int
+		wantErr error
+	}{
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusUnauthorized, ErrUpstreamUnauthorized},
+		{http.StatusForbidden, ErrUpstreamUnauthorized},
+		{http.StatusInternalServerError, ErrUpstreamUnavailable},
+		{http.StatusBadGateway, ErrUpstreamUnavailable},
+		{http.StatusServiceUnavailable, ErrUpstreamUnavailable},
+		{http.StatusBadRequest, nil},
+		{http.StatusUnprocessableEntity, nil},
+	}
+
+	sourceErrors := []error{ErrNotFound, ErrInvalidInput, ErrUpstreamUnauthorized, ErrUpstreamUnavailable}
+	for _, tt := range tests {
+		err := c.statusError(tt.status)
+		if err == nil || !strings.Contains(err.Error(), strconv.Itoa(tt.status)) {
+			t.Errorf("statusError(%d) = %v, want an error with the status", tt.status, err)
+			continue
+		}
+		for _, sourceErr := range sourceErrors {
+			if errors.Is(err, sourceErr) != (sourceErr == tt.wantErr) {
+				t.Errorf("statusError(%d) = %v, want it to wrap %v", tt.status, err, tt.wantErr)
+			}
+		}
+	}
+}
@@ -0,0 +1,499 @@
+package source
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/semver"
+)
+
+// releaseNotesFile holds the notes of a local release, next to its .deb
+const releaseNotesFile = "RELEASE_NOTES.md"
+
+// LocalSource serves releases and integrations from the local filesystem, for
+// servers without access to GitHub.
+//
+// Releases are directories named after the version holding the go-jo .deb
+// and optionally its notes in RELEASE_NOTES.md.
+// Integrations are either the branches of a bare git repository (a mirror of
+// go-jo-docker-environments) or the folders of a plain directory.
+type LocalSource struct {
+	releasesDir     string
+	integrationsDir string
+	bareRepo        bool
+}
+
+// NewLocalSource creates a new local filesystem artifact source
+func NewLocalSource(config domain.LocalSourceConfig) (*LocalSource, error) {
+	for _, dir := range []string{config.ReleasesDir, config.IntegrationsDir} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("local source directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("local source directory %s is not a directory", dir)
+		}
+	}
+
+	return &LocalSource{
+		releasesDir:     config.ReleasesDir,
+		integrationsDir: config.IntegrationsDir,
+		bareRepo:        isBareRepository(config.IntegrationsDir),
+	}, nil
+}
+
+// Name identifies the backend in logs
+func (s *LocalSource) Name() string {
+	kind := "folders"
+	if s.bareRepo {
+		kind = "git"
+	}
+	return fmt.Sprintf("local (%s, %s: %s)", s.releasesDir, kind, s.integrationsDir)
+}
+
+// ListVersions returns a release for every version directory
+func (s *LocalSource) ListVersions(ctx context.Context) ([]domain.Release, error) {
+	entries, err := os.ReadDir(s.releasesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read releases directory: %w", err)
+	}
+
+	var releases []domain.Release
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		release, err := s.GetVersion(ctx, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, *release)
+	}
+	return releases, nil
+}
+
+// GetVersion returns the release stored in a version directory
+func (s *LocalSource) GetVersion(ctx context.Context, version string) (*domain.Release, error) {
+	dir, err := childPath(s.releasesDir, version)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("version %s %w", version, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to read release %s: %w", version, err)
+	}
+
+	release := &domain.Release{
+		Version:    version,
+		Prerelease: semver.IsPrereleaseString(version),
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		if name == releaseNotesFile {
+			notes, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read notes of release %s: %w", version, err)
+			}
+			release.Notes = string(notes)
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		release.Assets = append(release.Assets, domain.Asset{Name: name, Size: info.Size()})
+
+		if release.AppPackage != nil || !strings.HasSuffix(name, ".deb") || strings.Contains(name, "api") {
+			continue
+		}
+
+		// The .deb was copied in when the release was published
+		location := filepath.Join(version, name)
+		release.AppPackage = &domain.Package{
+			ID:       fingerprint(location, info.Size(), info.ModTime().UnixNano()),
+			Name:     name,
+			Size:     info.Size(),
+			Location: location,
+		}
+		release.PublishedAt = info.ModTime()
+	}
+
+	return release, nil
+}
+
+// GetVersionCommit returns no commit, local releases are plain directories
+func (s *LocalSource) GetVersionCommit(ctx context.Context, version string) (string, error) {
+	return "", nil
+}
+
+// ListIntegrations returns the branches of the bare repository or the integration folders
+func (s *LocalSource) ListIntegrations(ctx context.Context) ([]domain.Integration, error) {
+	if s.bareRepo {
+		return s.listBranches(ctx, "refs/heads")
+	}
+
+	entries, err := os.ReadDir(s.integrationsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read integrations directory: %w", err)
+	}
+
+	var integrations []domain.Integration
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		integration, err := s.GetIntegration(ctx, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		integrations = append(integrations, *integration)
+	}
+	return integrations, nil
+}
+
+// GetIntegration resolves a branch to its head commit, or fingerprints an
+// integration folder. Branch names are matched exactly, so revision syntax
+// such as "main~1" or "x@{1}" doesn't resolve to other commits.
+func (s *LocalSource) GetIntegration(ctx context.Context, name string) (*domain.Integration, error) {
+	if s.bareRepo {
+		branches, err := s.listBranches(ctx, "refs/heads/"+name)
+		if err != nil {
+			return nil, err
+		}
+		for _, branch := range branches {
+			if branch.Name == name {
+				return &branch, nil
+			}
+		}
+		return nil, fmt.Errorf("integration %s %w", name, ErrNotFound)
+	}
+
+	dir, err := childPath(s.integrationsDir, name)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := folderRevision(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("integration %s %w", name, ErrNotFound)
+		}
+		return nil, err
+	}
+	return &domain.Integration{Name: name, Commit: revision}, nil
+}
+
+// GetIntegrationManifest reads the integration.yaml of the branch commit or
+// of the integration folder
+func (s *LocalSource) GetIntegrationManifest(ctx context.Context, integration *domain.Integration) (*domain.IntegrationManifest, error) {
+	if s.bareRepo {
+		listed, err := s.git(ctx, "ls-tree", "--name-only", integration.Commit, "--", manifestFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(listed)) == 0 {
+			return nil, manifestNotFound(ErrNotFound, integration)
+		}
+
+		data, err := s.git(ctx, "cat-file", "blob", integration.Commit+":"+manifestFile)
+		if err != nil {
+			return nil, err
+		}
+		return parseManifest(data)
+	}
+
+	dir, err := childPath(s.integrationsDir, integration.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filepath.Join(dir, manifestFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, manifestNotFound(ErrNotFound, integration)
+		}
+		return nil, err
+	}
+	return readManifest(file)
+}
+
+// FetchAppPackage opens a .deb from the releases directory
+func (s *LocalSource) FetchAppPackage(ctx context.Context, pkg *domain.Package) (io.ReadCloser, error) {
+	path := filepath.Join(s.releasesDir, pkg.Location)
+	if !isWithin(s.releasesDir, path) {
+		return nil, fmt.Errorf("invalid package location: %s", pkg.Location)
+	}
+	return os.Open(path)
+}
+
+// FetchIntegrationArchive streams a zip of the integration, nested in a single root directory
+func (s *LocalSource) FetchIntegrationArchive(ctx context.Context, integration *domain.Integration) (io.ReadCloser, error) {
+	root := archiveRoot(integration)
+
+	if s.bareRepo {
+		cmd := exec.CommandContext(ctx, "git", "--git-dir", s.integrationsDir, "archive", "--format=zip", "--prefix="+root+"/", integration.Commit)
+		return startCommand(cmd)
+	}
+
+	dir, err := childPath(s.integrationsDir, integration.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(zipFolder(writer, dir, root))
+	}()
+	return reader, nil
+}
+
+// Check verifies both directories are readable and, for a bare repository,
+// that git can read it
+func (s *LocalSource) Check(ctx context.Context) error {
+	for _, dir := range []string{s.releasesDir, s.integrationsDir} {
+		if _, err := os.ReadDir(dir); err != nil {
+			return err
+		}
+	}
+	if s.bareRepo {
+		if _, err := s.git(ctx, "rev-parse", "--git-dir"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listBranches lists the branches of the bare repository matching a
+// for-each-ref pattern with their head commits. Patterns also match the
+// branches below them (refs/heads/feature matches feature/x).
+func (s *LocalSource) listBranches(ctx context.Context, pattern string) ([]domain.Integration, error) {
+	output, err := s.git(ctx, "for-each-ref", "--format=%(objectname) %(refname:strip=2)", "--", pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var integrations []domain.Integration
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		commit, name, found := strings.Cut(scanner.Text(), " ")
+		if found {
+			integrations = append(integrations, domain.Integration{Name: name, Commit: commit})
+		}
+	}
+	return integrations, scanner.Err()
+}
+
+// git runs a git command against the bare repository
+func (s *LocalSource) git(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"--git-dir", s.integrationsDir}, args...)...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// commandReader streams the output of a command and reports its failure at EOF
+type commandReader struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+}
+
+// startCommand starts a command and returns its output stream
+func startCommand(cmd *exec.Cmd) (io.ReadCloser, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &commandReader{ReadCloser: stdout, cmd: cmd, stderr: stderr}, nil
+}
+
+// Read reads the command output and turns a failed exit into an error
+func (r *commandReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err == io.EOF {
+		if waitErr := r.cmd.Wait(); waitErr != nil {
+			return n, fmt.Errorf("%s failed: %v: %s", r.cmd.Args[0], waitErr, strings.TrimSpace(r.stderr.String()))
+		}
+	}
+	return n, err
+}
+
+// Close stops the command if its output was not fully read
+func (r *commandReader) Close() error {
+	err := r.ReadCloser.Close()
+	if r.cmd.ProcessState == nil {
+		r.cmd.Process.Kill()
+		r.cmd.Wait()
+	}
+	return err
+}
+
+// isBareRepository reports whether a directory is a bare git repository
+func isBareRepository(dir string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// childPath returns the path of a direct child of dir, refusing names that
+// would escape it
+func childPath(dir, name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("%w: invalid name %q", ErrInvalidInput, name)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// isWithin reports whether path is inside dir
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// fingerprint hashes the given values into a stable identifier
+func fingerprint(values ...interface{}) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(values...)))
+	return hex.EncodeToString(sum[:])
+}
+
+// folderRevision fingerprints the files of a folder, so the revision changes
+// whenever a file is added, removed or modified
+func folderRevision(dir string) (string, error) {
+	hash := sha256.New()
+	err := walkFolder(dir, func(rel string, info fs.FileInfo) error {
+		fmt.Fprintf(hash, "%s\x00%d\x00%d\x00%o\n", rel, info.Size(), info.ModTime().UnixNano(), info.Mode())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// zipFolder writes a zip of a folder with every entry nested under root
+func zipFolder(w io.Writer, dir, root string) error {
+	zipWriter := zip.NewWriter(w)
+
+	err := walkFolder(dir, func(rel string, info fs.FileInfo) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = root + "/" + filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			header.Name += "/"
+			_, err := zipWriter.CreateHeader(header)
+			return err
+		}
+
+		header.Method = zip.Deflate
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(filepath.Join(dir, rel))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(writer, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return zipWriter.Close()
+}
+
+// walkFolder visits the regular files and directories of a folder in a
+// stable order, skipping .git directories and other special files
+func walkFolder(dir string, visit func(rel string, info fs.FileInfo) error) error {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || entry.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(paths)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if err := visit(rel, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveRoot names the root directory of an integration archive
+func archiveRoot(integration *domain.Integration) string {
+	commit := integration.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	return strings.ReplaceAll(integration.Name, "/", "-") + "-" + commit
+}
@@ -0,0 +1,80 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+)
+
+// newBareRepo creates a bare repository with the given branches, each with
+// one more commit than the previous one, and returns its path
+func newBareRepo(t *testing.T, branches ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work")
+	bare := filepath.Join(dir, "bare.git")
+
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = work
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, output)
+		}
+	}
+
+	if err := os.MkdirAll(work, 0755); err != nil {
+		t.Fatal(err)
+	}
+	run("init", "-q", "-b", "main")
+	for i, branch := range branches {
+		if i > 0 {
+			run("checkout", "-q", "-b", branch)
+		}
+		if err := os.WriteFile(filepath.Join(work, "docker-compose.yml"), []byte(branch+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		run("add", ".")
+		run("commit", "-q", "-m", branch)
+	}
+	run("clone", "-q", "--bare", work, bare)
+	return bare
+}
+
+func TestLocalGetIntegrationMatchesBranchesExactly(t *testing.T) {
+	bare := newBareRepo(t, "main", "feature/x", "zabbix")
+	releases := t.TempDir()
+
+	s, err := NewLocalSource(domain.LocalSourceConfig{ReleasesDir: releases, IntegrationsDir: bare})
+	if err != nil {
+		t.Fatalf("NewLocalSource error = %v", err)
+	}
+
+	ctx := context.Background()
+	for _, name := range []string{"main", "feature/x", "zabbix"} {
+		integration, err := s.GetIntegration(ctx, name)
+		if err != nil {
+			t.Fatalf("GetIntegration(%q) error = %v", name, err)
+		}
+		if integration.Name != name || len(integration.Commit) != 40 {
+			t.Errorf("GetIntegration(%q) = %+v", name, integration)
+		}
+	}
+
+	for _, name := range []string{"zabbix~1", "zabbix^", "main@{0}", "feature", "feature/*", "zab*", "HEAD", "../heads/main", "nginx"} {
+		if integration, err := s.GetIntegration(ctx, name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetIntegration(%q) = %+v, %v, want ErrNotFound", name, integration, err)
+		}
+	}
+}
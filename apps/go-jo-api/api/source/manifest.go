@@ -0,0 +1,94 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/semver"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// manifestFile describes an integration, at the root of its branch or folder
+	manifestFile = "integration.yaml"
+	// maxManifestSize bounds the manifests read from the upstream
+	maxManifestSize = 64 * 1024
+)
+
+// readManifest parses and validates the manifest of an integration, closing
+// the body
+func readManifest(body io.ReadCloser) (*domain.IntegrationManifest, error) {
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, maxManifestSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxManifestSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", manifestFile, maxManifestSize)
+	}
+
+	return parseManifest(data)
+}
+
+// parseManifest parses a manifest, refusing unknown fields so typos don't go
+// unnoticed, and checks its versions, constraints and ports
+func parseManifest(data []byte) (*domain.IntegrationManifest, error) {
+	var manifest domain.IntegrationManifest
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid %s: %w", manifestFile, err)
+	}
+
+	if err := checkManifest(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", manifestFile, err)
+	}
+	return &manifest, nil
+}
+
+// checkManifest validates the fields of a manifest that are interpreted
+func checkManifest(manifest *domain.IntegrationManifest) error {
+	var minVersion *semver.Version
+	var err error
+
+	if manifest.GoJo.MinVersion != "" {
+		if minVersion, err = semver.Parse(manifest.GoJo.MinVersion); err != nil {
+			return fmt.Errorf("go_jo.min_version: %w", err)
+		}
+	}
+	if manifest.GoJo.MaxVersion != "" {
+		if _, err = semver.Parse(manifest.GoJo.MaxVersion); err != nil {
+			return fmt.Errorf("go_jo.max_version: %w", err)
+		}
+	}
+	// A partial max_version covers its whole line, "1.4.2" is not above "1.4"
+	if minVersion != nil && manifest.GoJo.MaxVersion != "" {
+		if within, _ := minVersion.AtMost(manifest.GoJo.MaxVersion); !within {
+			return fmt.Errorf("go_jo.min_version %s is above go_jo.max_version %s", manifest.GoJo.MinVersion, manifest.GoJo.MaxVersion)
+		}
+	}
+
+	for _, port := range manifest.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("port %d is out of range", port)
+		}
+	}
+
+	if _, err := semver.ParseConstraint(manifest.Requires.Docker); err != nil {
+		return fmt.Errorf("requires.docker: %w", err)
+	}
+	if _, err := semver.ParseConstraint(manifest.Requires.Compose); err != nil {
+		return fmt.Errorf("requires.compose: %w", err)
+	}
+	return nil
+}
+
+// manifestNotFound names the integration in the error of a missing manifest
+func manifestNotFound(err error, integration *domain.Integration) error {
+	return notFound(err, manifestFile+" of integration", integration.Name)
+}
@@ -0,0 +1,113 @@
+package source
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "empty", data: ""},
+		{name: "comments only", data: "# nothing to declare\n"},
+		{
+			name: "complete",
+			data: `name: Zabbix
+description: Zabbix server with its agent
+maintainer: ops@example.com
+go_jo:
+  min_version: v1.2.0
+  max_version: 2.0.0
+ports: [80, 10051]
+env: [ZABBIX_DB_PASSWORD]
+requires:
+  docker: ">=20.10"
+  compose: ">=2.0 <3.0"
+`,
+		},
+		{name: "same min and max", data: "go_jo: {min_version: v1.2.0, max_version: v1.2.0}"},
+		{name: "prerelease bounds", data: "go_jo: {min_version: v1.2.0-rc.1, max_version: v1.2.0}"},
+		{name: "partial max covers its line", data: "go_jo: {min_version: v1.4.2, max_version: \"1.4\"}"},
+		{name: "partial max covers its major", data: "go_jo: {min_version: v1.9.0, max_version: \"1\"}"},
+		{name: "lowest and highest port", data: "ports: [1, 65535]"},
+		{name: "unknown field", data: "name: Zabbix\nport: [80]\n", wantErr: "field port not found"},
+		{name: "unknown nested field", data: "go_jo: {minimum: v1.0.0}", wantErr: "field minimum not found"},
+		{name: "wrong type", data: "ports: eighty", wantErr: "cannot unmarshal"},
+		{name: "malformed YAML", data: "name: [Zabbix", wantErr: "invalid integration.yaml"},
+		{name: "invalid min_version", data: "go_jo: {min_version: latest}", wantErr: "go_jo.min_version"},
+		{name: "invalid max_version", data: "go_jo: {max_version: two}", wantErr: "go_jo.max_version"},
+		{name: "min above max", data: "go_jo: {min_version: v2.0.0, max_version: v1.9.9}", wantErr: "is above go_jo.max_version"},
+		{name: "min above partial max", data: "go_jo: {min_version: v1.5.0, max_version: \"1.4\"}", wantErr: "is above go_jo.max_version"},
+		{name: "prerelease above release", data: "go_jo: {min_version: v1.2.1-rc.1, max_version: v1.2.0}", wantErr: "is above"},
+		{name: "port zero", data: "ports: [80, 0]", wantErr: "port 0 is out of range"},
+		{name: "negative port", data: "ports: [-1]", wantErr: "port -1 is out of range"},
+		{name: "port too high", data: "ports: [65536]", wantErr: "port 65536 is out of range"},
+		{name: "invalid docker constraint", data: "requires: {docker: \">=twenty\"}", wantErr: "requires.docker"},
+		{name: "invalid compose constraint", data: "requires: {compose: \"~>2\"}", wantErr: "requires.compose"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := parseManifest([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseManifest error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseManifest error = %v", err)
+			}
+			if manifest == nil {
+				t.Fatal("parseManifest returned no manifest")
+			}
+		})
+	}
+}
+
+func TestParseManifestFields(t *testing.T) {
+	manifest, err := parseManifest([]byte(`name: Zabbix
+ports: [80, 10051]
+env: [ZABBIX_DB_PASSWORD]
+go_jo:
+  min_version: v1.2.0
+requires:
+  docker: ">=20.10"
+`))
+	if err != nil {
+		t.Fatalf("parseManifest error = %v", err)
+	}
+
+	if manifest.Name != "Zabbix" || manifest.GoJo.MinVersion != "v1.2.0" || manifest.GoJo.MaxVersion != "" ||
+		manifest.Requires.Docker != ">=20.10" || manifest.Requires.Compose != "" {
+		t.Errorf("manifest = %+v", manifest)
+	}
+	if !reflect.DeepEqual(manifest.Ports, []int{80, 10051}) || !reflect.DeepEqual(manifest.Env, []string{"ZABBIX_DB_PASSWORD"}) {
+		t.Errorf("ports = %v, env = %v", manifest.Ports, manifest.Env)
+	}
+}
+
+func TestReadManifestSize(t *testing.T) {
+	padding := "# " + strings.Repeat("x", maxManifestSize) + "\n"
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"at the limit", "name: Zabbix\n" + padding[:maxManifestSize-len("name: Zabbix\n")-1] + "\n", false},
+		{"over the limit", "name: Zabbix\n" + padding, true},
+	}
+
+	for _, tt := range tests {
+		_, err := readManifest(io.NopCloser(strings.NewReader(tt.data)))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: readManifest error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api/domain"
+)
+
+// Source types selectable with source.type in config.yaml
+const (
+	TypeGitHub = domain.SourceTypeGitHub
+	TypeLocal  = domain.SourceTypeLocal
+	TypeGitLab = domain.SourceTypeGitLab
+	TypeGitea  = domain.SourceTypeGitea
+)
+
+// Errors of artifact sources, wrapped with details. Handlers map them to HTTP
+// statuses; an exhausted upstream rate limit is reported as a RateLimitError.
+var (
+	// ErrNotFound means the version or integration does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidInput means a version or integration name can't be looked up
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrUpstreamUnauthorized means the upstream refused the configured credentials
+	ErrUpstreamUnauthorized = errors.New("upstream refused the credentials")
+	// ErrUpstreamUnavailable means the upstream could not be reached or failed
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+)
+
+// ArtifactSource provides go-jo releases and integration environments.
+// Implementations only return published (non-draft) releases.
+type ArtifactSource interface {
+	// Name identifies the backend in logs
+	Name() string
+
+	// ListVersions returns every published release
+	ListVersions(ctx context.Context) ([]domain.Release, error)
+
+	// GetVersion returns a single release with its app package
+	GetVersion(ctx context.Context, version string) (*domain.Release, error)
+
+	// GetVersionCommit resolves the tag of a release to its commit, empty
+	// when the source doesn't track commits
+	GetVersionCommit(ctx context.Context, version string) (string, error)
+
+	// ListIntegrations returns every integration environment
+	ListIntegrations(ctx context.Context) ([]domain.Integration, error)
+
+	// GetIntegration resolves an integration to its current commit
+	GetIntegration(ctx context.Context, name string) (*domain.Integration, error)
+
+	// GetIntegrationManifest reads the integration.yaml of an integration at
+	// its resolved commit. The error wraps ErrNotFound when it has none.
+	GetIntegrationManifest(ctx context.Context, integration *domain.Integration) (*domain.IntegrationManifest, error)
+
+	// FetchAppPackage streams the content of an app package
+	FetchAppPackage(ctx context.Context, pkg *domain.Package) (io.ReadCloser, error)
+
+	// FetchIntegrationArchive streams a zip archive of an integration at its
+	// resolved commit. All entries are nested in a single root directory.
+	FetchIntegrationArchive(ctx context.Context, integration *domain.Integration) (io.ReadCloser, error)
+}
+
+// Checker is implemented by sources that can verify they are reachable and
+// that their credentials are accepted, without listing everything
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// Invalidator is implemented by sources keeping listings in memory, so they
+// can be refreshed as soon as the upstream changes
+type Invalidator interface {
+	Invalidate()
+}
+
+// New creates the artifact source selected in the configuration, with its
+// listings cached for source.cache_ttl
+func New(config *domain.Config) (*CachedSource, error) {
+	backend, err := newBackend(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewCachedSource(backend, config.Source.CacheTTL), nil
+}
+
+// newBackend creates the backend selected with source.type
+func newBackend(config *domain.Config) (ArtifactSource, error) {
+	switch config.GetSourceType() {
+	case TypeGitHub:
+		return NewGitHubSource(config), nil
+	case TypeLocal:
+		return NewLocalSource(config.Source.Local)
+	case TypeGitLab:
+		return NewGitLabSource(config)
+	case TypeGitea:
+		return NewGiteaSource(config)
+	default:
+		return nil, fmt.Errorf("unknown source type: %q", config.GetSourceType())
+	}
+}
+
+// notFound names the missing version or integration in a not found error
+func notFound(err error, kind, name string) error {
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%s %s %w", kind, name, ErrNotFound)
+	}
+	return err
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os/signal"
+	"syscall"
 
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-api/api"
 )
@@ -10,8 +13,27 @@ func main() {
 	// Create and start the API
 	apiInstance := api.New()
 
-	// Start the server (this blocks until the server stops)
-	if err := apiInstance.Start(); err != nil {
-		log.Fatalf("Failed to start API server: %v", err)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start the server in the background so signals can be handled
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- apiInstance.Start()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			log.Fatalf("Failed to start API server: %v", err)
+		}
+	case <-ctx.Done():
+		// A second signal kills the process immediately
+		stop()
+		log.Println("Shutdown signal received")
+
+		if err := apiInstance.Stop(); err != nil {
+			log.Fatalf("Failed to stop API server: %v", err)
+		}
 	}
 }
@@ -5,23 +5,126 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/semver"
+)
+
+const (
+	// apiPrefix is the version of the go-jo-api routes used by the installer
+	apiPrefix = "/v1"
+	// downloadAttempts is the number of times an interrupted download is resumed
+	downloadAttempts = 3
+	// maxRetryAfter bounds the seconds waited when the API rate limits a download
+	maxRetryAfter = 300
+	// etagSuffix names the file keeping the ETag of a partial download
+	etagSuffix = ".etag"
 )
 
 // Client represents an API client for go-jo-api
 type Client struct {
-	baseURL    string
-	licenseKey string
-	httpClient *http.Client
+	baseURL        string
+	licenseKey     string
+	httpClient     *http.Client
+	downloadClient *http.Client
+}
+
+// VersionsResponse is the body of GET /v1/versions
+type VersionsResponse struct {
+	Versions []string  `json:"versions"`
+	Latest   string    `json:"latest,omitempty"`
+	Releases []Version `json:"releases"`
 }
 
-// APIResponse represents the structure of API responses
-type APIResponse struct {
-	Versions     []string `json:"versions,omitempty"`
-	Integrations []string `json:"integrations,omitempty"`
-	Error        string   `json:"error,omitempty"`
-	Message      string   `json:"message,omitempty"`
+// IntegrationsResponse is the body of GET /v1/integrations
+type IntegrationsResponse struct {
+	Integrations []string      `json:"integrations"`
+	Details      []Integration `json:"details"`
+}
+
+// Integration describes an integration from its integration.yaml
+type Integration struct {
+	Name            string   `json:"name"`
+	DisplayName     string   `json:"display_name"`
+	Description     string   `json:"description"`
+	Maintainer      string   `json:"maintainer"`
+	MinVersion      string   `json:"min_version"`
+	MaxVersion      string   `json:"max_version"`
+	Ports           []int    `json:"ports"`
+	Env             []string `json:"env"`
+	RequiresDocker  string   `json:"requires_docker"`
+	RequiresCompose string   `json:"requires_compose"`
+	// Compatible is nil when the API didn't check the go-jo version
+	Compatible *bool `json:"compatible"`
+}
+
+// ErrorResponse is the body of every API error
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Error codes of the API the installer acts on
+const (
+	CodeUnauthorized         = "unauthorized"
+	CodeLicenseExpired       = "license_expired"
+	CodeLicenseRevoked       = "license_revoked"
+	CodeLicenseSuspended     = "license_suspended"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeRateLimited          = "rate_limited"
+	CodeUpstreamUnauthorized = "upstream_unauthorized"
+	CodeUpstreamRateLimited  = "upstream_rate_limited"
+	CodeUpstreamUnavailable  = "upstream_unavailable"
+)
+
+// APIError is an error answered by the API, with its machine-readable code
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.Status, e.Message)
+}
+
+// newAPIError reads the error body of a response. Bodies that are not an
+// ErrorResponse are kept as the message, without a code.
+func newAPIError(status int, body []byte) *APIError {
+	var response ErrorResponse
+	if err := json.Unmarshal(body, &response); err == nil && response.Error != "" {
+		return &APIError{Status: status, Code: response.Code, Message: response.Message}
+	}
+	return &APIError{Status: status, Message: strings.TrimSpace(string(body))}
+}
+
+// Version describes a go-jo release offered by the API
+type Version struct {
+	Name       string `json:"version"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// VersionDetails is the body of GET /v1/versions/{version}
+type VersionDetails struct {
+	Version     string    `json:"version"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+	Commit      string    `json:"commit"`
+	Notes       string    `json:"notes"`
+	Assets      []Asset   `json:"assets"`
+}
+
+// Asset is a file attached to a go-jo release
+type Asset struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
 }
 
 // NewClient creates a new API client
@@ -32,127 +135,203 @@ func NewClient(baseURL, licenseKey string) *Client {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		downloadClient: &http.Client{
+			Timeout: 30 * time.Minute,
+		},
 	}
 }
 
-// GetVersions fetches available versions from the API
-func (c *Client) GetVersions() ([]string, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/versions", c.baseURL), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// GetVersions fetches available versions from the API, newest first, together
+// with the latest stable version
+func (c *Client) GetVersions() ([]Version, string, error) {
+	var response VersionsResponse
+	if err := c.getJSON("/versions", &response); err != nil {
+		return nil, "", err
 	}
 
-	req.Header.Set("Authorization", c.licenseKey)
+	// Sort versions (newest first)
+	versions := response.Releases
+	sort.SliceStable(versions, func(i, j int) bool {
+		return semver.CompareStrings(versions[i].Name, versions[j].Name) > 0
+	})
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
+	return versions, response.Latest, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+// GetVersionDetails fetches the release notes, publish date and commit of a version
+func (c *Client) GetVersionDetails(version string) (*VersionDetails, error) {
+	var details VersionDetails
+	if err := c.getJSON("/versions/"+url.PathEscape(version), &details); err != nil {
+		return nil, err
 	}
+	return &details, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+// GetIntegrations fetches available integrations from the API, checked
+// against a go-jo version. APIs without manifests only list names.
+func (c *Client) GetIntegrations(version string) ([]Integration, error) {
+	var response IntegrationsResponse
+	if err := c.getJSON("/integrations?version="+url.QueryEscape(version), &response); err != nil {
+		return nil, err
 	}
 
-	// Try to parse as JSON first
-	var apiResp APIResponse
-	if err := json.Unmarshal(body, &apiResp); err == nil {
-		if len(apiResp.Versions) > 0 {
-			return apiResp.Versions, nil
-		}
+	if len(response.Details) > 0 {
+		return response.Details, nil
 	}
 
-	// Fallback: try to parse as simple array
-	var versions []string
-	if err := json.Unmarshal(body, &versions); err == nil {
-		return versions, nil
+	integrations := make([]Integration, 0, len(response.Integrations))
+	for _, name := range response.Integrations {
+		integrations = append(integrations, Integration{Name: name, DisplayName: name})
 	}
-
-	return nil, fmt.Errorf("failed to parse versions response: %s", string(body))
+	return integrations, nil
 }
 
-// GetIntegrations fetches available integrations from the API
-func (c *Client) GetIntegrations() ([]string, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/integrations", c.baseURL), nil)
+// getJSON makes an authenticated request to a /v1 endpoint and decodes its JSON response
+func (c *Client) getJSON(path string, result interface{}) error {
+	req, err := http.NewRequest("GET", c.baseURL+apiPrefix+path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", c.licenseKey)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return newAPIError(resp.StatusCode, body)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", path, err)
 	}
+	return nil
+}
+
+// DownloadPackage downloads a package for the specified version and integration.
+// Interrupted downloads are retried, resuming the partial file with an HTTP
+// Range request. The ETag of the package is kept next to the partial file so
+// a later run can also resume it, as long as the package did not change.
+func (c *Client) DownloadPackage(version, integration, outputPath string) error {
+	url := fmt.Sprintf("%s%s/download/%s/%s", c.baseURL, apiPrefix, version, integration)
 
-	// Try to parse as JSON first
-	var apiResp APIResponse
-	if err := json.Unmarshal(body, &apiResp); err == nil {
-		if len(apiResp.Integrations) > 0 {
-			return apiResp.Integrations, nil
+	var err error
+	for attempt := 1; attempt <= downloadAttempts; attempt++ {
+		var retry bool
+		retry, err = c.downloadOnce(url, outputPath)
+		if err == nil || !retry {
+			return err
 		}
-	}
 
-	// Fallback: try to parse as simple array
-	var integrations []string
-	if err := json.Unmarshal(body, &integrations); err == nil {
-		return integrations, nil
+		if attempt < downloadAttempts {
+			fmt.Printf("\033[33m⚠️  Download interrupted (%v), resuming...\033[0m\n", err)
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
 	}
 
-	return nil, fmt.Errorf("failed to parse integrations response: %s", string(body))
+	return fmt.Errorf("%w (run the installer again to resume the download)", err)
 }
 
-// DownloadPackage downloads a package for the specified version and integration
-func (c *Client) DownloadPackage(version, integration, outputPath string) error {
-	url := fmt.Sprintf("%s/download/%s/%s", c.baseURL, version, integration)
+// downloadOnce performs a single download attempt, resuming a partial file when
+// possible. It reports whether a failed attempt is worth retrying.
+func (c *Client) downloadOnce(url, outputPath string) (bool, error) {
+	etagPath := outputPath + etagSuffix
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return false, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", c.licenseKey)
 
-	resp, err := c.httpClient.Do(req)
+	// Resume a partial download of the same package
+	var offset int64
+	if etag, err := os.ReadFile(etagPath); err == nil && len(etag) > 0 {
+		if info, err := os.Stat(outputPath); err == nil && info.Size() > 0 {
+			offset = info.Size()
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			req.Header.Set("If-Range", string(etag))
+		}
+	}
+
+	resp, err := c.downloadClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
+		return true, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	var file *os.File
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			return false, fmt.Errorf("unexpected content range: %s", resp.Header.Get("Content-Range"))
+		}
+		file, err = os.OpenFile(outputPath, os.O_WRONLY|os.O_APPEND, 0644)
+	case http.StatusOK:
+		// Full content: either a fresh download or the package changed
+		file, err = os.Create(outputPath)
+		if err == nil {
+			err = os.WriteFile(etagPath, []byte(resp.Header.Get("ETag")), 0644)
+		}
+	case http.StatusTooManyRequests:
+		// Rate limited by the API, wait as long as it asks before retrying
+		wait := retryAfter(resp.Header.Get("Retry-After"))
+		fmt.Printf("\033[33m⚠️  Rate limited by the API, waiting %s...\033[0m\n", wait)
+		time.Sleep(wait)
+		return true, fmt.Errorf("rate limited by the API")
+	case http.StatusServiceUnavailable:
+		// Only wait for an artifact source that tells when it recovers, e.g.
+		// a rate limited one; other outages fail fast
+		body, _ := io.ReadAll(resp.Body)
+		apiErr := newAPIError(resp.StatusCode, body)
+		if resp.Header.Get("Retry-After") == "" && apiErr.Code != CodeUpstreamRateLimited {
+			return false, apiErr
+		}
+		wait := retryAfter(resp.Header.Get("Retry-After"))
+		fmt.Printf("\033[33m⚠️  The API is temporarily unavailable, waiting %s...\033[0m\n", wait)
+		time.Sleep(wait)
+		return true, apiErr
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file does not match the package anymore, start over
+		os.Remove(outputPath)
+		os.Remove(etagPath)
+		return true, fmt.Errorf("partial download is no longer valid")
+	default:
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("download failed with status %d: %s", resp.StatusCode, string(body))
+		return false, newAPIError(resp.StatusCode, body)
 	}
-
-	// Create output file
-	file, err := os.Create(outputPath)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return false, fmt.Errorf("failed to create output file: %w", err)
 	}
 	defer file.Close()
 
 	// Copy response body to file
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		return true, fmt.Errorf("failed to write file: %w", err)
 	}
 
-	return nil
+	// The download is complete, nothing left to resume
+	os.Remove(etagPath)
+
+	return false, nil
+}
+
+// retryAfter parses a Retry-After header in seconds, bounded to a few minutes
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 1 {
+		return 5 * time.Second
+	}
+	if seconds > maxRetryAfter {
+		seconds = maxRetryAfter
+	}
+	return time.Duration(seconds) * time.Second
 }
@@ -1,20 +1,32 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-integration-installer/api"
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-integration-installer/config"
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-integration-installer/docker"
 	"github.com/henrique-ferreira-unvoid/go-jo/apps/go-jo-integration-installer/utils"
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/licensefile"
 )
 
 const MAX_OPTIONS = 15
 
+// maxNotesLines bounds the release notes shown below the version list
+const maxNotesLines = 12
+
+// versionDescribeDelay is how long a version stays highlighted before its
+// release notes are requested, so scrolling through the list doesn't spend
+// the rate limit of the license on versions that are only passed over
+const versionDescribeDelay = 300 * time.Millisecond
+
 // selectionModel represents the state of the selection interface
 type selectionModel struct {
 	items    []string
@@ -23,26 +35,91 @@ type selectionModel struct {
 	done     bool
 	title    string
 	latest   *string
+	labels   map[string]string
+
+	// describe loads the text shown below the list for the highlighted
+	// item, nil when items have no description
+	describe     func(item string) string
+	descriptions map[string]string
+	// describeDelay is how long an item must stay highlighted before it is
+	// described, except for the first one
+	describeDelay time.Duration
+}
+
+// descriptionMsg carries a description loaded by describe
+type descriptionMsg struct {
+	item string
+	text string
+}
+
+// describeTimerMsg fires describeDelay after an item was highlighted
+type describeTimerMsg struct {
+	item string
 }
 
 // initialSelectionModel creates a new selection model
-func initialSelectionModel(items []string, title string, latest *string) selectionModel {
+func initialSelectionModel(items []string, title string, latest *string, labels map[string]string,
+	describe func(string) string, describeDelay time.Duration) selectionModel {
 	return selectionModel{
-		items:  items,
-		cursor: 0,
-		title:  title,
-		latest: latest,
+		items:         items,
+		cursor:        0,
+		title:         title,
+		latest:        latest,
+		labels:        labels,
+		describe:      describe,
+		descriptions:  make(map[string]string),
+		describeDelay: describeDelay,
 	}
 }
 
 // Init initializes the model
 func (m selectionModel) Init() tea.Cmd {
-	return nil
+	return m.loadDescription()
+}
+
+// loadDescription loads the description of the highlighted item in the
+// background, once per item
+func (m selectionModel) loadDescription() tea.Cmd {
+	if m.describe == nil || len(m.items) == 0 {
+		return nil
+	}
+
+	item := m.items[m.cursor]
+	if _, ok := m.descriptions[item]; ok {
+		return nil
+	}
+	m.descriptions[item] = ""
+
+	return func() tea.Msg {
+		return descriptionMsg{item: item, text: m.describe(item)}
+	}
+}
+
+// scheduleDescription loads the description of the highlighted item once it
+// stayed highlighted for describeDelay
+func (m selectionModel) scheduleDescription() tea.Cmd {
+	if m.describeDelay <= 0 || m.describe == nil || len(m.items) == 0 {
+		return m.loadDescription()
+	}
+	if _, ok := m.descriptions[m.items[m.cursor]]; ok {
+		return nil
+	}
+
+	item := m.items[m.cursor]
+	return tea.Tick(m.describeDelay, func(time.Time) tea.Msg {
+		return describeTimerMsg{item: item}
+	})
 }
 
 // Update handles user input
 func (m selectionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case descriptionMsg:
+		m.descriptions[msg.item] = msg.text
+	case describeTimerMsg:
+		if m.items[m.cursor] == msg.item {
+			return m, m.loadDescription()
+		}
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c", "q":
@@ -51,10 +128,12 @@ func (m selectionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.cursor > 0 {
 				m.cursor--
 			}
+			return m, m.scheduleDescription()
 		case "down", "j":
 			if m.cursor < len(m.items)-1 {
 				m.cursor++
 			}
+			return m, m.scheduleDescription()
 		case "enter", " ":
 			m.selected = m.items[m.cursor]
 			m.done = true
@@ -90,6 +169,9 @@ func (m selectionModel) View() string {
 		if m.latest != nil && *m.latest == choice {
 			latest = "\033[33m(latest)\033[0m" // latest with yellow color!
 		}
+		if label, ok := m.labels[choice]; ok {
+			latest += " \033[35m" + label + "\033[0m" // extra label with magenta color
+		}
 
 		// Render the row with colors
 		choiceColor := ""
@@ -103,6 +185,14 @@ func (m selectionModel) View() string {
 		s += fmt.Sprintf("%s [%s] %s%s%s %s\n", cursor, checked, choiceColor, choice, resetColor, latest)
 	}
 
+	if m.describe != nil {
+		description := m.descriptions[m.items[m.cursor]]
+		if description == "" {
+			description = "\033[90mLoading...\033[0m"
+		}
+		s += "\n" + description + "\n"
+	}
+
 	s += "\n(press q to quit)\n"
 
 	return s
@@ -150,14 +240,25 @@ func Run() error {
 		return fmt.Errorf("failed to read license key: %w", err)
 	}
 
+	// Verify signed license files before contacting the API
+	licensePayload, verified, err := utils.VerifyLicense(licenseKey, cfg.LicensePublicKey)
+	if err != nil {
+		fmt.Printf("\033[31m❌ Invalid license: %v\033[0m\n", err)
+		return fmt.Errorf("invalid license: %w", err)
+	}
+	if licensePayload != nil {
+		printLicenseDetails(licensePayload, verified)
+	}
+
 	// Initialize API client
 	client := api.NewClient(cfg.APIURL, licenseKey)
 
 	// Get available versions
 	fmt.Printf("\033[36m🔍 Fetching available versions...\033[0m\n")
-	versions, err := client.GetVersions()
+	versions, latestVersion, err := client.GetVersions()
 	if err != nil {
 		fmt.Printf("\033[31m❌ Failed to fetch versions: %v\033[0m\n", err)
+		printErrorHint(err)
 		return fmt.Errorf("failed to fetch versions: %w", err)
 	}
 
@@ -168,7 +269,22 @@ func Run() error {
 
 	// Display versions and get user selection
 	fmt.Printf("\033[33m📦 Available versions:\033[0m\n")
-	selectedVersion, err := interactiveSelection(versions, "\033[32mSelect version\033[0m", &versions[0])
+	versionNames := make([]string, 0, len(versions))
+	versionLabels := make(map[string]string)
+	for _, version := range versions {
+		versionNames = append(versionNames, version.Name)
+		if version.Prerelease {
+			versionLabels[version.Name] = "(prerelease)"
+		}
+	}
+
+	var latest *string
+	if latestVersion != "" {
+		latest = &latestVersion
+	}
+
+	selectedVersion, err := interactiveSelection(versionNames, "\033[32mSelect version\033[0m", latest, versionLabels,
+		describeVersion(client), versionDescribeDelay)
 	if err != nil {
 		fmt.Printf("\033[31m❌ Version selection failed: %v\033[0m\n", err)
 		return err
@@ -178,9 +294,10 @@ func Run() error {
 
 	// Get available integrations
 	fmt.Printf("\033[36m🔍 Fetching available integrations...\033[0m\n")
-	integrations, err := client.GetIntegrations()
+	integrations, err := client.GetIntegrations(selectedVersion)
 	if err != nil {
 		fmt.Printf("\033[31m❌ Failed to fetch integrations: %v\033[0m\n", err)
+		printErrorHint(err)
 		return fmt.Errorf("failed to fetch integrations: %w", err)
 	}
 
@@ -191,13 +308,26 @@ func Run() error {
 
 	// Display integrations and get user selection
 	fmt.Printf("\033[33m🔌 Available integrations:\033[0m\n")
-	selectedIntegration, err := interactiveSelection(integrations, "\033[32mSelect integration\033[0m", nil)
+	integrationNames := make([]string, 0, len(integrations))
+	integrationLabels := make(map[string]string)
+	integrationsByName := make(map[string]api.Integration)
+	for _, integration := range integrations {
+		integrationNames = append(integrationNames, integration.Name)
+		integrationsByName[integration.Name] = integration
+		if integration.Compatible != nil && !*integration.Compatible {
+			integrationLabels[integration.Name] = "(incompatible with " + selectedVersion + ")"
+		}
+	}
+
+	selectedIntegration, err := interactiveSelection(integrationNames, "\033[32mSelect integration\033[0m", nil, integrationLabels,
+		describeIntegration(integrationsByName), 0)
 	if err != nil {
 		fmt.Printf("\033[31m❌ Integration selection failed: %v\033[0m\n", err)
 		return err
 	}
 
 	fmt.Printf("\033[32m✅ Selected integration: %s\033[0m\n", selectedIntegration)
+	printIntegrationWarnings(integrationsByName[selectedIntegration], selectedVersion)
 
 	// Download the package
 	fmt.Printf("\033[35m⬇️  Downloading package for version %s with integration %s...\033[0m\n",
@@ -210,6 +340,7 @@ func Run() error {
 	err = client.DownloadPackage(selectedVersion, safeSelectedIntegration, outputPath)
 	if err != nil {
 		fmt.Printf("\033[31m❌ Failed to download package: %v\033[0m\n", err)
+		printErrorHint(err)
 		return fmt.Errorf("failed to download package: %w", err)
 	}
 
@@ -229,6 +360,183 @@ func Run() error {
 	return nil
 }
 
+// describeVersion returns a describe function of the version selection
+// showing the publish date, commit and release notes of a version
+func describeVersion(client *api.Client) func(string) string {
+	return func(version string) string {
+		details, err := client.GetVersionDetails(version)
+		if err != nil {
+			return fmt.Sprintf("\033[90mRelease notes unavailable: %v\033[0m", err)
+		}
+
+		var header []string
+		if !details.PublishedAt.IsZero() {
+			header = append(header, "Published "+details.PublishedAt.Format(time.DateOnly))
+		}
+		if details.Commit != "" {
+			commit := details.Commit
+			if len(commit) > 12 {
+				commit = commit[:12]
+			}
+			header = append(header, "commit "+commit)
+		}
+
+		s := ""
+		if len(header) > 0 {
+			s = "\033[36m" + strings.Join(header, " · ") + "\033[0m\n"
+		}
+
+		notes := strings.TrimSpace(strings.ReplaceAll(details.Notes, "\r\n", "\n"))
+		if notes == "" {
+			return s + "\033[90mNo release notes\033[0m"
+		}
+
+		lines := strings.Split(notes, "\n")
+		if len(lines) > maxNotesLines {
+			lines = append(lines[:maxNotesLines], "\033[90m...\033[0m")
+		}
+		return s + strings.Join(lines, "\n")
+	}
+}
+
+// describeIntegration returns a describe function of the integration
+// selection showing the manifest of an integration
+func describeIntegration(integrations map[string]api.Integration) func(string) string {
+	return func(name string) string {
+		integration := integrations[name]
+
+		var lines []string
+		if integration.DisplayName != "" && integration.DisplayName != integration.Name {
+			lines = append(lines, "\033[1m"+integration.DisplayName+"\033[0m")
+		}
+		if integration.Description != "" {
+			lines = append(lines, strings.TrimSpace(integration.Description))
+		}
+		if integration.Maintainer != "" {
+			lines = append(lines, "\033[36mMaintainer: "+integration.Maintainer+"\033[0m")
+		}
+		if integration.MinVersion != "" || integration.MaxVersion != "" {
+			lines = append(lines, "\033[36mgo-jo versions: "+versionRange(integration.MinVersion, integration.MaxVersion)+"\033[0m")
+		}
+		if len(integration.Ports) > 0 {
+			ports := make([]string, 0, len(integration.Ports))
+			for _, port := range integration.Ports {
+				ports = append(ports, strconv.Itoa(port))
+			}
+			lines = append(lines, "\033[36mPorts: "+strings.Join(ports, ", ")+"\033[0m")
+		}
+		if len(integration.Env) > 0 {
+			lines = append(lines, "\033[36mEnvironment: "+strings.Join(integration.Env, ", ")+"\033[0m")
+		}
+		if integration.RequiresDocker != "" {
+			lines = append(lines, "\033[36mDocker: "+integration.RequiresDocker+"\033[0m")
+		}
+		if integration.RequiresCompose != "" {
+			lines = append(lines, "\033[36mDocker Compose: "+integration.RequiresCompose+"\033[0m")
+		}
+
+		if len(lines) == 0 {
+			return "\033[90mNo description\033[0m"
+		}
+		return strings.Join(lines, "\n")
+	}
+}
+
+// versionRange formats the go-jo versions an integration works with
+func versionRange(minVersion, maxVersion string) string {
+	switch {
+	case maxVersion == "":
+		return ">= " + minVersion
+	case minVersion == "":
+		return "<= " + maxVersion
+	default:
+		return minVersion + " - " + maxVersion
+	}
+}
+
+// printIntegrationWarnings tells the user about what the selected
+// integration needs and the host may be missing
+func printIntegrationWarnings(integration api.Integration, version string) {
+	if integration.Compatible != nil && !*integration.Compatible {
+		fmt.Printf("\033[33m⚠️  %s supports go-jo %s, not %s\033[0m\n", integration.Name,
+			versionRange(integration.MinVersion, integration.MaxVersion), version)
+	}
+
+	var missing []string
+	for _, name := range integration.Env {
+		if _, ok := os.LookupEnv(name); !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Printf("\033[33m⚠️  %s expects these environment variables, which are not set: %s\033[0m\n",
+			integration.Name, strings.Join(missing, ", "))
+	}
+}
+
+// printErrorHint tells the user what to do about API errors they can act on
+func printErrorHint(err error) {
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) {
+		return
+	}
+
+	var hint string
+	switch apiErr.Code {
+	case api.CodeUnauthorized:
+		hint = "Check that the license file holds the license key you received"
+	case api.CodeLicenseExpired:
+		hint = "Your license has expired, contact your vendor to renew it"
+	case api.CodeLicenseRevoked, api.CodeLicenseSuspended:
+		hint = "Your license is not active, contact your vendor"
+	case api.CodeForbidden:
+		hint = "Your license does not cover this version or integration"
+	case api.CodeNotFound:
+		hint = "The version or integration is no longer available, run the installer again to pick another one"
+	case api.CodeRateLimited, api.CodeUpstreamRateLimited, api.CodeUpstreamUnavailable:
+		hint = "The API is busy, try again in a few minutes"
+	case api.CodeUpstreamUnauthorized:
+		hint = "The API can't reach its release repositories, contact the API administrator"
+	default:
+		return
+	}
+	fmt.Printf("\033[33m💡 %s\033[0m\n", hint)
+}
+
+// printLicenseDetails displays the content of a signed license file
+func printLicenseDetails(payload *licensefile.Payload, verified bool) {
+	if verified {
+		fmt.Printf("\033[32m✅ License verified\033[0m\n")
+	} else {
+		fmt.Printf("\033[33m⚠️  License signature not verified (LICENSE_PUBLIC_KEY is not set)\033[0m\n")
+	}
+
+	fmt.Printf("\033[36m🪪  License: %s\033[0m\n", payload.ID)
+	fmt.Printf("\033[36m   Customer: %s\033[0m\n", payload.Customer)
+
+	expires := "never"
+	if !payload.ExpiresAt.IsZero() {
+		expires = payload.ExpiresAt.Format(time.DateOnly)
+	}
+	fmt.Printf("\033[36m   Expires: %s\033[0m\n", expires)
+
+	integrations := "all"
+	if len(payload.Integrations) > 0 {
+		integrations = strings.Join(payload.Integrations, ", ")
+	}
+	fmt.Printf("\033[36m   Integrations: %s\033[0m\n", integrations)
+
+	versions := "all"
+	if payload.Versions != "" {
+		versions = payload.Versions
+	}
+	fmt.Printf("\033[36m   Versions: %s\033[0m\n", versions)
+
+	if payload.Channel != "" {
+		fmt.Printf("\033[36m   Channel: %s\033[0m\n", payload.Channel)
+	}
+}
+
 // extractAndDeploy extracts the zip file and runs the deployment
 func extractAndDeploy(zipPath string) error {
 	// Create temporary directory
@@ -263,7 +571,9 @@ func extractAndDeploy(zipPath string) error {
 }
 
 // interactiveSelection provides a robust interactive selection using bubbletea
-func interactiveSelection(options []string, prompt string, latest *string) (string, error) {
+// describe, when not nil, loads the text shown below the highlighted option.
+func interactiveSelection(options []string, prompt string, latest *string, labels map[string]string,
+	describe func(string) string, describeDelay time.Duration) (string, error) {
 	if len(options) == 0 {
 		return "", fmt.Errorf("no options available")
 	}
@@ -273,7 +583,7 @@ func interactiveSelection(options []string, prompt string, latest *string) (stri
 		options = options[:MAX_OPTIONS]
 	}
 
-	m := initialSelectionModel(options, prompt, latest)
+	m := initialSelectionModel(options, prompt, latest, labels, describe, describeDelay)
 	p := tea.NewProgram(m)
 
 	// Run the program
@@ -9,9 +9,14 @@ import (
 
 const DEFAULT_API_URL = "http://18.230.69.122/"
 
+// DefaultLicensePublicKey is the base64 Ed25519 key used to verify signed
+// license files. It can be injected at build time with -ldflags -X.
+var DefaultLicensePublicKey = ""
+
 // Config holds the application configuration
 type Config struct {
-	APIURL string
+	APIURL           string
+	LicensePublicKey string
 }
 
 // Load loads configuration from environment variables and .env file
@@ -29,7 +34,14 @@ func Load() (*Config, error) {
 		apiURL = DEFAULT_API_URL
 	}
 
+	// Get license public key from environment variable
+	licensePublicKey := os.Getenv("LICENSE_PUBLIC_KEY")
+	if licensePublicKey == "" {
+		licensePublicKey = DefaultLicensePublicKey
+	}
+
 	return &Config{
-		APIURL: apiURL,
+		APIURL:           apiURL,
+		LicensePublicKey: licensePublicKey,
 	}, nil
 }
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/licensefile"
+)
+
+// VerifyLicense checks a signed license file before it is sent to the API.
+// It returns a nil payload for legacy opaque tokens. When no public key is
+// configured the payload is decoded without signature verification and
+// verified is false.
+func VerifyLicense(licenseKey, publicKey string) (payload *licensefile.Payload, verified bool, err error) {
+	if !licensefile.IsSigned(licenseKey) {
+		return nil, false, nil
+	}
+
+	if publicKey == "" {
+		payload, err := licensefile.Decode(licenseKey)
+		if err != nil {
+			return nil, false, err
+		}
+		if payload.Expired(time.Now()) {
+			return payload, false, fmt.Errorf("%w on %s", licensefile.ErrExpired, payload.ExpiresAt.Format(time.DateOnly))
+		}
+		return payload, false, nil
+	}
+
+	key, err := licensefile.ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	payload, err = licensefile.Verify(licenseKey, key, time.Now())
+	if err != nil {
+		return payload, false, err
+	}
+	return payload, true, nil
+}
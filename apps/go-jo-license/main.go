@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/licensefile"
+	"github.com/henrique-ferreira-unvoid/go-jo/pkg/semver"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "keygen":
+		err = keygen()
+	case "sign":
+		err = sign(os.Args[2:])
+	default:
+		usage()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the available commands
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage:\n")
+	fmt.Fprintf(os.Stderr, "  %s keygen\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s sign --key=<private-key-file> --id=<id> --customer=<name> [--expires=YYYY-MM-DD] [--integrations=a,b] [--versions=\">=1.4 <2.0\"] [--channel=stable|beta]\n", os.Args[0])
+}
+
+// keygen prints a new Ed25519 key pair
+func keygen() error {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	fmt.Printf("public_key:  %s\n", base64.StdEncoding.EncodeToString(publicKey))
+	fmt.Printf("private_key: %s\n", base64.StdEncoding.EncodeToString(privateKey))
+	return nil
+}
+
+// sign prints a signed license file for the given payload
+func sign(args []string) error {
+	flags := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyFile := flags.String("key", "", "file containing the base64 Ed25519 private key")
+	id := flags.String("id", "", "license ID")
+	customer := flags.String("customer", "", "customer name")
+	expires := flags.String("expires", "", "last day the license is valid (YYYY-MM-DD, until the end of that day UTC)")
+	integrations := flags.String("integrations", "", "comma separated integration patterns")
+	versions := flags.String("versions", "", "allowed version range")
+	channel := flags.String("channel", "", "release channel (stable or beta)")
+	flags.Parse(args)
+
+	if *keyFile == "" || *id == "" || *customer == "" {
+		return fmt.Errorf("--key, --id and --customer are required")
+	}
+
+	keyData, err := os.ReadFile(*keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %w", err)
+	}
+	privateKey, err := licensefile.ParsePrivateKey(string(keyData))
+	if err != nil {
+		return err
+	}
+
+	payload := &licensefile.Payload{
+		ID:       *id,
+		Customer: *customer,
+		IssuedAt: time.Now().UTC().Truncate(time.Second),
+		Versions: *versions,
+		Channel:  *channel,
+	}
+
+	if _, err := semver.ParseConstraint(*versions); err != nil {
+		return err
+	}
+	if *channel != "" && *channel != "stable" && *channel != "beta" {
+		return fmt.Errorf("--channel must be stable or beta")
+	}
+
+	if *expires != "" {
+		expiresAt, err := time.Parse(time.DateOnly, *expires)
+		if err != nil {
+			return fmt.Errorf("invalid --expires date: %w", err)
+		}
+		// The license stays valid for the whole expiry day
+		payload.ExpiresAt = licensefile.EndOfDay(expiresAt)
+	}
+
+	if *integrations != "" {
+		for _, integration := range strings.Split(*integrations, ",") {
+			if integration = strings.TrimSpace(integration); integration != "" {
+				payload.Integrations = append(payload.Integrations, integration)
+			}
+		}
+	}
+
+	signed, err := licensefile.Sign(payload, privateKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(signed)
+	return nil
+}
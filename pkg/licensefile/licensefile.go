@@ -0,0 +1,150 @@
+// Package licensefile implements the signed, offline-verifiable license format
+// shared by go-jo-api and go-jo-integration-installer.
+//
+// A signed license is the string "<payload>.<signature>", where payload is the
+// base64url encoded JSON document and signature is the base64url encoded
+// Ed25519 signature over the encoded payload.
+package licensefile
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// License file errors
+var (
+	ErrMalformed        = errors.New("license is not a valid signed license")
+	ErrInvalidSignature = errors.New("license signature is invalid (the file was modified or signed with a different key)")
+	ErrExpired          = errors.New("license has expired")
+	ErrInvalidKey       = errors.New("invalid license public key")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Payload is the signed content of a license file
+type Payload struct {
+	ID           string    `json:"id"`
+	Customer     string    `json:"customer"`
+	IssuedAt     time.Time `json:"issued_at,omitzero"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"`
+	Integrations []string  `json:"integrations,omitempty"`
+	Versions     string    `json:"versions,omitempty"`
+	Channel      string    `json:"channel,omitempty"`
+}
+
+// Expired reports whether the license is expired at the given time
+func (p *Payload) Expired(now time.Time) bool {
+	return !p.ExpiresAt.IsZero() && now.After(p.ExpiresAt)
+}
+
+// EndOfDay returns the expiry of a license that stays valid for the whole
+// UTC day of date: the last second of that day
+func EndOfDay(date time.Time) time.Time {
+	year, month, day := date.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1).Add(-time.Second)
+}
+
+// Sign encodes and signs a payload with the issuer's private key
+func Sign(payload *Payload, key ed25519.PrivateKey) (string, error) {
+	if payload.ID == "" {
+		return "", fmt.Errorf("license ID is required")
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode license payload: %w", err)
+	}
+
+	encoded := encoding.EncodeToString(data)
+	signature := ed25519.Sign(key, []byte(encoded))
+	return encoded + "." + encoding.EncodeToString(signature), nil
+}
+
+// IsSigned reports whether the token looks like a signed license
+func IsSigned(token string) bool {
+	_, _, _, err := split(token)
+	return err == nil
+}
+
+// Decode parses a signed license without verifying its signature
+func Decode(token string) (*Payload, error) {
+	_, data, _, err := split(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if payload.ID == "" {
+		return nil, fmt.Errorf("%w: missing license ID", ErrMalformed)
+	}
+	return &payload, nil
+}
+
+// Verify checks the signature and expiry of a signed license and returns its payload
+func Verify(token string, key ed25519.PublicKey, now time.Time) (*Payload, error) {
+	encoded, _, signature, err := split(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ed25519.Verify(key, []byte(encoded), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	payload, err := Decode(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Expired(now) {
+		return payload, fmt.Errorf("%w on %s", ErrExpired, payload.ExpiresAt.Format(time.DateOnly))
+	}
+
+	return payload, nil
+}
+
+// ParsePublicKey decodes a base64 encoded Ed25519 public key
+func ParsePublicKey(value string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// ParsePrivateKey decodes a base64 encoded Ed25519 private key
+func ParsePrivateKey(value string) (ed25519.PrivateKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid license private key")
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+// split separates a signed license into its encoded payload, decoded payload and signature
+func split(token string) (string, []byte, []byte, error) {
+	encoded, sig, found := strings.Cut(strings.TrimSpace(token), ".")
+	if !found || encoded == "" || sig == "" {
+		return "", nil, nil, ErrMalformed
+	}
+
+	data, err := encoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+
+	signature, err := encoding.DecodeString(sig)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return "", nil, nil, ErrMalformed
+	}
+
+	return encoded, data, signature, nil
+}
@@ -0,0 +1,124 @@
+package licensefile
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return publicKey, privateKey
+}
+
+func TestSignVerify(t *testing.T) {
+	publicKey, privateKey := newKey(t)
+	otherKey, _ := newKey(t)
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sign := func(payload *Payload) string {
+		signed, err := Sign(payload, privateKey)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return signed
+	}
+
+	valid := sign(&Payload{ID: "acme", Customer: "ACME Corp", Integrations: []string{"zabbix*"}})
+	encoded, signature, _ := strings.Cut(valid, ".")
+	forged := sign(&Payload{ID: "evil", Customer: "Evil Corp"})
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		key     ed25519.PublicKey
+		wantErr error
+		wantID  string
+	}{
+		{name: "valid", token: valid, key: publicKey, wantID: "acme"},
+		{name: "surrounding whitespace", token: "  " + valid + "\n", key: publicKey, wantID: "acme"},
+		{name: "tampered payload", token: forgedPayload + "." + signature, key: publicKey, wantErr: ErrInvalidSignature},
+		{name: "tampered signature", token: encoded + "." + strings.Repeat("A", len(signature)), key: publicKey, wantErr: ErrInvalidSignature},
+		{name: "wrong key", token: valid, key: otherKey, wantErr: ErrInvalidSignature},
+		{name: "expired", token: sign(&Payload{ID: "old", ExpiresAt: now.Add(-time.Hour)}), key: publicKey, wantErr: ErrExpired, wantID: "old"},
+		{name: "not yet expired", token: sign(&Payload{ID: "new", ExpiresAt: now.Add(time.Hour)}), key: publicKey, wantID: "new"},
+		{name: "plain token", token: "not-a-signed-license", key: publicKey, wantErr: ErrMalformed},
+		{name: "missing signature", token: encoded + ".", key: publicKey, wantErr: ErrMalformed},
+		{name: "short signature", token: encoded + ".AAAA", key: publicKey, wantErr: ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := Verify(tt.token, tt.key, now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if tt.wantID != "" && (payload == nil || payload.ID != tt.wantID) {
+				t.Fatalf("Verify() payload = %+v, want ID %q", payload, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestEndOfDay(t *testing.T) {
+	want := time.Date(2026, 1, 1, 23, 59, 59, 0, time.UTC)
+
+	for _, date := range []time.Time{
+		time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 1, 1, 18, 30, 0, 0, time.UTC),
+		time.Date(2026, 1, 2, 0, 30, 0, 0, time.FixedZone("CET", 3600)),
+	} {
+		if got := EndOfDay(date); !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("EndOfDay(%s) = %s, want %s", date, got, want)
+		}
+	}
+
+	// Month and year ends roll over
+	if got := EndOfDay(time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)); !got.Equal(time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC)) {
+		t.Errorf("EndOfDay(2025-12-31) = %s", got)
+	}
+}
+
+func TestSignRequiresID(t *testing.T) {
+	_, privateKey := newKey(t)
+	if _, err := Sign(&Payload{Customer: "ACME Corp"}, privateKey); err == nil {
+		t.Fatal("Sign() without ID succeeded")
+	}
+}
+
+func TestIsSigned(t *testing.T) {
+	_, privateKey := newKey(t)
+	signed, err := Sign(&Payload{ID: "acme"}, privateKey)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	if !IsSigned(signed) {
+		t.Error("IsSigned(signed license) = false")
+	}
+	if IsSigned("legacy-shared-token") {
+		t.Error("IsSigned(plain token) = true")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	if _, err := ParsePublicKey("dG9vIHNob3J0"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("ParsePublicKey(short key) error = %v, want %v", err, ErrInvalidKey)
+	}
+	if _, err := ParsePrivateKey("not base64!"); err == nil {
+		t.Error("ParsePrivateKey(invalid) succeeded")
+	}
+}
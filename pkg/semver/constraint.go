@@ -0,0 +1,156 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint is a version range such as ">=1.4 <2.0" or "^1.2 || ~2.0.3".
+// Comparators separated by spaces must all match; groups separated by "||"
+// are alternatives. An operator may be separated from its version by a space
+// (">= 1.4").
+type Constraint struct {
+	groups   [][]comparator
+	original string
+}
+
+type comparator struct {
+	op      string
+	version *Version
+	// components is the number of version components written, so "~1"
+	// can be told apart from "~1.0"
+	components int
+}
+
+var operators = []string{">=", "<=", "!=", ">", "<", "=", "^", "~"}
+
+// ParseConstraint parses a version range. An empty string matches every version.
+func ParseConstraint(value string) (*Constraint, error) {
+	c := &Constraint{original: strings.TrimSpace(value)}
+	if c.original == "" {
+		return c, nil
+	}
+
+	for _, group := range strings.Split(c.original, "||") {
+		var comparators []comparator
+		fields := strings.Fields(group)
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			// Allow a space between the operator and the version (">= 1.4")
+			if isOperator(field) {
+				if i+1 == len(fields) || isOperator(fields[i+1]) {
+					return nil, fmt.Errorf("invalid version constraint %q: operator %q is not followed by a version", value, field)
+				}
+				i++
+				field += fields[i]
+			}
+
+			comp, err := parseComparator(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint %q: %w", value, err)
+			}
+			comparators = append(comparators, comp)
+		}
+		if len(comparators) == 0 {
+			return nil, fmt.Errorf("invalid version constraint %q: empty range", value)
+		}
+		c.groups = append(c.groups, comparators)
+	}
+
+	return c, nil
+}
+
+// String returns the constraint as it was written
+func (c *Constraint) String() string {
+	return c.original
+}
+
+// Check reports whether the version satisfies the constraint
+func (c *Constraint) Check(v *Version) bool {
+	if len(c.groups) == 0 {
+		return true
+	}
+
+	for _, group := range c.groups {
+		matched := true
+		for _, comp := range group {
+			if !comp.check(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isOperator reports whether a field is an operator without its version
+func isOperator(field string) bool {
+	for _, op := range operators {
+		if field == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parseComparator parses a single comparator like ">=1.4"
+func parseComparator(field string) (comparator, error) {
+	op := "="
+	for _, candidate := range operators {
+		if strings.HasPrefix(field, candidate) {
+			op = candidate
+			field = strings.TrimPrefix(field, candidate)
+			break
+		}
+	}
+
+	v, err := Parse(field)
+	if err != nil {
+		return comparator{}, err
+	}
+
+	return comparator{op: op, version: v, components: components(field)}, nil
+}
+
+// check evaluates the comparator against a version. An upper bound such as
+// "<2.0" also excludes the prereleases of 2.0.0, so a customer pinned below a
+// major line never receives its release candidates.
+func (c comparator) check(v *Version) bool {
+	cmp := v.Compare(c.version)
+
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		if v.IsPrerelease() && !c.version.IsPrerelease() && v.sameCore(c.version) {
+			return false
+		}
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "^":
+		if cmp < 0 {
+			return false
+		}
+		if c.version.Major == 0 && c.components > 1 {
+			return v.Major == 0 && v.Minor == c.version.Minor
+		}
+		return v.Major == c.version.Major
+	case "~":
+		// "~1" allows the whole major line, "~1.2" and "~1.2.3" the minor line
+		if cmp < 0 || v.Major != c.version.Major {
+			return false
+		}
+		return c.components == 1 || v.Minor == c.version.Minor
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package semver
+
+import "testing"
+
+func TestConstraintCheck(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{constraint: "", match: []string{"0.1.0", "9.9.9", "2.0.0-rc.1"}},
+		{constraint: "1.4.2", match: []string{"v1.4.2"}, noMatch: []string{"1.4.3", "1.4.2-rc.1"}},
+		{constraint: "=1.4", match: []string{"1.4.0"}, noMatch: []string{"1.4.1"}},
+		{constraint: "!=1.4.0", match: []string{"1.4.1"}, noMatch: []string{"1.4.0"}},
+		{constraint: ">=1.4 <2.0", match: []string{"1.4.0", "1.9.9"}, noMatch: []string{"1.3.9", "2.0.0", "2.0.0-rc.1"}},
+		{constraint: ">= 1.4 < 2.0", match: []string{"1.4.0", "1.9.9"}, noMatch: []string{"1.3.9", "2.0.0"}},
+		{constraint: ">1.4", match: []string{"1.4.1"}, noMatch: []string{"1.4.0"}},
+		{constraint: "<=1.4", match: []string{"1.4.0", "1.3.0"}, noMatch: []string{"1.4.1"}},
+		{constraint: "<2.0.0-rc.2", match: []string{"2.0.0-rc.1"}, noMatch: []string{"2.0.0-rc.2", "2.0.0"}},
+		{constraint: "^1.2", match: []string{"1.2.0", "1.9.0"}, noMatch: []string{"1.1.9", "2.0.0"}},
+		{constraint: "^0.3.1", match: []string{"0.3.1", "0.3.9"}, noMatch: []string{"0.4.0", "0.3.0"}},
+		{constraint: "^0", match: []string{"0.0.1", "0.9.0"}, noMatch: []string{"1.0.0"}},
+		{constraint: "~1", match: []string{"1.0.0", "1.9.3"}, noMatch: []string{"0.9.0", "2.0.0"}},
+		{constraint: "~1.2", match: []string{"1.2.0", "1.2.9"}, noMatch: []string{"1.3.0", "1.1.9"}},
+		{constraint: "~1.2.3", match: []string{"1.2.3", "1.2.9"}, noMatch: []string{"1.2.2", "1.3.0"}},
+		{constraint: "^1.2 || ~2.0.3", match: []string{"1.5.0", "2.0.4"}, noMatch: []string{"2.1.0", "1.1.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseConstraint(%q) error = %v", tt.constraint, err)
+			}
+
+			for _, value := range tt.match {
+				v, err := Parse(value)
+				if err != nil {
+					t.Fatalf("Parse(%q) error = %v", value, err)
+				}
+				if !c.Check(v) {
+					t.Errorf("%q should match %s", tt.constraint, value)
+				}
+			}
+			for _, value := range tt.noMatch {
+				v, err := Parse(value)
+				if err != nil {
+					t.Fatalf("Parse(%q) error = %v", value, err)
+				}
+				if c.Check(v) {
+					t.Errorf("%q should not match %s", tt.constraint, value)
+				}
+			}
+		})
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, constraint := range []string{
+		">=",
+		">= <2.0",
+		">=1.4 ||",
+		">=x",
+		"1.4 ~",
+	} {
+		if _, err := ParseConstraint(constraint); err == nil {
+			t.Errorf("ParseConstraint(%q) succeeded, want error", constraint)
+		}
+	}
+}
+
+func TestConstraintString(t *testing.T) {
+	c, err := ParseConstraint("  >= 1.4 <2.0 ")
+	if err != nil {
+		t.Fatalf("ParseConstraint error = %v", err)
+	}
+	if got := c.String(); got != ">= 1.4 <2.0" {
+		t.Errorf("String() = %q", got)
+	}
+}
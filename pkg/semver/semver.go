@@ -0,0 +1,208 @@
+// Package semver parses and compares semantic versions as used by go-jo
+// release tags (for example "v1.4.2" or "v2.0.0-rc.1").
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+	Build      string
+	Original   string
+}
+
+// Parse parses a version string. A leading "v" is accepted and missing minor
+// or patch components default to zero.
+func Parse(value string) (*Version, error) {
+	original := value
+	value = strings.TrimPrefix(strings.TrimSpace(value), "v")
+	if value == "" {
+		return nil, fmt.Errorf("invalid version %q", original)
+	}
+
+	v := &Version{Original: original}
+
+	if core, build, found := strings.Cut(value, "+"); found {
+		if build == "" {
+			return nil, fmt.Errorf("invalid version %q: empty build metadata", original)
+		}
+		v.Build = build
+		value = core
+	}
+
+	if core, pre, found := strings.Cut(value, "-"); found {
+		if pre == "" {
+			return nil, fmt.Errorf("invalid version %q: empty prerelease", original)
+		}
+		v.Prerelease = strings.Split(pre, ".")
+		for _, id := range v.Prerelease {
+			if id == "" {
+				return nil, fmt.Errorf("invalid version %q: empty prerelease identifier", original)
+			}
+		}
+		value = core
+	}
+
+	parts := strings.Split(value, ".")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid version %q", original)
+	}
+
+	numbers := make([]uint64, 3)
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", original)
+		}
+		numbers[i] = n
+	}
+	v.Major, v.Minor, v.Patch = numbers[0], numbers[1], numbers[2]
+
+	return v, nil
+}
+
+// IsPrerelease reports whether the version carries prerelease identifiers
+func (v *Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// String returns the canonical form of the version without the "v" prefix
+func (v *Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 depending on the precedence of v and o.
+// Build metadata is ignored as required by the semver specification.
+func (v *Version) Compare(o *Version) int {
+	if c := compareUint(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// AtMost reports whether v is at or below an inclusive upper bound. A
+// partial bound covers its whole line, so "1.4" admits every 1.4.x and "1"
+// every 1.x. A prerelease bound is compared exactly.
+func (v *Version) AtMost(bound string) (bool, error) {
+	b, err := Parse(bound)
+	if err != nil {
+		return false, err
+	}
+	if b.IsPrerelease() {
+		return v.Compare(b) <= 0, nil
+	}
+
+	switch components(bound) {
+	case 1:
+		return v.Major <= b.Major, nil
+	case 2:
+		return v.Major < b.Major || v.Major == b.Major && v.Minor <= b.Minor, nil
+	}
+	return v.Compare(b) <= 0, nil
+}
+
+// components returns the number of version components written in value, so
+// "1.4" and "1.4.0" can be told apart
+func components(value string) int {
+	core := strings.TrimPrefix(strings.TrimSpace(value), "v")
+	core, _, _ = strings.Cut(core, "+")
+	core, _, _ = strings.Cut(core, "-")
+	return strings.Count(core, ".") + 1
+}
+
+// sameCore reports whether both versions share major, minor and patch
+func (v *Version) sameCore(o *Version) bool {
+	return v.Major == o.Major && v.Minor == o.Minor && v.Patch == o.Patch
+}
+
+// compareUint compares two numbers
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparePrerelease compares prerelease identifiers. A version without
+// prerelease has higher precedence than one with it.
+func comparePrerelease(a, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(a)), uint64(len(b)))
+}
+
+// compareIdentifier compares a single prerelease identifier. Numeric
+// identifiers compare numerically and sort below alphanumeric ones.
+func compareIdentifier(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case errA == nil && errB == nil:
+		return compareUint(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// CompareStrings compares two version strings. Valid versions sort above
+// strings that cannot be parsed, which are compared lexically.
+func CompareStrings(a, b string) int {
+	va, errA := Parse(a)
+	vb, errB := Parse(b)
+
+	switch {
+	case errA == nil && errB == nil:
+		return va.Compare(vb)
+	case errA == nil:
+		return 1
+	case errB == nil:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+// IsPrereleaseString reports whether a version string carries prerelease
+// identifiers. Unparsable strings are not considered prereleases.
+func IsPrereleaseString(value string) bool {
+	v, err := Parse(value)
+	return err == nil && v.IsPrerelease()
+}
@@ -0,0 +1,160 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "v1.4.2", want: "1.4.2"},
+		{value: "1.4", want: "1.4.0"},
+		{value: "2", want: "2.0.0"},
+		{value: "v2.0.0-rc.1", want: "2.0.0-rc.1"},
+		{value: "1.0.0-beta+build.5", want: "1.0.0-beta+build.5"},
+		{value: " v1.2.3 ", want: "1.2.3"},
+		{value: "", wantErr: true},
+		{value: "v", wantErr: true},
+		{value: "1.2.3.4", wantErr: true},
+		{value: "1.x", wantErr: true},
+		{value: "1.2.3-", wantErr: true},
+		{value: "1.2.3-rc..1", wantErr: true},
+		{value: "1.2.3+", wantErr: true},
+		{value: "main", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			v, err := Parse(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %s, want error", tt.value, v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.value, err)
+			}
+			if got := v.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.0.0", b: "1.0.0", want: 0},
+		{a: "v1.0.0", b: "1.0", want: 0},
+		{a: "1.10.0", b: "1.9.0", want: 1},
+		{a: "1.2.3", b: "1.2.4", want: -1},
+		{a: "2.0.0", b: "1.99.99", want: 1},
+		{a: "1.0.0-rc.1", b: "1.0.0", want: -1},
+		{a: "1.0.0-alpha", b: "1.0.0-alpha.1", want: -1},
+		{a: "1.0.0-alpha.1", b: "1.0.0-alpha.beta", want: -1},
+		{a: "1.0.0-beta.2", b: "1.0.0-beta.11", want: -1},
+		{a: "1.0.0-rc.1", b: "1.0.0-beta.11", want: 1},
+		{a: "1.0.0+build.1", b: "1.0.0+build.2", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, errA := Parse(tt.a)
+			b, errB := Parse(tt.b)
+			if errA != nil || errB != nil {
+				t.Fatalf("Parse errors: %v, %v", errA, errB)
+			}
+			if got := a.Compare(b); got != tt.want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := b.Compare(a); got != -tt.want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareStrings(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "v1.10.0", b: "v1.9.0", want: 1},
+		{a: "v1.0.0", b: "nightly", want: 1},
+		{a: "nightly", b: "v1.0.0", want: -1},
+		{a: "alpha", b: "beta", want: -1},
+	}
+
+	for _, tt := range tests {
+		if got := CompareStrings(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareStrings(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestIsPrereleaseString(t *testing.T) {
+	tests := map[string]bool{
+		"v2.0.0-rc.1": true,
+		"v2.0.0":      false,
+		"nightly":     false,
+	}
+
+	for value, want := range tests {
+		if got := IsPrereleaseString(value); got != want {
+			t.Errorf("IsPrereleaseString(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestAtMost(t *testing.T) {
+	tests := []struct {
+		version string
+		bound   string
+		want    bool
+		wantErr bool
+	}{
+		{version: "1.4.2", bound: "1.4.2", want: true},
+		{version: "1.4.3", bound: "1.4.2", want: false},
+		{version: "1.4.0", bound: "1.4.0", want: true},
+		{version: "1.4.9", bound: "1.4.0", want: false},
+		// A partial bound covers its whole line
+		{version: "1.4.9", bound: "1.4", want: true},
+		{version: "1.4.9-rc.1", bound: "v1.4", want: true},
+		{version: "1.5.0-rc.1", bound: "1.4", want: false},
+		{version: "1.5.0", bound: "1.4", want: false},
+		{version: "1.9.3", bound: "1", want: true},
+		{version: "2.0.0-rc.1", bound: "1", want: false},
+		{version: "0.9.0", bound: "1.4", want: true},
+		// A prerelease bound is exact
+		{version: "1.4.0-rc.2", bound: "1.4.0-rc.1", want: false},
+		{version: "1.4.0-rc.1", bound: "1.4.0-rc.1", want: true},
+		{version: "1.0.0", bound: "1.x", wantErr: true},
+		{version: "1.0.0", bound: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.bound, func(t *testing.T) {
+			v, err := Parse(tt.version)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.version, err)
+			}
+			got, err := v.AtMost(tt.bound)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("AtMost(%q) = %v, want error", tt.bound, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AtMost(%q) error = %v", tt.bound, err)
+			}
+			if got != tt.want {
+				t.Errorf("%s.AtMost(%q) = %v, want %v", tt.version, tt.bound, got, tt.want)
+			}
+		})
+	}
+}